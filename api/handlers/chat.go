@@ -3,10 +3,11 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/andriystech/lgc/facilities/ws"
 	"github.com/andriystech/lgc/services"
 )
 
-func WSConnectHandler(ws services.WebSocketService, ts services.TokenService) http.HandlerFunc {
+func WSConnectHandler(wssvc services.WebSocketService, ts services.TokenService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		q := r.URL.Query()
 		token, ok := q["token"]
@@ -15,12 +16,17 @@ func WSConnectHandler(ws services.WebSocketService, ts services.TokenService) ht
 			return
 		}
 
+		if _, err := ws.ProtocolFromRequest(r); err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
 		user, err := ts.GetUserByToken(r.Context(), token[0])
 		if err != nil {
 			SendErrorJsonResponse(w, http.StatusForbidden, err.Error())
 			return
 		}
-		err = ws.NewConnection(w, r, user)
+		err = wssvc.NewConnection(w, r, user)
 		if err != nil {
 			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
 			return
@@ -3,11 +3,11 @@ package handlers
 import (
 	"errors"
 	"fmt"
-	"lgc/mocks"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
+	"github.com/andriystech/lgc/mocks"
 	"github.com/andriystech/lgc/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -29,6 +29,12 @@ func TestNewConnection(t *testing.T) {
 			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Query parameter 'token' is missing"}`, http.StatusBadRequest),
 			prepareMocks: func(cr *mocks.ConnectionsRepository, ts *mocks.TokenService, wsvc *mocks.WebSocketService) {},
 		},
+		{
+			url:          fmt.Sprintf("chat/ws.rtm.start?token=%s&protocol=xml", fakeToken),
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"unsupported web socket protocol"}`, http.StatusBadRequest),
+			prepareMocks: func(cr *mocks.ConnectionsRepository, ts *mocks.TokenService, wsvc *mocks.WebSocketService) {},
+		},
 		{
 			url:      fmt.Sprintf("chat/ws.rtm.start?token=%s", fakeToken),
 			wantCode: http.StatusForbidden,
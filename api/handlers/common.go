@@ -4,8 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net/http"
+
+	"github.com/andriystech/lgc/pkg/logger"
 )
 
 type HttpErrorResponse struct {
@@ -16,12 +17,12 @@ type HttpErrorResponse struct {
 func ParseJsonBody(r *http.Request, v interface{}) (interface{}, error) {
 	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("Unable to read body. Reason: %s", err.Error())
+		logger.FromContext(r.Context()).Error("Unable to read body", "err", err)
 		return nil, err
 	}
 
 	if err := json.Unmarshal(body, v); err != nil {
-		log.Printf("Unable to parse JSON body. Reason: %s", err.Error())
+		logger.FromContext(r.Context()).Debug("Unable to parse JSON body", "err", err)
 		return nil, err
 	}
 	return v, nil
@@ -37,7 +38,7 @@ func SendErrorJsonResponse(w http.ResponseWriter, status int, message string) {
 func sendJsonResponse(w http.ResponseWriter, v interface{}, status int) {
 	out, err := json.Marshal(v)
 	if err != nil {
-		log.Printf("Unable to create response. Reason: %s", err.Error())
+		logger.Default().Error("Unable to create response", "err", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+)
+
+type MessageOutput struct {
+	Id          string            `json:"id"`
+	SenderId    string            `json:"senderId"`
+	SenderName  string            `json:"senderName"`
+	RecipientId string            `json:"recipientId"`
+	RoomId      string            `json:"roomId,omitempty"`
+	Payload     string            `json:"payload"`
+	Time        int64             `json:"time"`
+	DeliveredAt int64             `json:"deliveredAt,omitempty"`
+	ReadAt      int64             `json:"readAt,omitempty"`
+	EditedAt    int64             `json:"editedAt,omitempty"`
+	DeletedAt   int64             `json:"deletedAt,omitempty"`
+	History     []*RevisionOutput `json:"history,omitempty"`
+}
+
+type RevisionOutput struct {
+	Payload string `json:"payload"`
+	Time    int64  `json:"time"`
+}
+
+type EditMessageInput struct {
+	Payload string `json:"payload"`
+}
+
+type ReceiptOutput struct {
+	RecipientId string `json:"recipientId"`
+	DeliveredAt int64  `json:"deliveredAt,omitempty"`
+	ReadAt      int64  `json:"readAt,omitempty"`
+}
+
+type ReceiptsOutput struct {
+	Receipts []*ReceiptOutput `json:"receipts"`
+}
+
+type MessagesOutput struct {
+	Messages []*MessageOutput `json:"messages"`
+}
+
+type MessagesPageOutput struct {
+	MessagesOutput
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// MessagesHandler returns page of messages received by the authenticated user.
+func MessagesHandler(msvc services.MessageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		query, err := parseMessagesQuery(r, user.Id)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		page, err := msvc.GetMessages(r.Context(), query)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := &MessagesPageOutput{MessagesOutput: *composeMessagesOutput(page.Messages)}
+		if page.NextCursor != nil {
+			out.NextCursor = page.NextCursor.Encode()
+		}
+		sendJsonResponse(w, out, http.StatusOK)
+	}
+}
+
+// DirectMessagesHandler returns conversation of the authenticated user with the peer.
+func DirectMessagesHandler(msvc services.MessageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		peerId := r.URL.Query().Get("peerId")
+		if len(peerId) == 0 {
+			SendErrorJsonResponse(w, http.StatusBadRequest, "Query parameter 'peerId' is missing")
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		messages, err := msvc.GetDirectMessages(r.Context(), user.Id, peerId)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeMessagesOutput(messages), http.StatusOK)
+	}
+}
+
+// MessageReceiptsHandler returns delivery state of the authenticated user's message per recipient.
+func MessageReceiptsHandler(msvc services.MessageService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		messages, err := msvc.GetMessageReceipts(r.Context(), user.Id, mux.Vars(r)["id"])
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := &ReceiptsOutput{Receipts: []*ReceiptOutput{}}
+		for _, msg := range messages {
+			out.Receipts = append(out.Receipts, &ReceiptOutput{
+				RecipientId: msg.RecipientId,
+				DeliveredAt: msg.DeliveredAt,
+				ReadAt:      msg.ReadAt,
+			})
+		}
+		sendJsonResponse(w, out, http.StatusOK)
+	}
+}
+
+// EditMessageHandler replaces payload of the authenticated user's message.
+func EditMessageHandler(wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := ParseJsonBody(r, &EditMessageInput{})
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		msg, err := wssvc.EditMessage(r.Context(), user, mux.Vars(r)["id"], v.(*EditMessageInput).Payload)
+		if err != nil {
+			sendMessageUpdateError(w, err)
+			return
+		}
+		sendJsonResponse(w, composeMessageOutput(msg), http.StatusOK)
+	}
+}
+
+// DeleteMessageHandler retracts the authenticated user's message from all recipients.
+func DeleteMessageHandler(wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		if err := wssvc.DeleteMessage(r.Context(), user, mux.Vars(r)["id"]); err != nil {
+			sendMessageUpdateError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func sendMessageUpdateError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrEmptyFramePayload), errors.Is(err, services.ErrFramePayloadTooLong):
+		SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrNotMessageSender):
+		SendErrorJsonResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrMessageNotFound):
+		SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrMessageDeleted):
+		SendErrorJsonResponse(w, http.StatusGone, err.Error())
+	case errors.Is(err, repositories.ErrMessageChanged):
+		SendErrorJsonResponse(w, http.StatusConflict, err.Error())
+	default:
+		SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func parseMessagesQuery(r *http.Request, recipientId string) (*models.MessagesQuery, error) {
+	q := r.URL.Query()
+	query := &models.MessagesQuery{
+		RecipientId: recipientId,
+		SenderId:    q.Get("senderId"),
+		Limit:       models.MessagesPageDefaultLimit,
+	}
+	var err error
+	if query.From, err = parseTimestampParam(q, "from"); err != nil {
+		return nil, err
+	}
+	if query.To, err = parseTimestampParam(q, "to"); err != nil {
+		return nil, err
+	}
+	if v := q.Get("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil || query.Limit < 1 || query.Limit > models.MessagesPageMaxLimit {
+			return nil, fmt.Errorf("Query parameter 'limit' must be between 1 and %d", models.MessagesPageMaxLimit)
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		if query.After, err = models.DecodeMessageCursor(v); err != nil {
+			return nil, err
+		}
+	}
+	return query, nil
+}
+
+func parseTimestampParam(q url.Values, param string) (int64, error) {
+	v := q.Get(param)
+	if v == "" {
+		return 0, nil
+	}
+	ts, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || ts < 0 {
+		return 0, fmt.Errorf("Query parameter '%s' must be a unix timestamp", param)
+	}
+	return ts, nil
+}
+
+func composeMessagesOutput(messages []*models.Message) *MessagesOutput {
+	out := &MessagesOutput{Messages: []*MessageOutput{}}
+	for _, msg := range messages {
+		out.Messages = append(out.Messages, composeMessageOutput(msg))
+	}
+	return out
+}
+
+// composeMessageOutput exposes id shared by all copies of the message, so ids from the history
+// can be used to edit, delete, read the message and to get its receipts.
+func composeMessageOutput(msg *models.Message) *MessageOutput {
+	out := &MessageOutput{
+		Id:          msg.LogicalId(),
+		SenderId:    msg.SenderId,
+		SenderName:  msg.SenderName,
+		RecipientId: msg.RecipientId,
+		RoomId:      msg.RoomId,
+		Payload:     msg.Payload,
+		Time:        msg.Time,
+		DeliveredAt: msg.DeliveredAt,
+		ReadAt:      msg.ReadAt,
+		EditedAt:    msg.EditedAt,
+		DeletedAt:   msg.DeletedAt,
+	}
+	for _, revision := range msg.History {
+		out.History = append(out.History, &RevisionOutput{Payload: revision.Payload, Time: revision.Time})
+	}
+	return out
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var messagesTestUser = &models.User{Id: "1", UserName: "foo"}
+
+type messagesHandlersTestData struct {
+	url          string
+	wantCode     int
+	wantBody     string
+	prepareMocks func(*mocks.MessageService)
+}
+
+func TestDirectMessagesHandler(t *testing.T) {
+	ErrFindMessages := errors.New("Unable to find messages")
+	testConditions := []messagesHandlersTestData{
+		{
+			url:          "messages/direct",
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Query parameter 'peerId' is missing"}`, http.StatusBadRequest),
+			prepareMocks: func(ms *mocks.MessageService) {},
+		},
+		{
+			url:      "messages/direct?peerId=2",
+			wantCode: http.StatusOK,
+			wantBody: `{"messages":[{"id":"3","senderId":"2","senderName":"bar","recipientId":"1","payload":"hello","time":10}]}`,
+			prepareMocks: func(ms *mocks.MessageService) {
+				ms.On("GetDirectMessages", mock.Anything, "1", "2").Return([]*models.Message{
+					{Id: "3", SenderId: "2", SenderName: "bar", RecipientId: "1", Payload: "hello", Time: 10},
+				}, nil)
+			},
+		},
+		{
+			url:      "messages/direct?peerId=2",
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrFindMessages.Error()),
+			prepareMocks: func(ms *mocks.MessageService) {
+				ms.On("GetDirectMessages", mock.Anything, "1", "2").Return(nil, ErrFindMessages)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ms := new(mocks.MessageService)
+			testCond.prepareMocks(ms)
+
+			req, err := http.NewRequest(http.MethodGet, testCond.url, nil)
+			assert.Nil(t, err, "%v", err)
+			req = req.WithContext(services.ContextWithUser(req.Context(), messagesTestUser))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(DirectMessagesHandler(ms))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ms.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessagesHandler(t *testing.T) {
+	ErrFindMessages := errors.New("Unable to find messages")
+	cursor := &models.MessageCursor{Time: 10, Id: "3"}
+	testConditions := []messagesHandlersTestData{
+		{
+			url:          "messages?from=yesterday",
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Query parameter 'from' must be a unix timestamp"}`, http.StatusBadRequest),
+			prepareMocks: func(ms *mocks.MessageService) {},
+		},
+		{
+			url:          "messages?limit=1000",
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Query parameter 'limit' must be between 1 and %d"}`, http.StatusBadRequest, models.MessagesPageMaxLimit),
+			prepareMocks: func(ms *mocks.MessageService) {},
+		},
+		{
+			url:          "messages?cursor=!",
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusBadRequest, models.ErrInvalidMessageCursor.Error()),
+			prepareMocks: func(ms *mocks.MessageService) {},
+		},
+		{
+			url:      fmt.Sprintf("messages?senderId=2&from=5&to=20&limit=1&cursor=%s", cursor.Encode()),
+			wantCode: http.StatusOK,
+			wantBody: fmt.Sprintf(`{"messages":[{"id":"3","senderId":"2","senderName":"bar","recipientId":"1","payload":"hello","time":10}],"nextCursor":"%s"}`, cursor.Encode()),
+			prepareMocks: func(ms *mocks.MessageService) {
+				query := &models.MessagesQuery{RecipientId: "1", SenderId: "2", From: 5, To: 20, Limit: 1, After: cursor}
+				ms.On("GetMessages", mock.Anything, query).Return(&models.MessagesPage{
+					Messages:   []*models.Message{{Id: "3", SenderId: "2", SenderName: "bar", RecipientId: "1", Payload: "hello", Time: 10}},
+					NextCursor: cursor,
+				}, nil)
+			},
+		},
+		{
+			url:      "messages",
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrFindMessages.Error()),
+			prepareMocks: func(ms *mocks.MessageService) {
+				query := &models.MessagesQuery{RecipientId: "1", Limit: models.MessagesPageDefaultLimit}
+				ms.On("GetMessages", mock.Anything, query).Return(nil, ErrFindMessages)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ms := new(mocks.MessageService)
+			testCond.prepareMocks(ms)
+
+			req, err := http.NewRequest(http.MethodGet, testCond.url, nil)
+			assert.Nil(t, err, "%v", err)
+			req = req.WithContext(services.ContextWithUser(req.Context(), messagesTestUser))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(MessagesHandler(ms))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ms.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageReceiptsHandler(t *testing.T) {
+	ErrFindReceipts := errors.New("Unable to find receipts")
+	testConditions := []messagesHandlersTestData{
+		{
+			url:      "messages/m1/receipts",
+			wantCode: http.StatusOK,
+			wantBody: `{"receipts":[{"recipientId":"2","deliveredAt":10,"readAt":20},{"recipientId":"3"}]}`,
+			prepareMocks: func(ms *mocks.MessageService) {
+				ms.On("GetMessageReceipts", mock.Anything, "1", "m1").Return([]*models.Message{
+					{Id: "c1", OriginId: "m1", RecipientId: "2", DeliveredAt: 10, ReadAt: 20},
+					{Id: "c2", OriginId: "m1", RecipientId: "3"},
+				}, nil)
+			},
+		},
+		{
+			url:      "messages/m1/receipts",
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrFindReceipts.Error()),
+			prepareMocks: func(ms *mocks.MessageService) {
+				ms.On("GetMessageReceipts", mock.Anything, "1", "m1").Return(nil, ErrFindReceipts)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ms := new(mocks.MessageService)
+			testCond.prepareMocks(ms)
+
+			req, err := http.NewRequest(http.MethodGet, testCond.url, nil)
+			assert.Nil(t, err, "%v", err)
+			req = req.WithContext(services.ContextWithUser(req.Context(), messagesTestUser))
+			req = mux.SetURLVars(req, map[string]string{"id": "m1"})
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(MessageReceiptsHandler(ms))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ms.AssertExpectations(t)
+		})
+	}
+}
+
+func TestEditMessageHandler(t *testing.T) {
+	usr := &models.User{Id: "1", UserName: "foo"}
+	testConditions := []struct {
+		body         string
+		wantCode     int
+		wantBody     string
+		prepareMocks func(*mocks.WebSocketService)
+	}{
+		{
+			body:     `{"payload":"hello"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"id":"c1","senderId":"1","senderName":"foo","recipientId":"2","payload":"hello","time":5,"editedAt":10,"history":[{"payload":"helo","time":5}]}`,
+			prepareMocks: func(wss *mocks.WebSocketService) {
+				wss.On("EditMessage", mock.Anything, usr, "m1", "hello").Return(&models.Message{
+					Id: "c1", SenderId: "1", SenderName: "foo", RecipientId: "2", Payload: "hello", Time: 5, EditedAt: 10,
+					History: []*models.MessageRevision{{Payload: "helo", Time: 5}},
+				}, nil)
+			},
+		},
+		{
+			body:     `{"payload":"hello"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusForbidden, services.ErrNotMessageSender.Error()),
+			prepareMocks: func(wss *mocks.WebSocketService) {
+				wss.On("EditMessage", mock.Anything, usr, "m1", "hello").Return(nil, services.ErrNotMessageSender)
+			},
+		},
+		{
+			body:     `{"payload":""}`,
+			wantCode: http.StatusBadRequest,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusBadRequest, services.ErrEmptyFramePayload.Error()),
+			prepareMocks: func(wss *mocks.WebSocketService) {
+				wss.On("EditMessage", mock.Anything, usr, "m1", "").Return(nil, services.ErrEmptyFramePayload)
+			},
+		},
+		{
+			body:     `{"payload":"hello"}`,
+			wantCode: http.StatusGone,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusGone, services.ErrMessageDeleted.Error()),
+			prepareMocks: func(wss *mocks.WebSocketService) {
+				wss.On("EditMessage", mock.Anything, usr, "m1", "hello").Return(nil, services.ErrMessageDeleted)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			wssvc := new(mocks.WebSocketService)
+			testCond.prepareMocks(wssvc)
+
+			req, err := http.NewRequest(http.MethodPatch, "messages/m1", strings.NewReader(testCond.body))
+			assert.Nil(t, err, "%v", err)
+			req = mux.SetURLVars(req, map[string]string{"id": "m1"})
+			req = req.WithContext(services.ContextWithUser(req.Context(), usr))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(EditMessageHandler(wssvc))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			wssvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteMessageHandler(t *testing.T) {
+	usr := &models.User{Id: "1", UserName: "foo"}
+	testConditions := []struct {
+		wantCode int
+		wantBody string
+		err      error
+	}{
+		{wantCode: http.StatusNoContent},
+		{
+			wantCode: http.StatusNotFound,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusNotFound, repositories.ErrMessageNotFound.Error()),
+			err:      repositories.ErrMessageNotFound,
+		},
+		{
+			wantCode: http.StatusConflict,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusConflict, repositories.ErrMessageChanged.Error()),
+			err:      repositories.ErrMessageChanged,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			wssvc := new(mocks.WebSocketService)
+			wssvc.On("DeleteMessage", mock.Anything, usr, "m1").Return(testCond.err)
+
+			req, err := http.NewRequest(http.MethodDelete, "messages/m1", nil)
+			assert.Nil(t, err, "%v", err)
+			req = mux.SetURLVars(req, map[string]string{"id": "m1"})
+			req = req.WithContext(services.ContextWithUser(req.Context(), usr))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(DeleteMessageHandler(wssvc))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			wssvc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMessageIdRoundTrip(t *testing.T) {
+	delivery := &models.Message{Id: "c1", OriginId: "m1", SenderId: "2", SenderName: "bar", RecipientId: "1", Payload: "hello", Time: 10}
+	ms := new(mocks.MessageService)
+	wssvc := new(mocks.WebSocketService)
+	ms.On("GetMessages", mock.Anything, &models.MessagesQuery{RecipientId: "1", Limit: models.MessagesPageDefaultLimit}).Return(&models.MessagesPage{
+		Messages: []*models.Message{delivery},
+	}, nil)
+	ms.On("GetDirectMessages", mock.Anything, "1", "2").Return([]*models.Message{delivery}, nil)
+	ms.On("GetMessageReceipts", mock.Anything, "1", "m1").Return([]*models.Message{delivery}, nil)
+	wssvc.On("EditMessage", mock.Anything, messagesTestUser, "m1", "hi").Return(&models.Message{Id: "m1", SenderId: "1", Payload: "hi", Time: 10, EditedAt: 20}, nil)
+	wssvc.On("DeleteMessage", mock.Anything, messagesTestUser, "m1").Return(nil)
+
+	router := mux.NewRouter()
+	router.Handle("/messages", MessagesHandler(ms)).Methods(http.MethodGet)
+	router.Handle("/messages/direct", DirectMessagesHandler(ms)).Methods(http.MethodGet)
+	router.Handle("/messages/{id}", EditMessageHandler(wssvc)).Methods(http.MethodPatch)
+	router.Handle("/messages/{id}", DeleteMessageHandler(wssvc)).Methods(http.MethodDelete)
+	router.Handle("/messages/{id}/receipts", MessageReceiptsHandler(ms)).Methods(http.MethodGet)
+	serve := func(method, url, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		req = req.WithContext(services.ContextWithUser(req.Context(), messagesTestUser))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for _, url := range []string{"/messages", "/messages/direct?peerId=2"} {
+		out := &MessagesOutput{}
+		rr := serve(http.MethodGet, url, "")
+		assert.Nil(t, json.Unmarshal(rr.Body.Bytes(), out), "handler returned invalid body %s", rr.Body.String())
+		if !assert.Len(t, out.Messages, 1, "handler returned unexpected messages: %s", rr.Body.String()) {
+			return
+		}
+		id := out.Messages[0].Id
+		assert.Equal(t, "m1", id, "handler returned unexpected message id: got %v want %v", id, "m1")
+
+		rr = serve(http.MethodPatch, "/messages/"+id, `{"payload":"hi"}`)
+		assert.Equal(t, http.StatusOK, rr.Code, "edit returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		rr = serve(http.MethodGet, "/messages/"+id+"/receipts", "")
+		assert.Equal(t, `{"receipts":[{"recipientId":"1"}]}`, rr.Body.String(), "receipts returned unexpected body: got %v", rr.Body.String())
+		rr = serve(http.MethodDelete, "/messages/"+id, "")
+		assert.Equal(t, http.StatusNoContent, rr.Code, "delete returned wrong status code: got %v want %v", rr.Code, http.StatusNoContent)
+	}
+	ms.AssertExpectations(t)
+	wssvc.AssertExpectations(t)
+}
@@ -2,12 +2,68 @@ package handlers
 
 import (
 	"net/http"
+
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/pkg/metrics"
+	"github.com/andriystech/lgc/services"
 )
 
 type HealthCheckResult struct {
-	Status string `json:"status"`
+	Status string                           `json:"status"`
+	Checks map[string]DependencyCheckResult `json:"checks,omitempty"`
+}
+
+type DependencyCheckResult struct {
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+	Error     string  `json:"error,omitempty"`
 }
 
 func HealthCheck(w http.ResponseWriter, r *http.Request) {
 	sendJsonResponse(w, HealthCheckResult{Status: "ok"}, http.StatusOK)
 }
+
+// LivenessHandler reports that the process is able to serve requests, it does not check dependencies.
+func LivenessHandler(hs services.HealthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendHealth(w, hs.Live(r.Context()))
+	}
+}
+
+// ReadinessHandler responds with 503 when a dependency is unreachable or the server is shutting down.
+func ReadinessHandler(hs services.HealthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendHealth(w, hs.Ready(r.Context()))
+	}
+}
+
+func sendHealth(w http.ResponseWriter, health *models.Health) {
+	result := HealthCheckResult{Status: health.Status}
+	if len(health.Dependencies) > 0 {
+		result.Checks = make(map[string]DependencyCheckResult, len(health.Dependencies))
+		for name, dependency := range health.Dependencies {
+			result.Checks[name] = DependencyCheckResult{
+				Status:    dependency.Status,
+				LatencyMs: float64(dependency.Latency.Microseconds()) / 1000,
+				Error:     dependency.Error,
+			}
+		}
+	}
+	status := http.StatusOK
+	if !health.IsOk() {
+		status = http.StatusServiceUnavailable
+	}
+	sendJsonResponse(w, result, status)
+}
+
+// MetricsHandler writes metrics of the registry in the Prometheus text exposition format.
+func MetricsHandler(registry *metrics.Registry) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", metrics.ContentType)
+		w.WriteHeader(http.StatusOK)
+		if _, err := registry.WriteTo(w); err != nil {
+			logger.FromContext(r.Context()).Error("Unable to write metrics", "err", err)
+		}
+	}
+}
@@ -4,8 +4,13 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestHealthCheck(t *testing.T) {
@@ -20,3 +25,65 @@ func TestHealthCheck(t *testing.T) {
 	expected := `{"status":"ok"}`
 	assert.Equal(t, expected, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
 }
+
+func TestMetricsHandler(t *testing.T) {
+	registry := metrics.NewRegistry()
+	registry.NewGauge("lgc_ws_active_connections", "Web socket connections held by the instance.").Inc()
+	req, err := http.NewRequest(http.MethodGet, "/metrics", nil)
+	assert.Nil(t, err, "%v", err)
+
+	rr := httptest.NewRecorder()
+	MetricsHandler(registry).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	assert.Equal(t, metrics.ContentType, rr.Header().Get("Content-Type"), "handler returned wrong content type: got %v want %v", rr.Header().Get("Content-Type"), metrics.ContentType)
+	expected := "# HELP lgc_ws_active_connections Web socket connections held by the instance.\n# TYPE lgc_ws_active_connections gauge\nlgc_ws_active_connections 1\n"
+	assert.Equal(t, expected, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), expected)
+}
+
+func TestReadinessHandler(t *testing.T) {
+	testConditions := []struct {
+		tName    string
+		health   *models.Health
+		wantCode int
+		wantBody string
+	}{
+		{
+			tName: "should respond with ok when dependencies are reachable",
+			health: &models.Health{Status: models.HealthStatusOk, Dependencies: map[string]*models.DependencyHealth{
+				"mongo": {Status: models.HealthStatusOk, Latency: 1500 * time.Microsecond},
+			}},
+			wantCode: http.StatusOK,
+			wantBody: `{"status":"ok","checks":{"mongo":{"status":"ok","latencyMs":1.5}}}`,
+		},
+		{
+			tName: "should respond with service unavailable when dependency fails",
+			health: &models.Health{Status: models.HealthStatusFail, Dependencies: map[string]*models.DependencyHealth{
+				"mongo": {Status: models.HealthStatusFail, Latency: time.Second, Error: "timeout"},
+			}},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status":"fail","checks":{"mongo":{"status":"fail","latencyMs":1000,"error":"timeout"}}}`,
+		},
+		{
+			tName:    "should respond with service unavailable during shutdown",
+			health:   &models.Health{Status: models.HealthStatusShuttingDown},
+			wantCode: http.StatusServiceUnavailable,
+			wantBody: `{"status":"shutting_down"}`,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			hs := new(mocks.HealthService)
+			hs.On("Ready", mock.Anything).Return(testCond.health)
+			req := httptest.NewRequest(http.MethodGet, "/_health/ready", nil)
+			rr := httptest.NewRecorder()
+
+			ReadinessHandler(hs).ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			hs.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+)
+
+type ProfileOutput struct {
+	Id          string `json:"id"`
+	UserName    string `json:"userName"`
+	DisplayName string `json:"displayName"`
+	AvatarUrl   string `json:"avatarUrl"`
+	StatusText  string `json:"statusText"`
+	CreatedAt   int64  `json:"createdAt"`
+	UpdatedAt   int64  `json:"updatedAt"`
+}
+
+// UpdateProfileInput changes only fields present in the body, empty string clears the field.
+type UpdateProfileInput struct {
+	DisplayName *string `json:"displayName"`
+	AvatarUrl   *string `json:"avatarUrl"`
+	StatusText  *string `json:"statusText"`
+}
+
+type ChangePasswordInput struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+type DeleteAccountInput struct {
+	Password string `json:"password"`
+}
+
+// ProfileHandler returns public profile of the user with id from the path.
+func ProfileHandler(usvc services.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sendProfile(w, r, usvc, mux.Vars(r)["id"])
+	}
+}
+
+// OwnProfileHandler returns profile of the authenticated user.
+func OwnProfileHandler(usvc services.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		sendProfile(w, r, usvc, user.Id)
+	}
+}
+
+func UpdateProfileHandler(usvc services.UserService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := ParseJsonBody(r, &UpdateProfileInput{})
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		input := v.(*UpdateProfileInput)
+		update := &models.ProfileUpdate{
+			DisplayName: input.DisplayName,
+			AvatarUrl:   input.AvatarUrl,
+			StatusText:  input.StatusText,
+		}
+		if err = ValidateProfileUpdate(update); err != nil {
+			logger.FromContext(r.Context()).Debug("Invalid input", "err", err)
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		profile, err := usvc.UpdateProfile(r.Context(), user.Id, update)
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeProfileOutput(profile), http.StatusOK)
+	}
+}
+
+// ChangePasswordHandler replaces password of the authenticated user and disconnects its web socket sessions.
+// Tokens issued before the change are rejected, so the caller receives a new token pair.
+func ChangePasswordHandler(usvc services.UserService, tsvc services.TokenService, wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := ParseJsonBody(r, &ChangePasswordInput{})
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		input := v.(*ChangePasswordInput)
+		if len(input.CurrentPassword) == 0 {
+			SendErrorJsonResponse(w, http.StatusBadRequest, "field 'currentPassword' was not provided inside body")
+			return
+		}
+		if len(input.NewPassword) < models.PasswordMinLength {
+			SendErrorJsonResponse(w, http.StatusBadRequest, fmt.Sprintf("field 'newPassword' was not provided inside body or length less than %d", models.PasswordMinLength))
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		changed, err := usvc.ChangePassword(r.Context(), user.Id, input.CurrentPassword, input.NewPassword)
+		if err != nil {
+			sendAccountError(w, err)
+			return
+		}
+		CloseUserSessions(r, wssvc, user)
+		tokens, err := tsvc.IssueTokens(r.Context(), changed)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeTokensOutput(tokens), http.StatusOK)
+	}
+}
+
+// DeleteAccountHandler removes account of the authenticated user along with its sessions, presence,
+// room memberships and pending messages. Tokens which were already issued for the account are rejected.
+func DeleteAccountHandler(usvc services.UserService, wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := ParseJsonBody(r, &DeleteAccountInput{})
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		input := v.(*DeleteAccountInput)
+		if len(input.Password) == 0 {
+			SendErrorJsonResponse(w, http.StatusBadRequest, "field 'password' was not provided inside body")
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		if err = usvc.DeleteUser(r.Context(), user.Id, input.Password); err != nil {
+			sendAccountError(w, err)
+			return
+		}
+		RemoveUserData(r, wssvc, user)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CloseUserSessions disconnects all web socket sessions of the user, failures are only logged
+// since web socket connect rejects tokens of a deleted user and tokens issued before the password change.
+func CloseUserSessions(r *http.Request, wssvc services.WebSocketService, user *models.User) {
+	lg := logger.FromContext(r.Context())
+	sessions, err := wssvc.GetUserSessions(r.Context(), user)
+	if err != nil {
+		lg.Error("Unable to list sessions of user", "err", err)
+		return
+	}
+	for _, session := range sessions {
+		if err = wssvc.CloseSession(r.Context(), user, session.Id); err != nil && !errors.Is(err, services.ErrSessionNotFound) {
+			lg.Error("Unable to close session of user", "sessionId", session.Id, "err", err)
+		}
+	}
+}
+
+// RemoveUserData cleans up after the deleted account, failures are only logged since the account is already gone.
+func RemoveUserData(r *http.Request, wssvc services.WebSocketService, user *models.User) {
+	if err := wssvc.RemoveUser(r.Context(), user); err != nil {
+		logger.FromContext(r.Context()).Error("Unable to remove data of deleted user", "err", err)
+	}
+}
+
+// ValidateProfileUpdate checks lengths of provided fields, avatar has to be an absolute http(s) URL.
+func ValidateProfileUpdate(update *models.ProfileUpdate) error {
+	if update.DisplayName != nil && utf8.RuneCountInString(*update.DisplayName) > models.DisplayNameMaxLength {
+		return fmt.Errorf("field 'displayName' is longer than %d", models.DisplayNameMaxLength)
+	}
+	if update.StatusText != nil && utf8.RuneCountInString(*update.StatusText) > models.StatusTextMaxLength {
+		return fmt.Errorf("field 'statusText' is longer than %d", models.StatusTextMaxLength)
+	}
+	if update.AvatarUrl != nil && *update.AvatarUrl != "" {
+		if len(*update.AvatarUrl) > models.AvatarUrlMaxLength {
+			return fmt.Errorf("field 'avatarUrl' is longer than %d", models.AvatarUrlMaxLength)
+		}
+		u, err := url.Parse(*update.AvatarUrl)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("field 'avatarUrl' must be an absolute http or https URL")
+		}
+	}
+	return nil
+}
+
+func composeProfileOutput(user *models.User) *ProfileOutput {
+	return &ProfileOutput{
+		Id:          user.Id,
+		UserName:    user.UserName,
+		DisplayName: user.DisplayName,
+		AvatarUrl:   user.AvatarUrl,
+		StatusText:  user.StatusText,
+		CreatedAt:   user.CreatedAt,
+		UpdatedAt:   user.UpdatedAt,
+	}
+}
+
+func sendProfile(w http.ResponseWriter, r *http.Request, usvc services.UserService, id string) {
+	user, err := usvc.FindUserById(r.Context(), id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	sendJsonResponse(w, composeProfileOutput(user), http.StatusOK)
+}
+
+func sendAccountError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPassword):
+		SendErrorJsonResponse(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, repositories.ErrUserNotFound):
+		SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+	default:
+		SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var profileTestUser = &models.User{
+	Id:          "1",
+	UserName:    "foo",
+	DisplayName: "Foo",
+	AvatarUrl:   "https://example.com/foo.png",
+	StatusText:  "busy",
+	CreatedAt:   10,
+	UpdatedAt:   20,
+}
+
+const profileTestBody = `{"id":"1","userName":"foo","displayName":"Foo","avatarUrl":"https://example.com/foo.png","statusText":"busy","createdAt":10,"updatedAt":20}`
+
+type profileHandlersTestData struct {
+	tName        string
+	body         string
+	wantCode     int
+	wantBody     string
+	prepareMocks func(*mocks.UserService, *mocks.TokenService, *mocks.WebSocketService)
+}
+
+func runProfileHandlerTests(t *testing.T, method, path, target string, handler func(*mocks.UserService, *mocks.TokenService, *mocks.WebSocketService) http.Handler, testConditions []profileHandlersTestData) {
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			us := new(mocks.UserService)
+			ts := new(mocks.TokenService)
+			ws := new(mocks.WebSocketService)
+			testCond.prepareMocks(us, ts, ws)
+			router := mux.NewRouter()
+			router.Handle(path, handler(us, ts, ws)).Methods(method)
+
+			req := httptest.NewRequest(method, target, bytes.NewBufferString(testCond.body))
+			req = req.WithContext(services.ContextWithUser(req.Context(), &models.User{Id: "1", UserName: "foo"}))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			us.AssertExpectations(t)
+			ts.AssertExpectations(t)
+			ws.AssertExpectations(t)
+		})
+	}
+}
+
+func TestProfileHandler(t *testing.T) {
+	runProfileHandlerTests(t, http.MethodGet, "/user/{id}", "/user/1", func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) http.Handler {
+		return ProfileHandler(us)
+	}, []profileHandlersTestData{
+		{
+			tName:    "should return profile",
+			wantCode: http.StatusOK,
+			wantBody: profileTestBody,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("FindUserById", mock.Anything, "1").Return(profileTestUser, nil)
+			},
+		},
+		{
+			tName:    "should fail with not found status",
+			wantCode: http.StatusNotFound,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusNotFound, repositories.ErrUserNotFound.Error()),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("FindUserById", mock.Anything, "1").Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+	})
+}
+
+func TestUpdateProfileHandler(t *testing.T) {
+	runProfileHandlerTests(t, http.MethodPatch, "/user/me", "/user/me", func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) http.Handler {
+		return UpdateProfileHandler(us)
+	}, []profileHandlersTestData{
+		{
+			tName:    "should update provided fields",
+			body:     `{"displayName":"Foo","statusText":""}`,
+			wantCode: http.StatusOK,
+			wantBody: profileTestBody,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("UpdateProfile", mock.Anything, "1", mock.MatchedBy(func(update *models.ProfileUpdate) bool {
+					return *update.DisplayName == "Foo" && *update.StatusText == "" && update.AvatarUrl == nil
+				})).Return(profileTestUser, nil)
+			},
+		},
+		{
+			tName:        "should reject relative avatar url",
+			body:         `{"avatarUrl":"/foo.png"}`,
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"field 'avatarUrl' must be an absolute http or https URL"}`, http.StatusBadRequest),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {},
+		},
+	})
+}
+
+func TestChangePasswordHandler(t *testing.T) {
+	runProfileHandlerTests(t, http.MethodPut, "/user/me/password", "/user/me/password", func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) http.Handler {
+		return ChangePasswordHandler(us, ts, ws)
+	}, []profileHandlersTestData{
+		{
+			tName:    "should change password, close sessions and issue new tokens",
+			body:     `{"currentPassword":"hello","newPassword":"secret"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"accessToken":"access","refreshToken":"refresh","tokenType":"Bearer","expiresIn":60}`,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				changed := &models.User{Id: "1", UserName: "foo", PasswordChangedAt: 1640995200}
+				us.On("ChangePassword", mock.Anything, "1", "hello", "secret").Return(changed, nil)
+				ws.On("GetUserSessions", mock.Anything, mock.Anything).Return([]*models.Session{{Id: "s1"}}, nil)
+				ws.On("CloseSession", mock.Anything, mock.Anything, "s1").Return(nil)
+				ts.On("IssueTokens", mock.Anything, changed).Return(&models.TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 60}, nil)
+			},
+		},
+		{
+			tName:    "should fail with forbidden status for wrong current password",
+			body:     `{"currentPassword":"hellO","newPassword":"secret"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusForbidden, services.ErrInvalidPassword.Error()),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("ChangePassword", mock.Anything, "1", "hellO", "secret").Return(nil, services.ErrInvalidPassword)
+			},
+		},
+		{
+			tName:        "should reject short password",
+			body:         `{"currentPassword":"hello","newPassword":"abc"}`,
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"field 'newPassword' was not provided inside body or length less than %d"}`, http.StatusBadRequest, models.PasswordMinLength),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {},
+		},
+	})
+}
+
+func TestDeleteAccountHandler(t *testing.T) {
+	runProfileHandlerTests(t, http.MethodDelete, "/user/me", "/user/me", func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) http.Handler {
+		return DeleteAccountHandler(us, ws)
+	}, []profileHandlersTestData{
+		{
+			tName:    "should delete account and remove its data",
+			body:     `{"password":"hello"}`,
+			wantCode: http.StatusNoContent,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("DeleteUser", mock.Anything, "1", "hello").Return(nil)
+				ws.On("RemoveUser", mock.Anything, mock.Anything).Return(nil)
+			},
+		},
+		{
+			tName:    "should delete account even if its data is not removed",
+			body:     `{"password":"hello"}`,
+			wantCode: http.StatusNoContent,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("DeleteUser", mock.Anything, "1", "hello").Return(nil)
+				ws.On("RemoveUser", mock.Anything, mock.Anything).Return(errors.New("Unable to remove"))
+			},
+		},
+		{
+			tName:    "should fail with forbidden status for wrong password",
+			body:     `{"password":"hellO"}`,
+			wantCode: http.StatusForbidden,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusForbidden, services.ErrInvalidPassword.Error()),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService, ws *mocks.WebSocketService) {
+				us.On("DeleteUser", mock.Anything, "1", "hellO").Return(services.ErrInvalidPassword)
+			},
+		},
+	})
+}
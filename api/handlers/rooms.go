@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+)
+
+type CreateRoomInput struct {
+	Name string `json:"name"`
+}
+
+type RoomOutput struct {
+	Id      string   `json:"id"`
+	Name    string   `json:"name"`
+	OwnerId string   `json:"ownerId"`
+	Members []string `json:"members"`
+}
+
+type RoomsOutput struct {
+	Rooms []*RoomOutput `json:"rooms"`
+}
+
+// CreateRoomHandler creates a room owned by the authenticated user.
+func CreateRoomHandler(rsvc services.RoomService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		v, err := ParseJsonBody(r, &CreateRoomInput{})
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		roomInputData := v.(*CreateRoomInput)
+		if err := validateRoomData(roomInputData); err != nil {
+			logger.FromContext(r.Context()).Debug("Invalid input", "err", err)
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		user, _ := services.UserFromContext(r.Context())
+		room, err := rsvc.CreateRoom(r.Context(), roomInputData.Name, user.Id)
+		if errors.Is(err, repositories.ErrRoomWithNameAlreadyExists) {
+			SendErrorJsonResponse(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeRoomOutput(room), http.StatusCreated)
+	}
+}
+
+func ListRoomsHandler(rsvc services.RoomService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rooms, err := rsvc.ListRooms(r.Context())
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := &RoomsOutput{Rooms: []*RoomOutput{}}
+		for _, room := range rooms {
+			out.Rooms = append(out.Rooms, composeRoomOutput(room))
+		}
+		sendJsonResponse(w, out, http.StatusOK)
+	}
+}
+
+// JoinRoomHandler adds the authenticated user to the room.
+func JoinRoomHandler(rsvc services.RoomService) http.HandlerFunc {
+	return roomMembershipHandler(rsvc.JoinRoom)
+}
+
+// LeaveRoomHandler removes the authenticated user from the room.
+func LeaveRoomHandler(rsvc services.RoomService) http.HandlerFunc {
+	return roomMembershipHandler(rsvc.LeaveRoom)
+}
+
+func roomMembershipHandler(update func(context.Context, string, string) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		err := update(r.Context(), mux.Vars(r)["id"], user.Id)
+		if errors.Is(err, repositories.ErrRoomNotFound) {
+			SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func composeRoomOutput(room *models.Room) *RoomOutput {
+	return &RoomOutput{
+		Id:      room.Id,
+		Name:    room.Name,
+		OwnerId: room.OwnerId,
+		Members: room.Members,
+	}
+}
+
+func validateRoomData(data *CreateRoomInput) error {
+	if len(data.Name) < models.RoomNameMinLength {
+		return fmt.Errorf("field 'name' was not provided inside body or length less than %d", models.RoomNameMinLength)
+	}
+	return nil
+}
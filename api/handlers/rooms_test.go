@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var roomsTestUser = &models.User{Id: "2", UserName: "bar"}
+
+type roomHandlersTestData struct {
+	payload      string
+	wantCode     int
+	wantBody     string
+	prepareMocks func(*mocks.RoomService)
+}
+
+func TestCreateRoomHandler(t *testing.T) {
+	fakeRoom := &models.Room{Id: "1", Name: "general", OwnerId: "2", Members: []string{"2"}}
+	ErrCreateRoom := errors.New("Unable to create room")
+	testConditions := []roomHandlersTestData{
+		{
+			payload:  `{"name":"general"}`,
+			wantCode: http.StatusCreated,
+			wantBody: `{"id":"1","name":"general","ownerId":"2","members":["2"]}`,
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("CreateRoom", mock.Anything, "general", "2").Return(fakeRoom, nil)
+			},
+		},
+		{
+			payload:      `{"name":"ge"}`,
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"field 'name' was not provided inside body or length less than 3"}`, http.StatusBadRequest),
+			prepareMocks: func(rs *mocks.RoomService) {},
+		},
+		{
+			payload:  `{"name":"general"}`,
+			wantCode: http.StatusConflict,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusConflict, repositories.ErrRoomWithNameAlreadyExists.Error()),
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("CreateRoom", mock.Anything, "general", "2").Return(nil, repositories.ErrRoomWithNameAlreadyExists)
+			},
+		},
+		{
+			payload:  `{"name":"general"}`,
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrCreateRoom.Error()),
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("CreateRoom", mock.Anything, "general", "2").Return(nil, ErrCreateRoom)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			rs := new(mocks.RoomService)
+			testCond.prepareMocks(rs)
+
+			req, err := http.NewRequest(http.MethodPost, "rooms", strings.NewReader(testCond.payload))
+			assert.Nil(t, err, "%v", err)
+			req = req.WithContext(services.ContextWithUser(req.Context(), roomsTestUser))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(CreateRoomHandler(rs))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			rs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListRoomsHandler(t *testing.T) {
+	ErrListRooms := errors.New("Unable to list rooms")
+	testConditions := []roomHandlersTestData{
+		{
+			wantCode: http.StatusOK,
+			wantBody: `{"rooms":[{"id":"1","name":"general","ownerId":"2","members":["2"]}]}`,
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("ListRooms", mock.Anything).Return([]*models.Room{{Id: "1", Name: "general", OwnerId: "2", Members: []string{"2"}}}, nil)
+			},
+		},
+		{
+			wantCode: http.StatusOK,
+			wantBody: `{"rooms":[]}`,
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("ListRooms", mock.Anything).Return(nil, nil)
+			},
+		},
+		{
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrListRooms.Error()),
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("ListRooms", mock.Anything).Return(nil, ErrListRooms)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			rs := new(mocks.RoomService)
+			testCond.prepareMocks(rs)
+
+			req, err := http.NewRequest(http.MethodGet, "rooms", nil)
+			assert.Nil(t, err, "%v", err)
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(ListRoomsHandler(rs))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			rs.AssertExpectations(t)
+		})
+	}
+}
+
+func TestJoinRoomHandler(t *testing.T) {
+	testConditions := []roomHandlersTestData{
+		{
+			wantCode: http.StatusNoContent,
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("JoinRoom", mock.Anything, "1", "2").Return(nil)
+			},
+		},
+		{
+			wantCode: http.StatusNotFound,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusNotFound, repositories.ErrRoomNotFound.Error()),
+			prepareMocks: func(rs *mocks.RoomService) {
+				rs.On("JoinRoom", mock.Anything, "1", "2").Return(repositories.ErrRoomNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			rs := new(mocks.RoomService)
+			testCond.prepareMocks(rs)
+
+			req, err := http.NewRequest(http.MethodPost, "rooms/1/join", nil)
+			assert.Nil(t, err, "%v", err)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			req = req.WithContext(services.ContextWithUser(req.Context(), roomsTestUser))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(JoinRoomHandler(rs))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			rs.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+)
+
+type SessionOutput struct {
+	Id          string `json:"id"`
+	UserAgent   string `json:"userAgent"`
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectedAt int64  `json:"connectedAt"`
+}
+
+type SessionsOutput struct {
+	Sessions []*SessionOutput `json:"sessions"`
+}
+
+// SessionsHandler lists web socket sessions of the authenticated user opened on any instance.
+func SessionsHandler(wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		sessions, err := wssvc.GetUserSessions(r.Context(), user)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		out := &SessionsOutput{Sessions: []*SessionOutput{}}
+		for _, session := range sessions {
+			out.Sessions = append(out.Sessions, composeSessionOutput(session))
+		}
+		sendJsonResponse(w, out, http.StatusOK)
+	}
+}
+
+// CloseSessionHandler disconnects one of the authenticated user's sessions.
+func CloseSessionHandler(wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := services.UserFromContext(r.Context())
+		err := wssvc.CloseSession(r.Context(), user, mux.Vars(r)["id"])
+		if errors.Is(err, services.ErrSessionNotFound) {
+			SendErrorJsonResponse(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func composeSessionOutput(session *models.Session) *SessionOutput {
+	return &SessionOutput{
+		Id:          session.Id,
+		UserAgent:   session.UserAgent,
+		RemoteAddr:  session.RemoteAddr,
+		ConnectedAt: session.ConnectedAt,
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type sessionHandlersTestData struct {
+	wantCode     int
+	wantBody     string
+	prepareMocks func(*mocks.WebSocketService)
+}
+
+var sessionsTestUser = &models.User{Id: "1", UserName: "foo"}
+
+func TestSessionsHandler(t *testing.T) {
+	errListSessions := errors.New("Unable to list sessions")
+	testConditions := []sessionHandlersTestData{
+		{
+			wantCode: http.StatusOK,
+			wantBody: `{"sessions":[{"id":"s1","userAgent":"firefox","remoteAddr":"127.0.0.1:5000","connectedAt":10}]}`,
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("GetUserSessions", mock.Anything, sessionsTestUser).Return([]*models.Session{
+					{Id: "s1", UserId: "1", UserAgent: "firefox", RemoteAddr: "127.0.0.1:5000", ConnectedAt: 10},
+				}, nil)
+			},
+		},
+		{
+			wantCode: http.StatusOK,
+			wantBody: `{"sessions":[]}`,
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("GetUserSessions", mock.Anything, sessionsTestUser).Return(nil, nil)
+			},
+		},
+		{
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, errListSessions.Error()),
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("GetUserSessions", mock.Anything, sessionsTestUser).Return(nil, errListSessions)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ws := new(mocks.WebSocketService)
+			testCond.prepareMocks(ws)
+
+			req, err := http.NewRequest(http.MethodGet, "user/sessions", nil)
+			assert.Nil(t, err, "%v", err)
+			req = req.WithContext(services.ContextWithUser(req.Context(), sessionsTestUser))
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(SessionsHandler(ws))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ws.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCloseSessionHandler(t *testing.T) {
+	errCloseSession := errors.New("Unable to close session")
+	testConditions := []sessionHandlersTestData{
+		{
+			wantCode: http.StatusNoContent,
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("CloseSession", mock.Anything, sessionsTestUser, "s1").Return(nil)
+			},
+		},
+		{
+			wantCode: http.StatusNotFound,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusNotFound, services.ErrSessionNotFound.Error()),
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("CloseSession", mock.Anything, sessionsTestUser, "s1").Return(services.ErrSessionNotFound)
+			},
+		},
+		{
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, errCloseSession.Error()),
+			prepareMocks: func(ws *mocks.WebSocketService) {
+				ws.On("CloseSession", mock.Anything, sessionsTestUser, "s1").Return(errCloseSession)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ws := new(mocks.WebSocketService)
+			testCond.prepareMocks(ws)
+
+			req, err := http.NewRequest(http.MethodDelete, "user/sessions/s1", nil)
+			assert.Nil(t, err, "%v", err)
+			req = mux.SetURLVars(req.WithContext(services.ContextWithUser(req.Context(), sessionsTestUser)), map[string]string{"id": "s1"})
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(CloseSessionHandler(ws))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ws.AssertExpectations(t)
+		})
+	}
+}
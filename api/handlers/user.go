@@ -3,12 +3,11 @@ package handlers
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 
 	"github.com/andriystech/lgc/db/repositories"
 	"github.com/andriystech/lgc/models"
-	"github.com/andriystech/lgc/pkg/hasher"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/andriystech/lgc/services"
 )
 
@@ -19,6 +18,14 @@ type RegisterOutput struct {
 
 type LoginOutput struct {
 	Url string `json:"url"`
+	TokensOutput
+}
+
+type TokensOutput struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+	TokenType    string `json:"tokenType"`
+	ExpiresIn    int    `json:"expiresIn"`
 }
 
 type ActiveConnectionsOutput struct {
@@ -29,6 +36,13 @@ type ActiveUsersOutput struct {
 	Users []string `json:"users"`
 }
 
+type SendQueueStatsOutput struct {
+	Capacity     int   `json:"capacity"`
+	Queued       int64 `json:"queued"`
+	Dropped      int64 `json:"dropped"`
+	Disconnected int64 `json:"disconnected"`
+}
+
 type RegisterInput struct {
 	UserName string `json:"userName"`
 	Password string `json:"password"`
@@ -39,6 +53,10 @@ type UserCredsInput struct {
 	Password string `json:"password"`
 }
 
+type RefreshTokenInput struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
 func RegisterUserHandler(usvc services.UserService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		v, err := ParseJsonBody(r, &RegisterInput{})
@@ -48,7 +66,7 @@ func RegisterUserHandler(usvc services.UserService) http.HandlerFunc {
 		}
 		userInputData := v.(*RegisterInput)
 		if err := validateUserRegistrationData(userInputData); err != nil {
-			log.Printf("Invalid input. Reason: %s", err.Error())
+			logger.FromContext(r.Context()).Debug("Invalid input", "err", err)
 			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -86,16 +104,64 @@ func LogInUserHandler(usvc services.UserService, tsvc services.TokenService) htt
 			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		if !hasher.CheckPasswordHash(c.Password, user.Password) {
+		if !usvc.CheckPassword(user, c.Password) {
 			SendErrorJsonResponse(w, http.StatusUnauthorized, "Unable to log in user. Reason: Invalid creds")
 			return
 		}
+		if err = usvc.UpgradePasswordHash(r.Context(), user, c.Password); err != nil {
+			logger.FromContext(r.Context()).Error("Unable to upgrade password hash of user", "userId", user.Id, "err", err)
+		}
 		token, err := tsvc.GenerateToken(r.Context(), user)
 		if err != nil {
 			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		sendJsonResponse(w, composeLoginOutput(r, token), http.StatusCreated)
+		tokens, err := tsvc.IssueTokens(r.Context(), user)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeLoginOutput(r, token, tokens), http.StatusCreated)
+	}
+}
+
+func RefreshTokenHandler(tsvc services.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refreshToken, err := fetchRefreshToken(r)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		tokens, err := tsvc.RefreshTokens(r.Context(), refreshToken)
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+			SendErrorJsonResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		sendJsonResponse(w, composeTokensOutput(tokens), http.StatusOK)
+	}
+}
+
+func LogOutUserHandler(tsvc services.TokenService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		refreshToken, err := fetchRefreshToken(r)
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		err = tsvc.RevokeRefreshToken(r.Context(), refreshToken)
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+			SendErrorJsonResponse(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			SendErrorJsonResponse(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
 	}
 }
 
@@ -125,10 +191,44 @@ func ActiveUsersHandler(wssvc services.WebSocketService) http.HandlerFunc {
 	}
 }
 
-func composeLoginOutput(r *http.Request, token *models.Token) *LoginOutput {
+func SendQueueStatsHandler(wssvc services.WebSocketService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := wssvc.GetSendQueueStats(r.Context())
+		sendJsonResponse(w, &SendQueueStatsOutput{
+			Capacity:     stats.Capacity,
+			Queued:       stats.Queued,
+			Dropped:      stats.Dropped,
+			Disconnected: stats.Disconnected,
+		}, http.StatusOK)
+	}
+}
+
+func composeLoginOutput(r *http.Request, token *models.Token, tokens *models.TokenPair) *LoginOutput {
 	return &LoginOutput{
-		Url: fmt.Sprintf("ws://%s/chat/ws.rtm.start?token=%s", r.Host, token.Payload),
+		Url:          fmt.Sprintf("ws://%s/chat/ws.rtm.start?token=%s", r.Host, token.Payload),
+		TokensOutput: *composeTokensOutput(tokens),
+	}
+}
+
+func composeTokensOutput(tokens *models.TokenPair) *TokensOutput {
+	return &TokensOutput{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+}
+
+func fetchRefreshToken(r *http.Request) (string, error) {
+	v, err := ParseJsonBody(r, &RefreshTokenInput{})
+	if err != nil {
+		return "", err
+	}
+	input := v.(*RefreshTokenInput)
+	if len(input.RefreshToken) == 0 {
+		return "", errors.New("field 'refreshToken' was not provided inside body")
 	}
+	return input.RefreshToken, nil
 }
 
 func fetchLogInCreds(r *http.Request) (*UserCredsInput, error) {
@@ -138,7 +238,7 @@ func fetchLogInCreds(r *http.Request) (*UserCredsInput, error) {
 	}
 	cred := v.(*UserCredsInput)
 	if err := validateCreds(cred); err != nil {
-		log.Printf("Invalid input. Reason: %s", err.Error())
+		logger.FromContext(r.Context()).Debug("Invalid input", "err", err)
 		return nil, err
 	}
 
@@ -3,14 +3,16 @@ package handlers
 import (
 	"errors"
 	"fmt"
-	"lgc/mocks"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/mocks"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -133,14 +135,33 @@ func TestLogInUserHandler(t *testing.T) {
 	fakeUsr := &models.User{UserName: "foobar", Password: "e0b50e3adb85ce07a41196709ba642886ba828a354acee42eae7559bc7c623981897f56020699ed61fa052f4784bf37e76eff016ee065d77bc158dd172eabd76"}
 	ErrFindUsrDb := errors.New("Unable to find user")
 	ErrTokenGenerate := errors.New("Unable to generate token")
+	ErrUpgradeHash := errors.New("Unable to update user password")
+	ErrIssueTokens := errors.New("Unable to issue tokens")
+	fakeTokens := &models.TokenPair{AccessToken: "access", RefreshToken: "refresh", ExpiresIn: 900}
+	wantLoginBody := fmt.Sprintf(`{"url":"ws:///chat/ws.rtm.start?token=%s","accessToken":"access","refreshToken":"refresh","tokenType":"Bearer","expiresIn":900}`, fakeToken)
 	testConditions := []logInUserHandlerTestData{
 		{
 			payload:  fmt.Sprintf(`{"userName":"%s","password":"%s"}`, "foobar", "qwerty123456"),
 			wantCode: http.StatusCreated,
-			wantBody: fmt.Sprintf(`{"url":"ws:///chat/ws.rtm.start?token=%s"}`, fakeToken),
+			wantBody: wantLoginBody,
 			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService) {
 				us.On("FindUserByName", mock.Anything, fakeUsr.UserName).Return(fakeUsr, nil)
+				us.On("CheckPassword", fakeUsr, "qwerty123456").Return(true)
+				us.On("UpgradePasswordHash", mock.Anything, fakeUsr, "qwerty123456").Return(nil)
 				ts.On("GenerateToken", mock.Anything, fakeUsr).Return(&models.Token{Payload: fakeToken}, nil)
+				ts.On("IssueTokens", mock.Anything, fakeUsr).Return(fakeTokens, nil)
+			},
+		},
+		{
+			payload:  fmt.Sprintf(`{"userName":"%s","password":"%s"}`, "foobar", "qwerty123456"),
+			wantCode: http.StatusCreated,
+			wantBody: wantLoginBody,
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService) {
+				us.On("FindUserByName", mock.Anything, fakeUsr.UserName).Return(fakeUsr, nil)
+				us.On("CheckPassword", fakeUsr, "qwerty123456").Return(true)
+				us.On("UpgradePasswordHash", mock.Anything, fakeUsr, "qwerty123456").Return(ErrUpgradeHash)
+				ts.On("GenerateToken", mock.Anything, fakeUsr).Return(&models.Token{Payload: fakeToken}, nil)
+				ts.On("IssueTokens", mock.Anything, fakeUsr).Return(fakeTokens, nil)
 			},
 		},
 		{
@@ -176,6 +197,7 @@ func TestLogInUserHandler(t *testing.T) {
 			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService) {
 				usr := &models.User{UserName: "foobar", Password: "qwerty123456"}
 				us.On("FindUserByName", mock.Anything, usr.UserName).Return(usr, nil)
+				us.On("CheckPassword", usr, "e0b50e").Return(false)
 			},
 		},
 		{
@@ -192,9 +214,23 @@ func TestLogInUserHandler(t *testing.T) {
 			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrTokenGenerate.Error()),
 			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService) {
 				us.On("FindUserByName", mock.Anything, fakeUsr.UserName).Return(fakeUsr, nil)
+				us.On("CheckPassword", fakeUsr, "qwerty123456").Return(true)
+				us.On("UpgradePasswordHash", mock.Anything, fakeUsr, "qwerty123456").Return(nil)
 				ts.On("GenerateToken", mock.Anything, fakeUsr).Return(nil, ErrTokenGenerate)
 			},
 		},
+		{
+			payload:  fmt.Sprintf(`{"userName":"%s","password":"%s"}`, "foobar", "qwerty123456"),
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrIssueTokens.Error()),
+			prepareMocks: func(us *mocks.UserService, ts *mocks.TokenService) {
+				us.On("FindUserByName", mock.Anything, fakeUsr.UserName).Return(fakeUsr, nil)
+				us.On("CheckPassword", fakeUsr, "qwerty123456").Return(true)
+				us.On("UpgradePasswordHash", mock.Anything, fakeUsr, "qwerty123456").Return(nil)
+				ts.On("GenerateToken", mock.Anything, fakeUsr).Return(&models.Token{Payload: fakeToken}, nil)
+				ts.On("IssueTokens", mock.Anything, fakeUsr).Return(nil, ErrIssueTokens)
+			},
+		},
 	}
 
 	for _, testCond := range testConditions {
@@ -298,3 +334,130 @@ func TestActiveUsersHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestRefreshTokenHandler(t *testing.T) {
+	ErrRefresh := errors.New("Unable to refresh tokens")
+	testConditions := []struct {
+		payload      string
+		wantCode     int
+		wantBody     string
+		prepareMocks func(*mocks.TokenService)
+	}{
+		{
+			payload:  `{"refreshToken":"refresh"}`,
+			wantCode: http.StatusOK,
+			wantBody: `{"accessToken":"access2","refreshToken":"refresh2","tokenType":"Bearer","expiresIn":900}`,
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("RefreshTokens", mock.Anything, "refresh").Return(&models.TokenPair{AccessToken: "access2", RefreshToken: "refresh2", ExpiresIn: 900}, nil)
+			},
+		},
+		{
+			payload:      `{}`,
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"field 'refreshToken' was not provided inside body"}`, http.StatusBadRequest),
+			prepareMocks: func(ts *mocks.TokenService) {},
+		},
+		{
+			payload:  `{"refreshToken":"refresh"}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusUnauthorized, services.ErrTokenRevoked.Error()),
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("RefreshTokens", mock.Anything, "refresh").Return(nil, services.ErrTokenRevoked)
+			},
+		},
+		{
+			payload:  `{"refreshToken":"refresh"}`,
+			wantCode: http.StatusInternalServerError,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusInternalServerError, ErrRefresh.Error()),
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("RefreshTokens", mock.Anything, "refresh").Return(nil, ErrRefresh)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ts := new(mocks.TokenService)
+			testCond.prepareMocks(ts)
+
+			req, err := http.NewRequest(http.MethodPost, "user/token/refresh", strings.NewReader(testCond.payload))
+			assert.Nil(t, err, "%v", err)
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(RefreshTokenHandler(ts))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ts.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLogOutUserHandler(t *testing.T) {
+	testConditions := []struct {
+		payload      string
+		wantCode     int
+		wantBody     string
+		prepareMocks func(*mocks.TokenService)
+	}{
+		{
+			payload:  `{"refreshToken":"refresh"}`,
+			wantCode: http.StatusNoContent,
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("RevokeRefreshToken", mock.Anything, "refresh").Return(nil)
+			},
+		},
+		{
+			payload:      `{"refreshToken":""}`,
+			wantCode:     http.StatusBadRequest,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"field 'refreshToken' was not provided inside body"}`, http.StatusBadRequest),
+			prepareMocks: func(ts *mocks.TokenService) {},
+		},
+		{
+			payload:  `{"refreshToken":"refresh"}`,
+			wantCode: http.StatusUnauthorized,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusUnauthorized, services.ErrInvalidToken.Error()),
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("RevokeRefreshToken", mock.Anything, "refresh").Return(services.ErrInvalidToken)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		tName := fmt.Sprintf("should respond with %d status and %s body", testCond.wantCode, testCond.wantBody)
+		t.Run(tName, func(t *testing.T) {
+			ts := new(mocks.TokenService)
+			testCond.prepareMocks(ts)
+
+			req, err := http.NewRequest(http.MethodPost, "user/logout", strings.NewReader(testCond.payload))
+			assert.Nil(t, err, "%v", err)
+
+			rr := httptest.NewRecorder()
+			handler := http.HandlerFunc(LogOutUserHandler(ts))
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ts.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendQueueStatsHandler(t *testing.T) {
+	wssvc := new(mocks.WebSocketService)
+	wssvc.On("GetSendQueueStats", mock.Anything).Return(ws.QueueStats{Capacity: 256, Queued: 3, Dropped: 2, Disconnected: 1})
+	wantBody := `{"capacity":256,"queued":3,"dropped":2,"disconnected":1}`
+
+	req, err := http.NewRequest(http.MethodGet, "user/active/queues", nil)
+	assert.Nil(t, err, "%v", err)
+
+	rr := httptest.NewRecorder()
+	handler := http.HandlerFunc(SendQueueStatsHandler(wssvc))
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	assert.Equal(t, wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), wantBody)
+	wssvc.AssertExpectations(t)
+}
@@ -0,0 +1,80 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/andriystech/lgc/api/handlers"
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/services"
+)
+
+const bearerPrefix = "Bearer "
+
+// Authenticate resolves the bearer access token of a request into the user stored in
+// the request context. Requests without Authorization header pass through anonymously,
+// requests with invalid or expired token are rejected.
+func Authenticate(tsvc services.TokenService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !strings.HasPrefix(header, bearerPrefix) {
+				sendUnauthorized(w, "Authorization header must use Bearer scheme")
+				return
+			}
+			user, err := tsvc.GetUserByAccessToken(r.Context(), strings.TrimPrefix(header, bearerPrefix))
+			if err != nil {
+				sendUnauthorized(w, err.Error())
+				return
+			}
+			annotateAccessLog(r.Context(), "userId", user.Id)
+			ctx := logger.With(services.ContextWithUser(r.Context(), user), "userId", user.Id)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireUser rejects requests which were not authenticated by Authenticate middleware.
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := services.UserFromContext(r.Context()); !ok {
+			sendUnauthorized(w, "Access token is missing")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Authorize restricts a route to the access level: public routes are open to everyone,
+// authenticated ones require a valid access token and admin ones additionally require
+// the user id to be listed in adminIds. Unknown access level denies every request.
+// It relies on the user resolved by Authenticate middleware.
+func Authorize(access string, adminIds []string) func(http.Handler) http.Handler {
+	admins := make(map[string]bool, len(adminIds))
+	for _, id := range adminIds {
+		admins[id] = true
+	}
+	return func(next http.Handler) http.Handler {
+		if access == config.AccessPublic {
+			return next
+		}
+		return RequireUser(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := services.UserFromContext(r.Context())
+			if access != config.AccessAuthenticated && !admins[user.Id] {
+				handlers.SendErrorJsonResponse(w, http.StatusForbidden, "Access denied")
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+func sendUnauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	handlers.SendErrorJsonResponse(w, http.StatusUnauthorized, message)
+}
@@ -0,0 +1,134 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuthenticate(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	testConditions := []struct {
+		tName        string
+		header       string
+		requireUser  bool
+		wantCode     int
+		wantBody     string
+		prepareMocks func(*mocks.TokenService)
+	}{
+		{
+			tName:        "should pass anonymous request",
+			wantCode:     http.StatusOK,
+			wantBody:     "anonymous",
+			prepareMocks: func(ts *mocks.TokenService) {},
+		},
+		{
+			tName:    "should pass authenticated user",
+			header:   "Bearer access",
+			wantCode: http.StatusOK,
+			wantBody: usr.Id,
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("GetUserByAccessToken", mock.Anything, "access").Return(usr, nil)
+			},
+		},
+		{
+			tName:    "should reject invalid token",
+			header:   "Bearer access",
+			wantCode: http.StatusUnauthorized,
+			wantBody: fmt.Sprintf(`{"status":%d,"message":"%s"}`, http.StatusUnauthorized, services.ErrInvalidToken.Error()),
+			prepareMocks: func(ts *mocks.TokenService) {
+				ts.On("GetUserByAccessToken", mock.Anything, "access").Return(nil, services.ErrInvalidToken)
+			},
+		},
+		{
+			tName:        "should reject unsupported scheme",
+			header:       "Basic Zm9vOmJhcg==",
+			wantCode:     http.StatusUnauthorized,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Authorization header must use Bearer scheme"}`, http.StatusUnauthorized),
+			prepareMocks: func(ts *mocks.TokenService) {},
+		},
+		{
+			tName:        "should reject anonymous request to protected route",
+			requireUser:  true,
+			wantCode:     http.StatusUnauthorized,
+			wantBody:     fmt.Sprintf(`{"status":%d,"message":"Access token is missing"}`, http.StatusUnauthorized),
+			prepareMocks: func(ts *mocks.TokenService) {},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ts := new(mocks.TokenService)
+			testCond.prepareMocks(ts)
+			var next http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if user, ok := services.UserFromContext(r.Context()); ok {
+					fmt.Fprint(w, user.Id)
+					return
+				}
+				fmt.Fprint(w, "anonymous")
+			})
+			if testCond.requireUser {
+				next = RequireUser(next)
+			}
+			handler := Authenticate(ts)(next)
+
+			req, err := http.NewRequest(http.MethodGet, "messages", nil)
+			assert.Nil(t, err, "%v", err)
+			if testCond.header != "" {
+				req.Header.Set("Authorization", testCond.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantBody, rr.Body.String(), "handler returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			ts.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	admin := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "admin"}
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46", UserName: "foo"}
+	adminIds := []string{admin.Id}
+	testConditions := []struct {
+		tName    string
+		access   string
+		user     *models.User
+		wantCode int
+	}{
+		{tName: "should pass anonymous user to public route", access: config.AccessPublic, wantCode: http.StatusOK},
+		{tName: "should reject anonymous user on authenticated route", access: config.AccessAuthenticated, wantCode: http.StatusUnauthorized},
+		{tName: "should pass user to authenticated route", access: config.AccessAuthenticated, user: usr, wantCode: http.StatusOK},
+		{tName: "should reject anonymous user on admin route", access: config.AccessAdmin, wantCode: http.StatusUnauthorized},
+		{tName: "should forbid admin route for user", access: config.AccessAdmin, user: usr, wantCode: http.StatusForbidden},
+		{tName: "should pass admin to admin route", access: config.AccessAdmin, user: admin, wantCode: http.StatusOK},
+		{tName: "should forbid route with unknown access for user", access: "everyone", user: usr, wantCode: http.StatusForbidden},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			handler := Authorize(testCond.access, adminIds)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			req, err := http.NewRequest(http.MethodGet, "user/active", nil)
+			assert.Nil(t, err, "%v", err)
+			if testCond.user != nil {
+				req = req.WithContext(services.ContextWithUser(req.Context(), testCond.user))
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "handler returned wrong status code: got %v want %v", rr.Code, testCond.wantCode)
+		})
+	}
+}
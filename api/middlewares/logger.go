@@ -1,26 +1,90 @@
 package middlewares
 
 import (
+	"context"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/andriystech/lgc/api/handlers"
-	gorilla "github.com/gorilla/handlers"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/google/uuid"
 )
 
-func LogHttpCalls(dst io.Writer) func(http.Handler) http.Handler {
-	return func(h http.Handler) http.Handler {
-		return gorilla.LoggingHandler(dst, h)
+// RequestIdHeader carries id which correlates logs of a request, it is echoed in the response.
+const RequestIdHeader = "X-Request-ID"
+
+const maxRequestIdLength = 128
+
+// RequestId stores logger tagged with the request id in the request context. Id sent by
+// a client or a proxy is kept when it is reasonably short and printable, otherwise a new one is generated.
+func RequestId(lg *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIdHeader)
+			if !validRequestId(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIdHeader, id)
+			ctx := logger.NewContext(r.Context(), lg.With("requestId", id))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func validRequestId(id string) bool {
+	if id == "" || len(id) > maxRequestIdLength {
+		return false
+	}
+	for _, r := range id {
+		if r < '!' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+type accessLogKey struct{}
+
+// accessLog collects pairs added by inner middlewares, e.g. user id resolved by Authenticate,
+// which are written with the access log entry.
+type accessLog struct {
+	keyvals []interface{}
+}
+
+// annotateAccessLog adds the key value pairs to the access log entry of the request.
+func annotateAccessLog(ctx context.Context, keyvals ...interface{}) {
+	if al, ok := ctx.Value(accessLogKey{}).(*accessLog); ok {
+		al.keyvals = append(al.keyvals, keyvals...)
 	}
 }
 
+// LogHttpCalls writes an entry per request with the logger stored in the request context by RequestId.
+// Web socket requests are logged once the connection is closed.
+func LogHttpCalls(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		al := &accessLog{}
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, al)))
+
+		keyvals := []interface{}{
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.statusCode(),
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remoteAddr", r.RemoteAddr,
+		}
+		logger.FromContext(r.Context()).Info("Served request", append(keyvals, al.keyvals...)...)
+	})
+}
+
 func PanicAndRecover(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Println(err)
+				logger.FromContext(r.Context()).Error("Recovered from panic", "err", fmt.Sprint(err))
 				handlers.SendErrorJsonResponse(w, http.StatusInternalServerError, fmt.Sprint(err))
 			}
 		}()
@@ -0,0 +1,95 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequestId(t *testing.T) {
+	testConditions := []struct {
+		tName    string
+		header   string
+		wantSame bool
+	}{
+		{tName: "should keep request id sent by client", header: "req-42", wantSame: true},
+		{tName: "should generate missing request id", header: ""},
+		{tName: "should replace request id with spaces", header: "req 42"},
+		{tName: "should replace too long request id", header: strings.Repeat("a", maxRequestIdLength+1)},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			handler := RequestId(logger.New(out, logger.LevelInfo, logger.FormatJSON))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger.FromContext(r.Context()).Info("Handled")
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/messages", nil)
+			if testCond.header != "" {
+				req.Header.Set(RequestIdHeader, testCond.header)
+			}
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			got := rr.Header().Get(RequestIdHeader)
+			if testCond.wantSame {
+				assert.Equal(t, testCond.header, got, "RequestId returned unexpected id: got %v want %v", got, testCond.header)
+			} else {
+				assert.NotEqual(t, testCond.header, got, "RequestId kept invalid id %v", got)
+				assert.Len(t, got, 36, "RequestId generated unexpected id: got %v want uuid", got)
+			}
+			entry := map[string]interface{}{}
+			assert.Nil(t, json.Unmarshal(out.Bytes(), &entry), "logger wrote invalid entry %s", out.String())
+			assert.Equal(t, got, entry["requestId"], "logger wrote unexpected request id: got %v want %v", entry["requestId"], got)
+		})
+	}
+}
+
+func TestLogHttpCalls(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	ts := new(mocks.TokenService)
+	ts.On("GetUserByAccessToken", mock.Anything, "access").Return(usr, nil)
+	out := &bytes.Buffer{}
+	handler := RequestId(logger.New(out, logger.LevelInfo, logger.FormatJSON))(LogHttpCalls(Authenticate(ts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger.FromContext(r.Context()).Info("Handled")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte("created"))
+	}))))
+	req := httptest.NewRequest(http.MethodPost, "/rooms", nil)
+	req.Header.Set(RequestIdHeader, "req-42")
+	req.Header.Set("Authorization", "Bearer access")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if !assert.Len(t, lines, 2, "LogHttpCalls wrote unexpected entries: %s", out.String()) {
+		return
+	}
+	handled, access := map[string]interface{}{}, map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal([]byte(lines[0]), &handled), "logger wrote invalid entry %s", lines[0])
+	assert.Nil(t, json.Unmarshal([]byte(lines[1]), &access), "logger wrote invalid entry %s", lines[1])
+	assert.Equal(t, usr.Id, handled["userId"], "handler logged unexpected user id: got %v want %v", handled["userId"], usr.Id)
+	want := map[string]interface{}{
+		"msg":       "Served request",
+		"requestId": "req-42",
+		"userId":    usr.Id,
+		"method":    http.MethodPost,
+		"path":      "/rooms",
+		"status":    float64(http.StatusCreated),
+		"bytes":     float64(len("created")),
+	}
+	for key, value := range want {
+		assert.Equal(t, value, access[key], "LogHttpCalls wrote unexpected %s: got %v want %v", key, access[key], value)
+	}
+	ts.AssertExpectations(t)
+}
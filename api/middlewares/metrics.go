@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andriystech/lgc/pkg/metrics"
+	"github.com/gorilla/mux"
+)
+
+// UnmatchedRoute labels requests which did not match any route.
+const UnmatchedRoute = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounter(
+		"lgc_http_requests_total",
+		"Served HTTP requests by method, route and status.",
+		"method", "route", "status",
+	)
+	httpRequestDuration = metrics.NewHistogram(
+		"lgc_http_request_duration_seconds",
+		"Latency of HTTP requests by method, route and status, web socket requests are measured until the upgrade.",
+		metrics.DefaultBuckets,
+		"method", "route", "status",
+	)
+)
+
+// MeasureHttpCalls counts requests and observes their latency. Requests are labelled with
+// the route template returned by route rather than the path, so ids in paths do not
+// produce new series.
+func MeasureHttpCalls(route func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := &statusRecorder{ResponseWriter: w}
+			next.ServeHTTP(rec, r)
+
+			elapsed := time.Since(start)
+			if !rec.hijackedAt.IsZero() {
+				elapsed = rec.hijackedAt.Sub(start)
+			}
+			labels := []string{r.Method, route(r), strconv.Itoa(rec.statusCode())}
+			httpRequests.Inc(labels...)
+			httpRequestDuration.Observe(elapsed.Seconds(), labels...)
+		})
+	}
+}
+
+// MeasureUnmatchedRoutes counts responses of the router to requests which matched no route or method,
+// gorilla router serves them without the middlewares installed by Use.
+func MeasureUnmatchedRoutes(router *mux.Router, measure func(http.Handler) http.Handler) {
+	router.NotFoundHandler = measure(http.NotFoundHandler())
+	router.MethodNotAllowedHandler = measure(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}))
+}
+
+// MuxRoute returns path template of the route matched by gorilla router.
+func MuxRoute(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return UnmatchedRoute
+}
+
+// statusRecorder remembers response status and size and keeps hijacking available for web sockets.
+type statusRecorder struct {
+	http.ResponseWriter
+	status     int
+	bytes      int
+	hijackedAt time.Time
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	if sr.status == 0 {
+		sr.status = status
+	}
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += n
+	return n, err
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := sr.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		sr.hijackedAt = time.Now()
+	}
+	return conn, rw, err
+}
+
+// statusCode treats hijacked connections as switched protocols, upgrader writes the status itself.
+func (sr *statusRecorder) statusCode() int {
+	switch {
+	case sr.status != 0:
+		return sr.status
+	case !sr.hijackedAt.IsZero():
+		return http.StatusSwitchingProtocols
+	default:
+		return http.StatusOK
+	}
+}
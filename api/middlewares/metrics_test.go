@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMeasureHttpCalls(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MeasureHttpCalls(MuxRoute))
+	router.HandleFunc("/test/rooms/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}).Methods("GET")
+	testConditions := []struct {
+		tName  string
+		path   string
+		status string
+	}{
+		{tName: "should label request with route template", path: "/test/rooms/1", status: "200"},
+		{tName: "should label request with written status", path: "/test/rooms/missing", status: "404"},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			before := httpRequests.Value(http.MethodGet, "/test/rooms/{id}", testCond.status)
+			observed := httpRequestDuration.Count(http.MethodGet, "/test/rooms/{id}", testCond.status)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, testCond.path, nil))
+
+			got := httpRequests.Value(http.MethodGet, "/test/rooms/{id}", testCond.status) - before
+			assert.Equal(t, 1.0, got, "MeasureHttpCalls counted unexpected requests: got %v want %v", got, 1)
+			gotObserved := httpRequestDuration.Count(http.MethodGet, "/test/rooms/{id}", testCond.status) - observed
+			assert.Equal(t, uint64(1), gotObserved, "MeasureHttpCalls observed unexpected latencies: got %v want %v", gotObserved, 1)
+		})
+	}
+}
+
+func TestMeasureUnmatchedRoutes(t *testing.T) {
+	router := mux.NewRouter()
+	measure := MeasureHttpCalls(MuxRoute)
+	router.Use(measure)
+	MeasureUnmatchedRoutes(router, measure)
+	router.HandleFunc("/test/unmatched", func(w http.ResponseWriter, r *http.Request) {}).Methods("GET")
+	testConditions := []struct {
+		tName  string
+		method string
+		path   string
+		status int
+	}{
+		{tName: "should count request of unknown route", method: http.MethodGet, path: "/test/missing", status: http.StatusNotFound},
+		{tName: "should count request of unknown method", method: http.MethodPost, path: "/test/unmatched", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			status := strconv.Itoa(testCond.status)
+			before := httpRequests.Value(testCond.method, UnmatchedRoute, status)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, httptest.NewRequest(testCond.method, testCond.path, nil))
+
+			assert.Equal(t, testCond.status, rr.Code, "router returned unexpected status: got %v want %v", rr.Code, testCond.status)
+			got := httpRequests.Value(testCond.method, UnmatchedRoute, status) - before
+			assert.Equal(t, 1.0, got, "MeasureHttpCalls counted unexpected requests: got %v want %v", got, 1)
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/andriystech/lgc/api/handlers"
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/pkg/ratelimit"
+	"github.com/andriystech/lgc/services"
+)
+
+// LimitLogin slows down password guessing both from a single address and against a single account.
+func LimitLogin(cnf *config.ServerConfig) func(http.Handler) http.Handler {
+	rate := ratelimit.PerMinute(cnf.LoginRateLimitPerMinute)
+	byIP := LimitByIP(ratelimit.New(rate, cnf.LoginRateLimitBurst))
+	byUserName := LimitByUserName(ratelimit.New(rate, cnf.LoginRateLimitBurst))
+	return func(next http.Handler) http.Handler {
+		return byIP(byUserName(next))
+	}
+}
+
+// LimitPasswordChecks applies the login limits to requests which verify password of the authenticated user,
+// counting attempts per client address and per account. The returned middleware shares its state between routes.
+func LimitPasswordChecks(cnf *config.ServerConfig) func(http.Handler) http.Handler {
+	rate := ratelimit.PerMinute(cnf.LoginRateLimitPerMinute)
+	byIP := LimitByIP(ratelimit.New(rate, cnf.LoginRateLimitBurst))
+	byUser := LimitByUser(ratelimit.New(rate, cnf.LoginRateLimitBurst))
+	return func(next http.Handler) http.Handler {
+		return byIP(byUser(next))
+	}
+}
+
+func LimitRegistration(cnf *config.ServerConfig) func(http.Handler) http.Handler {
+	return LimitByIP(ratelimit.New(ratelimit.PerMinute(cnf.RegisterRateLimitPerMinute), cnf.RegisterRateLimitBurst))
+}
+
+// LimitByIP rejects requests of a client address which exceeded the limit.
+func LimitByIP(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return rateLimit(l, clientIP)
+}
+
+// LimitByUserName rejects requests carrying JSON body with userName which exceeded the limit,
+// so brute force of a single account is stopped regardless of client addresses.
+// Requests without user name pass through to be rejected by the handler.
+func LimitByUserName(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return rateLimit(l, bodyUserName)
+}
+
+// LimitByUser rejects requests of the authenticated user which exceeded the limit.
+// Anonymous requests pass through to be rejected by the handler.
+func LimitByUser(l *ratelimit.Limiter) func(http.Handler) http.Handler {
+	return rateLimit(l, contextUserId)
+}
+
+func rateLimit(l *ratelimit.Limiter, key func(*http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k := key(r); k != "" {
+				if ok, wait := l.Allow(k); !ok {
+					sendTooManyRequests(w, wait)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func contextUserId(r *http.Request) string {
+	if user, ok := services.UserFromContext(r.Context()); ok {
+		return user.Id
+	}
+	return ""
+}
+
+// maxCredentialsSize caps the body peeked by the limiter, credentials fit into a few kilobytes.
+const maxCredentialsSize = 4 << 10
+
+// bodyUserName peeks at the request body leaving it readable by the next handler.
+// Oversized body is not parsed, the next handler fails to read it past the cap.
+func bodyUserName(r *http.Request) string {
+	limited := http.MaxBytesReader(nil, r.Body, maxCredentialsSize)
+	body, err := ioutil.ReadAll(limited)
+	r.Body = ioutil.NopCloser(io.MultiReader(bytes.NewReader(body), limited))
+	if err != nil {
+		return ""
+	}
+	creds := &struct {
+		UserName string `json:"userName"`
+	}{}
+	if err = json.Unmarshal(body, creds); err != nil {
+		return ""
+	}
+	return creds.UserName
+}
+
+func sendTooManyRequests(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	handlers.SendErrorJsonResponse(w, http.StatusTooManyRequests, "Too many requests, try again later")
+}
@@ -0,0 +1,126 @@
+package middlewares
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/ratelimit"
+	"github.com/andriystech/lgc/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitByIP(t *testing.T) {
+	limit := LimitByIP(ratelimit.New(ratelimit.PerMinute(1), 1))
+	next := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	testConditions := []struct {
+		tName      string
+		remoteAddr string
+		wantCode   int
+		wantRetry  string
+	}{
+		{tName: "should pass first request", remoteAddr: "10.0.0.1:1000", wantCode: http.StatusOK},
+		{tName: "should reject request from the same address", remoteAddr: "10.0.0.1:2000", wantCode: http.StatusTooManyRequests, wantRetry: "60"},
+		{tName: "should pass request from another address", remoteAddr: "10.0.0.2:1000", wantCode: http.StatusOK},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/login", nil)
+			req.RemoteAddr = testCond.remoteAddr
+			rr := httptest.NewRecorder()
+
+			next.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "LimitByIP returned unexpected status: got %v want %v", rr.Code, testCond.wantCode)
+			assert.Equal(t, testCond.wantRetry, rr.Header().Get("Retry-After"), "LimitByIP returned unexpected Retry-After: got %v want %v", rr.Header().Get("Retry-After"), testCond.wantRetry)
+		})
+	}
+}
+
+func TestLimitByUserName(t *testing.T) {
+	limit := LimitByUserName(ratelimit.New(ratelimit.PerMinute(1), 1))
+	next := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	}))
+	testConditions := []struct {
+		tName    string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{tName: "should pass first attempt and keep body", body: `{"userName":"foo"}`, wantCode: http.StatusOK, wantBody: `{"userName":"foo"}`},
+		{tName: "should reject next attempt for the same user", body: `{"userName":"foo","password":"x"}`, wantCode: http.StatusTooManyRequests},
+		{tName: "should pass attempt for another user", body: `{"userName":"bar"}`, wantCode: http.StatusOK, wantBody: `{"userName":"bar"}`},
+		{tName: "should pass request without user name", body: `not json`, wantCode: http.StatusOK, wantBody: `not json`},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(testCond.body))
+			rr := httptest.NewRecorder()
+
+			next.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "LimitByUserName returned unexpected status: got %v want %v", rr.Code, testCond.wantCode)
+			if testCond.wantBody != "" {
+				assert.Equal(t, testCond.wantBody, rr.Body.String(), "LimitByUserName returned unexpected body: got %v want %v", rr.Body.String(), testCond.wantBody)
+			}
+		})
+	}
+}
+
+func TestLimitByUserNameCapsBody(t *testing.T) {
+	limit := LimitByUserName(ratelimit.New(ratelimit.PerMinute(1), 1))
+	var readErr error
+	next := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = ioutil.ReadAll(r.Body)
+	}))
+	body := `{"userName":"foo","password":"` + strings.Repeat("x", maxCredentialsSize) + `"}`
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		next.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/user/login", strings.NewReader(body)))
+
+		assert.Equal(t, http.StatusOK, rr.Code, "LimitByUserName returned unexpected status: got %v want %v", rr.Code, http.StatusOK)
+		assert.NotNil(t, readErr, "LimitByUserName passed oversized body to the next handler")
+	}
+}
+
+func TestLimitByUser(t *testing.T) {
+	limit := LimitByUser(ratelimit.New(ratelimit.PerMinute(1), 1))
+	next := limit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "ok")
+	}))
+	testConditions := []struct {
+		tName    string
+		user     *models.User
+		wantCode int
+	}{
+		{tName: "should pass first attempt", user: &models.User{Id: "1"}, wantCode: http.StatusOK},
+		{tName: "should reject next attempt of the same user", user: &models.User{Id: "1"}, wantCode: http.StatusTooManyRequests},
+		{tName: "should pass attempt of another user", user: &models.User{Id: "2"}, wantCode: http.StatusOK},
+		{tName: "should pass anonymous request", wantCode: http.StatusOK},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/user/me/password", nil)
+			if testCond.user != nil {
+				req = req.WithContext(services.ContextWithUser(req.Context(), testCond.user))
+			}
+			rr := httptest.NewRecorder()
+
+			next.ServeHTTP(rr, req)
+
+			assert.Equal(t, testCond.wantCode, rr.Code, "LimitByUser returned unexpected status: got %v want %v", rr.Code, testCond.wantCode)
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package restapi
+
+import (
+	"github.com/andriystech/lgc/api/restapi/handlers"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/services"
+)
+
+// Application bundles API handlers with background jobs and middlewares sharing the same dependencies.
+type Application struct {
+	Handlers         handlers.Handlers
+	TokensJanitor    services.TokensJanitor
+	TokenService     services.TokenService
+	WebSocketService services.WebSocketService
+	HealthService    services.HealthService
+	Logger           *logger.Logger
+}
@@ -5,20 +5,27 @@ package restapi
 import (
 	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	_ "net/http/pprof"
-	"os"
 	"time"
 
 	"github.com/go-openapi/errors"
 	"github.com/go-openapi/runtime"
+	"github.com/go-openapi/runtime/middleware"
 
+	httpHandlers "github.com/andriystech/lgc/api/handlers"
 	"github.com/andriystech/lgc/api/middlewares"
 	"github.com/andriystech/lgc/api/restapi/operations"
 	"github.com/andriystech/lgc/api/restapi/operations/chat"
+	"github.com/andriystech/lgc/api/restapi/operations/messages"
 	"github.com/andriystech/lgc/api/restapi/operations/user"
 	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/logging"
 	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/pkg/metrics"
 )
 
 //go:generate swagger generate server --target ../../api --name LetsGoChat --spec ../../swagger.yml --principal interface{}
@@ -45,33 +52,101 @@ func configureAPI(api *operations.LetsGoChatAPI) http.Handler {
 
 	api.JSONProducer = runtime.JSONProducer()
 
-	serverConfig := config.GetServerConfig()
-	ctx, cancel := context.WithTimeout(
-		context.Background(),
-		time.Duration(serverConfig.DbConnectionTimeoutInSeconds),
-	)
+	// swagger server owns the command line, so config is read from CONFIG_FILE and environment
+	serverConfig, _, err := config.Load(nil)
+	if err != nil {
+		panic(err)
+	}
+	lg := logging.NewLogger(serverConfig)
+	logger.SetDefault(lg)
+	api.Logger = func(format string, args ...interface{}) {
+		lg.Info(fmt.Sprintf(format, args...))
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.DbConnectionTimeout)
 	db, err := mongo.NewClient(serverConfig)
 	if err != nil {
 		panic(err)
 	}
 	db.Connect(ctx)
 
-	handlers := InitializeHandlers(db)
+	repositories.MigrateMessages(
+		logger.NewContext(context.Background(), lg),
+		serverConfig,
+		mongo.NewMessagesCollection(db, serverConfig),
+		mongo.NewDeliveriesCollection(db, serverConfig),
+	)
+
+	app := InitializeApplication(db, serverConfig, lg)
+	handlers := app.Handlers
+	jobsCtx, stopJobs := context.WithCancel(logger.NewContext(context.Background(), app.Logger))
+	go app.TokensJanitor.Run(jobsCtx)
+	go app.WebSocketService.Run(jobsCtx)
 
 	api.UserCreateUserHandler = user.CreateUserHandlerFunc(handlers.RegisterUser)
 	api.ChatGetActiveUsersHandler = chat.GetActiveUsersHandlerFunc(handlers.GetActiveUsers)
 	api.ChatGetActiveUsersCountHandler = chat.GetActiveUsersCountHandlerFunc(handlers.GetActiveUsersCount)
 	api.UserLoginUserHandler = user.LoginUserHandlerFunc(handlers.LoginUser)
+	api.UserRefreshTokenHandler = user.RefreshTokenHandlerFunc(handlers.RefreshToken)
+	api.UserLogoutUserHandler = user.LogoutUserHandlerFunc(handlers.LogoutUser)
+	api.UserGetUserProfileHandler = user.GetUserProfileHandlerFunc(handlers.GetUserProfile)
+	api.UserGetOwnProfileHandler = user.GetOwnProfileHandlerFunc(handlers.GetOwnProfile)
+	api.UserUpdateOwnProfileHandler = user.UpdateOwnProfileHandlerFunc(handlers.UpdateOwnProfile)
+	api.UserChangePasswordHandler = user.ChangePasswordHandlerFunc(handlers.ChangePassword)
+	api.UserDeleteAccountHandler = user.DeleteAccountHandlerFunc(handlers.DeleteAccount)
 	api.ChatWsRTMStartHandler = chat.WsRTMStartHandlerFunc(handlers.StartChat)
-
-	api.PreServerShutdown = func() {}
+	api.MessagesGetMessagesHandler = messages.GetMessagesHandlerFunc(handlers.GetMessages)
+
+	shutdownTimeout := serverConfig.ShutdownTimeout
+	drainDelay := serverConfig.ShutdownDrainDelay
+	api.PreServerShutdown = func() {
+		app.HealthService.Shutdown()
+		// listeners are closed after this hook, keep serving until load balancers notice the failing readiness probe
+		time.Sleep(drainDelay)
+		shutdownCtx, cancelShutdown := context.WithTimeout(logger.NewContext(context.Background(), app.Logger), shutdownTimeout)
+		defer cancelShutdown()
+		if err := app.WebSocketService.Shutdown(shutdownCtx); err != nil {
+			app.Logger.Error("Unable to close web socket connections", "err", err)
+		}
+	}
 
 	api.ServerShutdown = func() {
+		stopJobs()
 		cancel()
-		db.Disconnect(ctx)
+		disconnectCtx, cancelDisconnect := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelDisconnect()
+		if err := db.Disconnect(disconnectCtx); err != nil {
+			app.Logger.Error("Unable to disconnect from database", "err", err)
+		}
 	}
 
-	return setupGlobalMiddleware(api.Serve(setupMiddlewares))
+	operationsAccess := map[string]string{
+		"getActiveUsers":      serverConfig.MonitoringAccess,
+		"getActiveUsersCount": serverConfig.MonitoringAccess,
+		"getMessages":         config.AccessAuthenticated,
+		"getUserProfile":      config.AccessAuthenticated,
+		"getOwnProfile":       config.AccessAuthenticated,
+		"updateOwnProfile":    config.AccessAuthenticated,
+		"changePassword":      config.AccessAuthenticated,
+		"deleteAccount":       config.AccessAuthenticated,
+	}
+	authorize := authorizeOperations(operationsAccess, serverConfig.AdminUserIds)
+	passwordChecks := middlewares.LimitPasswordChecks(serverConfig)
+	limit := limitOperations(map[string]func(http.Handler) http.Handler{
+		"loginUser":      middlewares.LimitLogin(serverConfig),
+		"createUser":     middlewares.LimitRegistration(serverConfig),
+		"changePassword": passwordChecks,
+		"deleteAccount":  passwordChecks,
+	})
+
+	operational := serveOperationalRoutes(map[string]http.Handler{
+		"/_health/live":  httpHandlers.LivenessHandler(app.HealthService),
+		"/_health/ready": httpHandlers.ReadinessHandler(app.HealthService),
+		"/metrics":       middlewares.Authorize(serverConfig.MonitoringAccess, serverConfig.AdminUserIds)(httpHandlers.MetricsHandler(metrics.DefaultRegistry)),
+	})
+
+	return middlewares.RequestId(app.Logger)(middlewares.LogHttpCalls(middlewares.Authenticate(app.TokenService)(operational(setupGlobalMiddleware(api.Serve(func(handler http.Handler) http.Handler {
+		return setupMiddlewares(limit(authorize(handler)))
+	}))))))
 }
 
 // The TLS configuration before HTTPS server starts.
@@ -92,7 +167,67 @@ func configureServer(s *http.Server, scheme, addr string) {
 // The middleware configuration is for the handler executors. These do not apply to the swagger.json document.
 // The middleware executes after routing but before authentication, binding and validation.
 func setupMiddlewares(handler http.Handler) http.Handler {
-	return middlewares.PanicAndRecover(middlewares.LogHttpCalls(os.Stdout)(handler))
+	return middlewares.MeasureHttpCalls(operationRoute)(middlewares.PanicAndRecover(handler))
+}
+
+// operationRoute labels metrics of a request with the path pattern of the matched operation.
+func operationRoute(r *http.Request) string {
+	if route := middleware.MatchedRouteFrom(r); route != nil {
+		return route.PathPattern
+	}
+	return middlewares.UnmatchedRoute
+}
+
+// serveOperationalRoutes serves probes and metrics next to the API, these GET endpoints are not a part of the spec.
+func serveOperationalRoutes(routes map[string]http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if handler, ok := routes[r.URL.Path]; ok && r.Method == http.MethodGet {
+				handler.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// authorizeOperations restricts matched operations to the configured access levels,
+// operations missing in the map stay public.
+func authorizeOperations(access map[string]string, adminIds []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		guarded := make(map[string]http.Handler, len(access))
+		for operationId, level := range access {
+			guarded[operationId] = middlewares.Authorize(level, adminIds)(next)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := middleware.MatchedRouteFrom(r); route != nil && route.Operation != nil {
+				if handler, ok := guarded[route.Operation.ID]; ok {
+					handler.ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// limitOperations applies rate limits to matched operations, operations missing in the map are not limited.
+func limitOperations(limits map[string]func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		limited := make(map[string]http.Handler, len(limits))
+		for operationId, limit := range limits {
+			limited[operationId] = limit(next)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if route := middleware.MatchedRouteFrom(r); route != nil && route.Operation != nil {
+				if handler, ok := limited[route.Operation.ID]; ok {
+					handler.ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
 }
 
 // The middleware configuration happens before anything, this middleware also applies to serving the swagger.json document.
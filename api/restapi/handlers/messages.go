@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/andriystech/lgc/api/models"
+	"github.com/andriystech/lgc/api/restapi/operations/messages"
+	domain "github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/services"
+	"github.com/go-openapi/runtime/middleware"
+)
+
+type MessagesHandler interface {
+	GetMessages(messages.GetMessagesParams) middleware.Responder
+}
+
+type MessagesHandlerContainer struct {
+	messageService services.MessageService
+}
+
+func NewMessagesHandler(ms services.MessageService) MessagesHandler {
+	return &MessagesHandlerContainer{messageService: ms}
+}
+
+func (mh *MessagesHandlerContainer) GetMessages(params messages.GetMessagesParams) middleware.Responder {
+	user, _ := services.UserFromContext(params.HTTPRequest.Context())
+	query := &domain.MessagesQuery{
+		RecipientId: user.Id,
+		Limit:       domain.MessagesPageDefaultLimit,
+	}
+	if params.SenderID != nil {
+		query.SenderId = *params.SenderID
+	}
+	if params.From != nil {
+		query.From = *params.From
+	}
+	if params.To != nil {
+		query.To = *params.To
+	}
+	if params.Limit != nil {
+		query.Limit = int(*params.Limit)
+	}
+	if params.Cursor != nil {
+		cursor, err := domain.DecodeMessageCursor(*params.Cursor)
+		if err != nil {
+			return messages.NewGetMessagesBadRequest().WithPayload(&models.ErrorResponse{
+				Message: err.Error(),
+				Status:  http.StatusBadRequest,
+			})
+		}
+		query.After = cursor
+	}
+
+	page, err := mh.messageService.GetMessages(params.HTTPRequest.Context(), query)
+	if err != nil {
+		return messages.NewGetMessagesInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+
+	payload := &models.MessagesPageResponse{Messages: []*models.Message{}}
+	for _, msg := range page.Messages {
+		payload.Messages = append(payload.Messages, &models.Message{
+			ID:          msg.LogicalId(),
+			SenderID:    msg.SenderId,
+			SenderName:  msg.SenderName,
+			RecipientID: msg.RecipientId,
+			RoomID:      msg.RoomId,
+			Payload:     msg.Payload,
+			Time:        msg.Time,
+			DeliveredAt: msg.DeliveredAt,
+			ReadAt:      msg.ReadAt,
+		})
+	}
+	if page.NextCursor != nil {
+		payload.NextCursor = page.NextCursor.Encode()
+	}
+	return messages.NewGetMessagesOK().WithPayload(payload)
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/andriystech/lgc/api/restapi/operations/chat"
+	"github.com/andriystech/lgc/api/restapi/operations/messages"
 	"github.com/andriystech/lgc/api/restapi/operations/user"
 	"github.com/go-openapi/runtime/middleware"
 )
@@ -9,18 +10,27 @@ import (
 type Handlers interface {
 	RegisterUser(user.CreateUserParams) middleware.Responder
 	LoginUser(user.LoginUserParams) middleware.Responder
+	RefreshToken(user.RefreshTokenParams) middleware.Responder
+	LogoutUser(user.LogoutUserParams) middleware.Responder
+	GetUserProfile(user.GetUserProfileParams) middleware.Responder
+	GetOwnProfile(user.GetOwnProfileParams) middleware.Responder
+	UpdateOwnProfile(user.UpdateOwnProfileParams) middleware.Responder
+	ChangePassword(user.ChangePasswordParams) middleware.Responder
+	DeleteAccount(user.DeleteAccountParams) middleware.Responder
 	GetActiveUsers(chat.GetActiveUsersParams) middleware.Responder
 	GetActiveUsersCount(chat.GetActiveUsersCountParams) middleware.Responder
 	StartChat(chat.WsRTMStartParams) middleware.Responder
+	GetMessages(messages.GetMessagesParams) middleware.Responder
 }
 
 type HandlersContainer struct {
-	user UserHandler
-	chat ChatHandler
+	user     UserHandler
+	chat     ChatHandler
+	messages MessagesHandler
 }
 
-func NewHandlers(uh UserHandler, ch ChatHandler) Handlers {
-	return &HandlersContainer{user: uh, chat: ch}
+func NewHandlers(uh UserHandler, ch ChatHandler, mh MessagesHandler) Handlers {
+	return &HandlersContainer{user: uh, chat: ch, messages: mh}
 }
 
 func (h *HandlersContainer) RegisterUser(params user.CreateUserParams) middleware.Responder {
@@ -31,6 +41,34 @@ func (h *HandlersContainer) LoginUser(params user.LoginUserParams) middleware.Re
 	return h.user.Login(params)
 }
 
+func (h *HandlersContainer) RefreshToken(params user.RefreshTokenParams) middleware.Responder {
+	return h.user.RefreshToken(params)
+}
+
+func (h *HandlersContainer) LogoutUser(params user.LogoutUserParams) middleware.Responder {
+	return h.user.Logout(params)
+}
+
+func (h *HandlersContainer) GetUserProfile(params user.GetUserProfileParams) middleware.Responder {
+	return h.user.GetProfile(params)
+}
+
+func (h *HandlersContainer) GetOwnProfile(params user.GetOwnProfileParams) middleware.Responder {
+	return h.user.GetOwnProfile(params)
+}
+
+func (h *HandlersContainer) UpdateOwnProfile(params user.UpdateOwnProfileParams) middleware.Responder {
+	return h.user.UpdateOwnProfile(params)
+}
+
+func (h *HandlersContainer) ChangePassword(params user.ChangePasswordParams) middleware.Responder {
+	return h.user.ChangePassword(params)
+}
+
+func (h *HandlersContainer) DeleteAccount(params user.DeleteAccountParams) middleware.Responder {
+	return h.user.DeleteAccount(params)
+}
+
 func (h *HandlersContainer) GetActiveUsers(params chat.GetActiveUsersParams) middleware.Responder {
 	return h.chat.GetActiveUsers(params)
 }
@@ -42,3 +80,7 @@ func (h *HandlersContainer) GetActiveUsersCount(params chat.GetActiveUsersCountP
 func (h *HandlersContainer) StartChat(params chat.WsRTMStartParams) middleware.Responder {
 	return h.chat.Start(params)
 }
+
+func (h *HandlersContainer) GetMessages(params messages.GetMessagesParams) middleware.Responder {
+	return h.messages.GetMessages(params)
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"net/http"
 
+	httpHandlers "github.com/andriystech/lgc/api/handlers"
 	"github.com/andriystech/lgc/api/models"
 	"github.com/andriystech/lgc/api/restapi/operations/user"
 	"github.com/andriystech/lgc/db/repositories"
-	"github.com/andriystech/lgc/pkg/hasher"
+	domain "github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/andriystech/lgc/services"
 	"github.com/go-openapi/runtime/middleware"
 	"github.com/go-openapi/strfmt"
@@ -17,15 +19,23 @@ import (
 type UserHandler interface {
 	Register(user.CreateUserParams) middleware.Responder
 	Login(user.LoginUserParams) middleware.Responder
+	RefreshToken(user.RefreshTokenParams) middleware.Responder
+	Logout(user.LogoutUserParams) middleware.Responder
+	GetProfile(user.GetUserProfileParams) middleware.Responder
+	GetOwnProfile(user.GetOwnProfileParams) middleware.Responder
+	UpdateOwnProfile(user.UpdateOwnProfileParams) middleware.Responder
+	ChangePassword(user.ChangePasswordParams) middleware.Responder
+	DeleteAccount(user.DeleteAccountParams) middleware.Responder
 }
 
 type UserHandlerContainer struct {
-	userService  services.UserService
-	tokenService services.TokenService
+	userService      services.UserService
+	tokenService     services.TokenService
+	webSocketService services.WebSocketService
 }
 
-func NewUserHandler(us services.UserService, ts services.TokenService) UserHandler {
-	return &UserHandlerContainer{userService: us, tokenService: ts}
+func NewUserHandler(us services.UserService, ts services.TokenService, ws services.WebSocketService) UserHandler {
+	return &UserHandlerContainer{userService: us, tokenService: ts, webSocketService: ws}
 }
 
 func (uh *UserHandlerContainer) Register(params user.CreateUserParams) middleware.Responder {
@@ -62,13 +72,17 @@ func (uh *UserHandlerContainer) Login(params user.LoginUserParams) middleware.Re
 			Status:  http.StatusBadRequest,
 		})
 	}
-	if !hasher.CheckPasswordHash(*params.Body.Password, um.Password) {
+	if !uh.userService.CheckPassword(um, *params.Body.Password) {
 		return user.NewLoginUserBadRequest().WithPayload(&models.ErrorResponse{
 			Message: "Unable to log in user. Reason: Invalid creds",
 			Status:  http.StatusBadRequest,
 		})
 	}
 
+	if err = uh.userService.UpgradePasswordHash(params.HTTPRequest.Context(), um, *params.Body.Password); err != nil {
+		logger.FromContext(params.HTTPRequest.Context()).Error("Unable to upgrade password hash of user", "userId", um.Id, "err", err)
+	}
+
 	token, err := uh.tokenService.GenerateToken(params.HTTPRequest.Context(), um)
 	if err != nil {
 		return user.NewLoginUserInternalServerError().WithPayload(&models.ErrorResponse{
@@ -77,8 +91,197 @@ func (uh *UserHandlerContainer) Login(params user.LoginUserParams) middleware.Re
 		})
 	}
 
+	tokens, err := uh.tokenService.IssueTokens(params.HTTPRequest.Context(), um)
+	if err != nil {
+		return user.NewLoginUserInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+
 	url := fmt.Sprintf("ws://%s/chat/ws.rtm.start?token=%s", params.HTTPRequest.Host, token.Payload)
 	return user.NewLoginUserOK().WithPayload(&models.LoginUserResonse{
-		URL: &url,
+		URL:          &url,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn),
 	})
 }
+
+func (uh *UserHandlerContainer) RefreshToken(params user.RefreshTokenParams) middleware.Responder {
+	tokens, err := uh.tokenService.RefreshTokens(params.HTTPRequest.Context(), *params.Body.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+		return user.NewRefreshTokenUnauthorized().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusUnauthorized,
+		})
+	}
+	if err != nil {
+		return user.NewRefreshTokenInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewRefreshTokenOK().WithPayload(&models.TokensResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn),
+	})
+}
+
+func (uh *UserHandlerContainer) Logout(params user.LogoutUserParams) middleware.Responder {
+	err := uh.tokenService.RevokeRefreshToken(params.HTTPRequest.Context(), *params.Body.RefreshToken)
+	if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenRevoked) {
+		return user.NewLogoutUserUnauthorized().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusUnauthorized,
+		})
+	}
+	if err != nil {
+		return user.NewLogoutUserInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewLogoutUserNoContent()
+}
+
+func (uh *UserHandlerContainer) GetProfile(params user.GetUserProfileParams) middleware.Responder {
+	um, err := uh.userService.FindUserById(params.HTTPRequest.Context(), params.ID)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return user.NewGetUserProfileNotFound().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusNotFound,
+		})
+	}
+	if err != nil {
+		return user.NewGetUserProfileInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewGetUserProfileOK().WithPayload(composeProfileResponse(um))
+}
+
+func (uh *UserHandlerContainer) GetOwnProfile(params user.GetOwnProfileParams) middleware.Responder {
+	current, _ := services.UserFromContext(params.HTTPRequest.Context())
+	um, err := uh.userService.FindUserById(params.HTTPRequest.Context(), current.Id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return user.NewGetOwnProfileNotFound().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusNotFound,
+		})
+	}
+	if err != nil {
+		return user.NewGetOwnProfileInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewGetOwnProfileOK().WithPayload(composeProfileResponse(um))
+}
+
+func (uh *UserHandlerContainer) UpdateOwnProfile(params user.UpdateOwnProfileParams) middleware.Responder {
+	update := &domain.ProfileUpdate{
+		DisplayName: params.Body.DisplayName,
+		AvatarUrl:   params.Body.AvatarURL,
+		StatusText:  params.Body.StatusText,
+	}
+	if err := httpHandlers.ValidateProfileUpdate(update); err != nil {
+		return user.NewUpdateOwnProfileBadRequest().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		})
+	}
+	current, _ := services.UserFromContext(params.HTTPRequest.Context())
+	um, err := uh.userService.UpdateProfile(params.HTTPRequest.Context(), current.Id, update)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return user.NewUpdateOwnProfileNotFound().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusNotFound,
+		})
+	}
+	if err != nil {
+		return user.NewUpdateOwnProfileInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewUpdateOwnProfileOK().WithPayload(composeProfileResponse(um))
+}
+
+func (uh *UserHandlerContainer) ChangePassword(params user.ChangePasswordParams) middleware.Responder {
+	current, _ := services.UserFromContext(params.HTTPRequest.Context())
+	changed, err := uh.userService.ChangePassword(params.HTTPRequest.Context(), current.Id, *params.Body.CurrentPassword, *params.Body.NewPassword)
+	if errors.Is(err, services.ErrInvalidPassword) {
+		return user.NewChangePasswordForbidden().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusForbidden,
+		})
+	}
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return user.NewChangePasswordNotFound().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusNotFound,
+		})
+	}
+	if err != nil {
+		return user.NewChangePasswordInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	httpHandlers.CloseUserSessions(params.HTTPRequest, uh.webSocketService, current)
+	tokens, err := uh.tokenService.IssueTokens(params.HTTPRequest.Context(), changed)
+	if err != nil {
+		return user.NewChangePasswordInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	return user.NewChangePasswordOK().WithPayload(&models.TokensResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.ExpiresIn),
+	})
+}
+
+func (uh *UserHandlerContainer) DeleteAccount(params user.DeleteAccountParams) middleware.Responder {
+	current, _ := services.UserFromContext(params.HTTPRequest.Context())
+	err := uh.userService.DeleteUser(params.HTTPRequest.Context(), current.Id, *params.Body.Password)
+	if errors.Is(err, services.ErrInvalidPassword) {
+		return user.NewDeleteAccountForbidden().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusForbidden,
+		})
+	}
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return user.NewDeleteAccountNotFound().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusNotFound,
+		})
+	}
+	if err != nil {
+		return user.NewDeleteAccountInternalServerError().WithPayload(&models.ErrorResponse{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		})
+	}
+	httpHandlers.RemoveUserData(params.HTTPRequest, uh.webSocketService, current)
+	return user.NewDeleteAccountNoContent()
+}
+
+func composeProfileResponse(um *domain.User) *models.UserProfileResponse {
+	return &models.UserProfileResponse{
+		ID:          um.Id,
+		UserName:    um.UserName,
+		DisplayName: um.DisplayName,
+		AvatarURL:   um.AvatarUrl,
+		StatusText:  um.StatusText,
+		CreatedAt:   um.CreatedAt,
+		UpdatedAt:   um.UpdatedAt,
+	}
+}
@@ -10,38 +10,61 @@ import (
 	"github.com/andriystech/lgc/api/restapi/handlers"
 	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
 	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/andriystech/lgc/services"
 	"github.com/google/wire"
 )
 
 // Injectors from wire.go:
 
-func InitializeHandlers(db mongo.ClientHelper) handlers.Handlers {
-	serverConfig := config.GetServerConfig()
+func InitializeApplication(db mongo.ClientHelper, serverConfig *config.ServerConfig, lg *logger.Logger) *Application {
 	usersCollection := mongo.NewUsersCollection(db, serverConfig)
 	usersRepository := repositories.NewUsersRepository(usersCollection)
-	userService := services.NewUserService(usersRepository)
-	tokensRepository := repositories.NewTokensRepository(serverConfig)
-	tokenService := services.NewTokenService(tokensRepository)
-	userHandler := handlers.NewUserHandler(userService, tokenService)
+	userService := services.NewUserService(usersRepository, serverConfig)
+	tokensCollection := mongo.NewTokensCollection(db, serverConfig)
+	tokensRepository := repositories.NewTokensRepository(serverConfig, tokensCollection)
+	revokedTokensCollection := mongo.NewRevokedTokensCollection(db, serverConfig)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(serverConfig, revokedTokensCollection)
+	tokenService := services.NewTokenService(tokensRepository, revokedTokensRepository, usersRepository, serverConfig)
 	connectionsRepository := repositories.NewConnectionsRepository()
 	messagesCollection := mongo.NewMessagesCollection(db, serverConfig)
-	messagesRepository := repositories.NewMessagesRepository(messagesCollection)
+	deliveriesCollection := mongo.NewDeliveriesCollection(db, serverConfig)
+	messagesRepository := repositories.NewMessagesRepository(messagesCollection, deliveriesCollection)
+	roomsCollection := mongo.NewRoomsCollection(db, serverConfig)
+	roomsRepository := repositories.NewRoomsRepository(roomsCollection)
 	upgraderHelper := ws.NewUpgrader(serverConfig)
-	webSocketService := services.NewWebSocketService(connectionsRepository, messagesRepository, usersRepository, upgraderHelper)
+	eventsCollection := mongo.NewEventsCollection(db, serverConfig)
+	brokerBroker := broker.NewBroker(serverConfig, eventsCollection)
+	presenceCollection := mongo.NewPresenceCollection(db, serverConfig)
+	presenceRepository := repositories.NewPresenceRepository(serverConfig, presenceCollection)
+	webSocketService := services.NewWebSocketService(connectionsRepository, messagesRepository, roomsRepository, usersRepository, upgraderHelper, brokerBroker, presenceRepository, serverConfig)
+	userHandler := handlers.NewUserHandler(userService, tokenService, webSocketService)
 	chatHandler := handlers.NewChatHandler(tokenService, webSocketService)
-	handlersHandlers := handlers.NewHandlers(userHandler, chatHandler)
-	return handlersHandlers
+	messageService := services.NewMessageService(messagesRepository)
+	messagesHandler := handlers.NewMessagesHandler(messageService)
+	handlersHandlers := handlers.NewHandlers(userHandler, chatHandler, messagesHandler)
+	tokensJanitor := services.NewTokensJanitor(tokensRepository, revokedTokensRepository, serverConfig)
+	healthService := services.NewHealthService(db, serverConfig)
+	application := &Application{
+		Handlers:         handlersHandlers,
+		TokensJanitor:    tokensJanitor,
+		TokenService:     tokenService,
+		WebSocketService: webSocketService,
+		HealthService:    healthService,
+		Logger:           lg,
+	}
+	return application
 }
 
 // wire.go:
 
-var collectionsSet = wire.NewSet(mongo.NewMessagesCollection, mongo.NewUsersCollection)
+var collectionsSet = wire.NewSet(mongo.NewDeliveriesCollection, mongo.NewEventsCollection, mongo.NewMessagesCollection, mongo.NewPresenceCollection, mongo.NewRevokedTokensCollection, mongo.NewRoomsCollection, mongo.NewTokensCollection, mongo.NewUsersCollection)
 
-var repositoriesSet = wire.NewSet(repositories.NewConnectionsRepository, repositories.NewMessagesRepository, repositories.NewTokensRepository, repositories.NewUsersRepository)
+var repositoriesSet = wire.NewSet(repositories.NewConnectionsRepository, repositories.NewMessagesRepository, repositories.NewPresenceRepository, repositories.NewRevokedTokensRepository, repositories.NewRoomsRepository, repositories.NewTokensRepository, repositories.NewUsersRepository)
 
-var servicesSet = wire.NewSet(services.NewTokenService, services.NewUserService, services.NewWebSocketService)
+var servicesSet = wire.NewSet(services.NewHealthService, services.NewMessageService, services.NewRoomService, services.NewTokensJanitor, services.NewTokenService, services.NewUserService, services.NewWebSocketService)
 
-var handlersSet = wire.NewSet(handlers.NewUserHandler, handlers.NewChatHandler)
+var handlersSet = wire.NewSet(handlers.NewUserHandler, handlers.NewChatHandler, handlers.NewMessagesHandler)
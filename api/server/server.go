@@ -1,44 +1,141 @@
 package server
 
 import (
-	"log"
+	"context"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/andriystech/lgc/api/handlers"
 	"github.com/andriystech/lgc/api/middlewares"
 	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/pkg/metrics"
 	"github.com/andriystech/lgc/services"
 	"github.com/gorilla/mux"
 )
 
 type HttpServer interface {
-	Run()
+	Run() error
 }
 
 type HttpServerContainer struct {
+	messageService   services.MessageService
+	roomService      services.RoomService
 	tokenService     services.TokenService
 	userService      services.UserService
 	webSocketService services.WebSocketService
+	tokensJanitor    services.TokensJanitor
+	healthService    services.HealthService
+	logger           *logger.Logger
 	config           *config.ServerConfig
 }
 
-func NewHttpServer(ts services.TokenService, us services.UserService, ws services.WebSocketService, cg *config.ServerConfig) HttpServer {
-	return &HttpServerContainer{tokenService: ts, userService: us, webSocketService: ws, config: cg}
+func NewHttpServer(
+	ms services.MessageService,
+	rs services.RoomService,
+	ts services.TokenService,
+	us services.UserService,
+	ws services.WebSocketService,
+	tj services.TokensJanitor,
+	hs services.HealthService,
+	lg *logger.Logger,
+	cg *config.ServerConfig,
+) HttpServer {
+	return &HttpServerContainer{
+		messageService:   ms,
+		roomService:      rs,
+		tokenService:     ts,
+		userService:      us,
+		webSocketService: ws,
+		tokensJanitor:    tj,
+		healthService:    hs,
+		logger:           lg,
+		config:           cg,
+	}
 }
 
-func (hsc *HttpServerContainer) Run() {
+// Run serves requests until SIGINT or SIGTERM is received, then stops accepting
+// new connections and drains web socket sessions within the shutdown timeout.
+func (hsc *HttpServerContainer) Run() error {
 	router := mux.NewRouter()
-	router.Use(middlewares.LogHttpCalls(os.Stdout))
+	router.Use(middlewares.RequestId(hsc.logger))
+	measure := middlewares.MeasureHttpCalls(middlewares.MuxRoute)
+	router.Use(measure)
+	middlewares.MeasureUnmatchedRoutes(router, measure)
+	router.Use(middlewares.LogHttpCalls)
 	router.Use(middlewares.PanicAndRecover)
-	router.HandleFunc("/user/active/count", handlers.ActiveConnectionsCountHandler(hsc.webSocketService)).Methods("GET")
-	router.HandleFunc("/user/active", handlers.ActiveUsersHandler(hsc.webSocketService)).Methods("GET")
-	router.HandleFunc("/user/login", handlers.LogInUserHandler(hsc.userService, hsc.tokenService)).Methods("POST")
-	router.HandleFunc("/user", handlers.RegisterUserHandler(hsc.userService)).Methods("POST")
+	router.Use(middlewares.Authenticate(hsc.tokenService))
+	monitoring := middlewares.Authorize(hsc.config.MonitoringAccess, hsc.config.AdminUserIds)
+	passwordChecks := middlewares.LimitPasswordChecks(hsc.config)
+	router.Handle("/user/active/count", monitoring(handlers.ActiveConnectionsCountHandler(hsc.webSocketService))).Methods("GET")
+	router.Handle("/user/active/queues", monitoring(handlers.SendQueueStatsHandler(hsc.webSocketService))).Methods("GET")
+	router.Handle("/user/active", monitoring(handlers.ActiveUsersHandler(hsc.webSocketService))).Methods("GET")
+	router.Handle("/user/login", middlewares.LimitLogin(hsc.config)(handlers.LogInUserHandler(hsc.userService, hsc.tokenService))).Methods("POST")
+	router.HandleFunc("/user/token/refresh", handlers.RefreshTokenHandler(hsc.tokenService)).Methods("POST")
+	router.HandleFunc("/user/logout", handlers.LogOutUserHandler(hsc.tokenService)).Methods("POST")
+	router.Handle("/user/sessions", middlewares.RequireUser(handlers.SessionsHandler(hsc.webSocketService))).Methods("GET")
+	router.Handle("/user/sessions/{id}", middlewares.RequireUser(handlers.CloseSessionHandler(hsc.webSocketService))).Methods("DELETE")
+	router.Handle("/user", middlewares.LimitRegistration(hsc.config)(handlers.RegisterUserHandler(hsc.userService))).Methods("POST")
+	router.Handle("/user/me", middlewares.RequireUser(handlers.OwnProfileHandler(hsc.userService))).Methods("GET")
+	router.Handle("/user/me", middlewares.RequireUser(handlers.UpdateProfileHandler(hsc.userService))).Methods("PATCH")
+	router.Handle("/user/me", middlewares.RequireUser(passwordChecks(handlers.DeleteAccountHandler(hsc.userService, hsc.webSocketService)))).Methods("DELETE")
+	router.Handle("/user/me/password", middlewares.RequireUser(passwordChecks(handlers.ChangePasswordHandler(hsc.userService, hsc.tokenService, hsc.webSocketService)))).Methods("PUT")
+	router.Handle("/user/{id}", middlewares.RequireUser(handlers.ProfileHandler(hsc.userService))).Methods("GET")
+	router.Handle("/rooms", middlewares.RequireUser(handlers.CreateRoomHandler(hsc.roomService))).Methods("POST")
+	router.HandleFunc("/rooms", handlers.ListRoomsHandler(hsc.roomService)).Methods("GET")
+	router.Handle("/rooms/{id}/join", middlewares.RequireUser(handlers.JoinRoomHandler(hsc.roomService))).Methods("POST")
+	router.Handle("/rooms/{id}/leave", middlewares.RequireUser(handlers.LeaveRoomHandler(hsc.roomService))).Methods("POST")
+	router.Handle("/messages", middlewares.RequireUser(handlers.MessagesHandler(hsc.messageService))).Methods("GET")
+	router.Handle("/messages/{id}", middlewares.RequireUser(handlers.EditMessageHandler(hsc.webSocketService))).Methods("PATCH")
+	router.Handle("/messages/{id}", middlewares.RequireUser(handlers.DeleteMessageHandler(hsc.webSocketService))).Methods("DELETE")
+	router.Handle("/messages/{id}/receipts", middlewares.RequireUser(handlers.MessageReceiptsHandler(hsc.messageService))).Methods("GET")
+	router.Handle("/messages/direct", middlewares.RequireUser(handlers.DirectMessagesHandler(hsc.messageService))).Methods("GET")
 	router.HandleFunc("/_health", handlers.HealthCheck).Methods("GET")
+	router.HandleFunc("/_health/live", handlers.LivenessHandler(hsc.healthService)).Methods("GET")
+	router.HandleFunc("/_health/ready", handlers.ReadinessHandler(hsc.healthService)).Methods("GET")
+	router.Handle("/metrics", monitoring(handlers.MetricsHandler(metrics.DefaultRegistry))).Methods("GET")
 	router.HandleFunc("/chat/ws.rtm.start", handlers.WSConnectHandler(hsc.webSocketService, hsc.tokenService))
 	http.Handle("/", router)
 
-	log.Printf("Server is listening %s port", hsc.config.Port)
-	log.Fatal(http.ListenAndServe(hsc.config.Port, nil))
+	ctx, cancel := context.WithCancel(logger.NewContext(context.Background(), hsc.logger))
+	defer cancel()
+	go hsc.tokensJanitor.Run(ctx)
+	go hsc.webSocketService.Run(ctx)
+
+	stopCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := &http.Server{Addr: hsc.config.Port}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+	hsc.logger.Info("Server is listening", "port", hsc.config.Port)
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-stopCtx.Done():
+	}
+
+	hsc.logger.Info("Shutting down the server")
+	return hsc.shutdown(srv)
+}
+
+// shutdown keeps serving requests while the server is reported as not ready for the drain delay,
+// so load balancers stop routing to it before it stops accepting connections.
+func (hsc *HttpServerContainer) shutdown(srv *http.Server) error {
+	hsc.healthService.Shutdown()
+	time.Sleep(hsc.config.ShutdownDrainDelay)
+	shutdownCtx, cancelShutdown := context.WithTimeout(logger.NewContext(context.Background(), hsc.logger), hsc.config.ShutdownTimeout)
+	defer cancelShutdown()
+	// web socket connections are hijacked, so http server does not wait for them
+	err := srv.Shutdown(shutdownCtx)
+	if wsErr := hsc.webSocketService.Shutdown(shutdownCtx); err == nil {
+		err = wsErr
+	}
+	return err
 }
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/api/handlers"
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShutdownDrainsReadiness(t *testing.T) {
+	cnf := &config.ServerConfig{ShutdownTimeout: time.Second, ShutdownDrainDelay: 300 * time.Millisecond, HealthCheckTimeout: time.Second}
+	db := new(mocks.ClientHelper)
+	db.On("Ping", mock.Anything).Return(nil)
+	ws := new(mocks.WebSocketService)
+	ws.On("Shutdown", mock.Anything).Return(nil)
+	hs := services.NewHealthService(db, cnf)
+	hsc := &HttpServerContainer{webSocketService: ws, healthService: hs, logger: logger.Default(), config: cnf}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_health/ready", handlers.ReadinessHandler(hs))
+	srv := &http.Server{Handler: mux}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err, "Unable to listen: %v", err)
+	go srv.Serve(ln)
+	url := "http://" + ln.Addr().String() + "/_health/ready"
+
+	res, err := http.Get(url)
+	assert.Nil(t, err, "readiness probe failed before shutdown: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode, "readiness probe returned unexpected status before shutdown: got %v want %v", res.StatusCode, http.StatusOK)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hsc.shutdown(srv)
+	}()
+	time.Sleep(100 * time.Millisecond)
+
+	res, err = http.Get(url)
+	assert.Nil(t, err, "readiness probe failed during drain delay: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode, "readiness probe returned unexpected status during drain delay: got %v want %v", res.StatusCode, http.StatusServiceUnavailable)
+
+	select {
+	case err = <-done:
+		assert.Nil(t, err, "shutdown returned unexpected result: got error %v want %v", err, nil)
+	case <-time.After(2 * time.Second):
+		t.Fatal("shutdown did not finish within the shutdown timeout")
+	}
+	_, err = http.Get(url)
+	assert.NotNil(t, err, "readiness probe succeeded after shutdown")
+	ws.AssertExpectations(t)
+}
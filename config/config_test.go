@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfigFile(t, "lgc.yml", "server_port: :7000\nmongo_db_name: file\nws_pong_wait: 2m\nadmin_user_ids: [a, b]\n")
+	t.Setenv("MONGO_DB_NAME", "env")
+	t.Setenv("WS_PONG_WAIT", "90s")
+	t.Setenv("SERVER_PORT", ":7001")
+
+	cnf, cmd, err := Load([]string{"--config", path, "--server-port", ":7002"})
+
+	assert.Nil(t, err, "Load returned unexpected error: got %v want nil", err)
+	assert.Equal(t, path, cmd.ConfigFile, "Load returned unexpected config file: got %v want %v", cmd.ConfigFile, path)
+	assert.Equal(t, ":7002", cnf.Port, "Load returned unexpected port: got %v want %v", cnf.Port, ":7002")
+	assert.Equal(t, "env", cnf.DbName, "Load returned unexpected db name: got %v want %v", cnf.DbName, "env")
+	assert.Equal(t, 90*time.Second, cnf.WsPongWait, "Load returned unexpected pong wait: got %v want %v", cnf.WsPongWait, 90*time.Second)
+	assert.Equal(t, []string{"a", "b"}, cnf.AdminUserIds, "Load returned unexpected admins: got %v want %v", cnf.AdminUserIds, []string{"a", "b"})
+	assert.Equal(t, 50*time.Second, cnf.WsPingPeriod, "Load returned unexpected ping period: got %v want %v", cnf.WsPingPeriod, 50*time.Second)
+}
+
+func TestLoadJsonFile(t *testing.T) {
+	path := writeConfigFile(t, "lgc.json", `{"ws_max_message_size": 1024, "token_ttl": "5m", "broker_backend": "mongo"}`)
+	t.Setenv("CONFIG_FILE", path)
+
+	cnf, _, err := Load(nil)
+
+	assert.Nil(t, err, "Load returned unexpected error: got %v want nil", err)
+	assert.Equal(t, int64(1024), cnf.WsMaxMessageSize, "Load returned unexpected max message size: got %v want %v", cnf.WsMaxMessageSize, 1024)
+	assert.Equal(t, 5*time.Minute, cnf.TokenTTL, "Load returned unexpected token TTL: got %v want %v", cnf.TokenTTL, 5*time.Minute)
+	assert.Equal(t, BrokerMongo, cnf.BrokerBackend, "Load returned unexpected broker: got %v want %v", cnf.BrokerBackend, BrokerMongo)
+}
+
+func TestLoadErrors(t *testing.T) {
+	testConditions := []struct {
+		tName   string
+		file    string
+		env     map[string]string
+		args    []string
+		wantErr string
+	}{
+		{
+			tName:   "should reject unknown key of config file",
+			file:    "server_prot: :7000\n",
+			wantErr: `unknown key "server_prot"`,
+		},
+		{
+			tName:   "should reject invalid duration in config file",
+			file:    "presence_ttl: 30\n",
+			wantErr: `key presence_ttl: invalid duration "30"`,
+		},
+		{
+			tName:   "should reject invalid environment variable",
+			env:     map[string]string{"WS_READ_BUFFER": "big"},
+			wantErr: `environment variable WS_READ_BUFFER: invalid integer "big"`,
+		},
+		{
+			tName:   "should reject invalid flag",
+			args:    []string{"--shutdown-timeout", "soon"},
+			wantErr: `invalid value "soon" for flag -shutdown-timeout`,
+		},
+		{
+			tName:   "should reject extra arguments",
+			args:    []string{"serve"},
+			wantErr: "unexpected arguments: serve",
+		},
+		{
+			tName:   "should list all validation problems",
+			env:     map[string]string{"TOKENS_STORAGE": "redis", "WS_PING_PERIOD": "1m"},
+			args:    []string{"--access-token-ttl", "0s"},
+			wantErr: `invalid config: TOKENS_STORAGE must be one of memory, mongo, got "redis"; ACCESS_TOKEN_TTL must be positive, got 0s; WS_PING_PERIOD must be shorter than WS_PONG_WAIT, otherwise live peers are disconnected`,
+		},
+		{
+			tName:   "should reject password hashing parameters out of range",
+			env:     map[string]string{"PASSWORD_BCRYPT_COST": "3", "PASSWORD_ARGON2_THREADS": "4", "PASSWORD_ARGON2_MEMORY": "16", "PASSWORD_SCRYPT_LOG_N": "0"},
+			wantErr: `invalid config: PASSWORD_BCRYPT_COST must be between 4 and 31, got 3; PASSWORD_ARGON2_MEMORY must be between 32 and 4294967295, got 16; PASSWORD_SCRYPT_LOG_N must be between 1 and 31, got 0`,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			if testCond.file != "" {
+				t.Setenv("CONFIG_FILE", writeConfigFile(t, "lgc.yaml", testCond.file))
+			}
+			for name, value := range testCond.env {
+				t.Setenv(name, value)
+			}
+
+			_, _, err := Load(testCond.args)
+
+			if assert.NotNil(t, err, "Load returned unexpected result: got nil want error") {
+				assert.Contains(t, err.Error(), testCond.wantErr, "Load returned unexpected error: got %v want %v", err, testCond.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDefault(t *testing.T) {
+	err := Default().Validate()
+
+	assert.Nil(t, err, "Validate returned unexpected error: got %v want nil", err)
+}
+
+func TestPrint(t *testing.T) {
+	cnf := Default()
+	cnf.MongoDbUrl = "mongodb://root:secret@db:27017/?replicaSet=rs0"
+	cnf.JwtSecret = "jwt-secret"
+	cnf.PresenceTTL = 45 * time.Second
+	out := &bytes.Buffer{}
+
+	err := Print(out, cnf)
+
+	assert.Nil(t, err, "Print returned unexpected error: got %v want nil", err)
+	assert.NotContains(t, out.String(), "secret@", "Print returned unredacted MongoDB password")
+	assert.NotContains(t, out.String(), "jwt-secret", "Print returned unredacted JWT secret")
+	assert.Contains(t, out.String(), "mongodb_url: mongodb://root:xxxxx@db:27017/?replicaSet=rs0\n")
+	assert.Contains(t, out.String(), "jwt_secret: <redacted>\n")
+	assert.Contains(t, out.String(), "presence_ttl: 45s\n")
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"io"
+	"net/url"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const redacted = "<redacted>"
+
+// Print writes effective config in the config file format. Secrets are redacted,
+// passwords of connection strings are replaced while hosts are kept for troubleshooting.
+func Print(w io.Writer, cnf *ServerConfig) error {
+	values := yaml.MapSlice{}
+	for _, f := range fields(cnf) {
+		values = append(values, yaml.MapItem{Key: f.key(), Value: printValue(f)})
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func printValue(f *field) interface{} {
+	switch f.info.Tag.Get("secret") {
+	case "true":
+		if f.value.String() == "" {
+			return ""
+		}
+		return redacted
+	case "url":
+		u, err := url.Parse(f.value.String())
+		if err != nil {
+			return redacted
+		}
+		return u.Redacted()
+	}
+	if f.value.Type() == durationType {
+		return time.Duration(f.value.Int()).String()
+	}
+	return f.value.Interface()
+}
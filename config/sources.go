@@ -0,0 +1,195 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// field is a config field bound to its external names.
+type field struct {
+	env   string
+	value reflect.Value
+	info  reflect.StructField
+}
+
+func (f *field) key() string {
+	return strings.ToLower(f.env)
+}
+
+func (f *field) flag() string {
+	return strings.ReplaceAll(f.key(), "_", "-")
+}
+
+func fields(cnf *ServerConfig) []*field {
+	v := reflect.ValueOf(cnf).Elem()
+	t := v.Type()
+	res := make([]*field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		res = append(res, &field{env: t.Field(i).Tag.Get("env"), value: v.Field(i), info: t.Field(i)})
+	}
+	return res
+}
+
+// set parses raw value according to the field type.
+func (f *field) set(raw string) error {
+	raw = strings.TrimSpace(raw)
+	if f.value.Type() == durationType {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, use units like 30s or 5m", raw)
+		}
+		f.value.SetInt(int64(d))
+		return nil
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		f.value.SetInt(n)
+	case reflect.Slice:
+		f.value.Set(reflect.ValueOf(splitList(raw)))
+	default:
+		return fmt.Errorf("unsupported type %s", f.value.Type())
+	}
+	return nil
+}
+
+// splitList reads comma separated values skipping blank ones.
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func applyEnv(cnf *ServerConfig) error {
+	for _, f := range fields(cnf) {
+		raw, ok := os.LookupEnv(f.env)
+		if !ok {
+			continue
+		}
+		if err := f.set(raw); err != nil {
+			return fmt.Errorf("environment variable %s: %w", f.env, err)
+		}
+	}
+	return nil
+}
+
+// applyFile reads JSON file when its name ends with .json and YAML file otherwise.
+// Unknown keys are rejected to catch typos.
+func applyFile(cnf *ServerConfig, path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	values := map[string]interface{}{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &values)
+	} else {
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	byKey := map[string]*field{}
+	for _, f := range fields(cnf) {
+		byKey[f.key()] = f
+	}
+	for key, value := range values {
+		f, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown key %q", path, key)
+		}
+		if err = f.set(fileValue(value)); err != nil {
+			return fmt.Errorf("config file %s: key %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// fileValue converts decoded scalar or list into the raw form accepted by field.set.
+func fileValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ",")
+	case float64:
+		// JSON numbers are decoded as floats
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// flagValue keeps raw value of the flag, so flags are applied after other sources.
+type flagValue struct {
+	name string
+	raw  string
+}
+
+// parseCommandLine parses server flags, every config field has a flag named after its key.
+// Flag values are checked while parsing to report errors together with the flags usage.
+func parseCommandLine(args []string) (*Command, []*flagValue, error) {
+	cmd := &Command{}
+	fs := flag.NewFlagSet("lgc", flag.ContinueOnError)
+	fs.StringVar(&cmd.ConfigFile, "config", os.Getenv("CONFIG_FILE"), "path to YAML or JSON config file, env CONFIG_FILE")
+	fs.BoolVar(&cmd.PrintConfig, "print-config", false, "print effective config with redacted secrets and exit")
+
+	var values []*flagValue
+	for _, f := range fields(&ServerConfig{}) {
+		f := f
+		usage := fmt.Sprintf("%s, env %s", f.info.Tag.Get("usage"), f.env)
+		fs.Func(f.flag(), usage, func(raw string) error {
+			if err := f.set(raw); err != nil {
+				return err
+			}
+			values = append(values, &flagValue{name: f.flag(), raw: raw})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	return cmd, values, nil
+}
+
+func applyFlags(cnf *ServerConfig, values []*flagValue) error {
+	byName := map[string]*field{}
+	for _, f := range fields(cnf) {
+		byName[f.flag()] = f
+	}
+	for _, v := range values {
+		if err := byName[v.name].set(v.raw); err != nil {
+			return fmt.Errorf("flag --%s: %w", v.name, err)
+		}
+	}
+	return nil
+}
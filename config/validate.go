@@ -0,0 +1,103 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/andriystech/lgc/pkg/hasher"
+	"github.com/andriystech/lgc/pkg/logger"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ValidationError lists all problems found in the config.
+type ValidationError []string
+
+func (e ValidationError) Error() string {
+	return "invalid config: " + strings.Join(e, "; ")
+}
+
+// Validate checks that the server is able to start with the config.
+func (cnf *ServerConfig) Validate() error {
+	var problems ValidationError
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+	oneOf := func(env, value string, allowed ...string) {
+		for _, a := range allowed {
+			if value == a {
+				return
+			}
+		}
+		problems = append(problems, fmt.Sprintf("%s must be one of %s, got %q", env, strings.Join(allowed, ", "), value))
+	}
+	positive := func(env string, d time.Duration) {
+		check(d > 0, "%s must be positive, got %s", env, d)
+	}
+	notNegative := func(env string, n int64) {
+		check(n >= 0, "%s must not be negative, got %d", env, n)
+	}
+	between := func(env string, n, min, max int64) {
+		check(n >= min && n <= max, "%s must be between %d and %d, got %d", env, min, max, n)
+	}
+
+	check(cnf.Port != "", "SERVER_PORT must not be empty")
+	u, err := url.Parse(cnf.MongoDbUrl)
+	check(err == nil && (u.Scheme == "mongodb" || u.Scheme == "mongodb+srv"), "MONGODB_URL must be a mongodb:// or mongodb+srv:// connection string")
+	check(cnf.DbName != "", "MONGO_DB_NAME must not be empty")
+	positive("DB_CONNECTION_TIMEOUT", cnf.DbConnectionTimeout)
+	positive("TOKEN_TTL", cnf.TokenTTL)
+	oneOf("TOKENS_STORAGE", cnf.TokensStorage, TokensStorageMemory, TokensStorageMongo)
+	notNegative("TOKENS_SWEEP_INTERVAL", int64(cnf.TokensSweepInterval))
+	oneOf("PASSWORD_HASH_ALGORITHM", cnf.PasswordHashAlgorithm, string(hasher.Argon2id), string(hasher.Bcrypt), string(hasher.Scrypt))
+	between("PASSWORD_BCRYPT_COST", int64(cnf.PasswordBcryptCost), int64(bcrypt.MinCost), int64(bcrypt.MaxCost))
+	between("PASSWORD_ARGON2_TIME", int64(cnf.PasswordArgon2Time), 1, math.MaxUint32)
+	between("PASSWORD_ARGON2_THREADS", int64(cnf.PasswordArgon2Threads), 1, math.MaxUint8)
+	// argon2id needs at least 8 KiB per thread
+	between("PASSWORD_ARGON2_MEMORY", int64(cnf.PasswordArgon2Memory), 8*int64(cnf.PasswordArgon2Threads), math.MaxUint32)
+	between("PASSWORD_SCRYPT_LOG_N", int64(cnf.PasswordScryptLogN), 1, 31)
+	between("PASSWORD_SCRYPT_R", int64(cnf.PasswordScryptR), 1, 1<<30-1)
+	between("PASSWORD_SCRYPT_P", int64(cnf.PasswordScryptP), 1, 1<<30-1)
+	check(int64(cnf.PasswordScryptR)*int64(cnf.PasswordScryptP) < 1<<30, "PASSWORD_SCRYPT_R multiplied by PASSWORD_SCRYPT_P must be less than 2^30")
+	positive("ACCESS_TOKEN_TTL", cnf.AccessTokenTTL)
+	positive("REFRESH_TOKEN_TTL", cnf.RefreshTokenTTL)
+	check(cnf.RefreshTokenTTL >= cnf.AccessTokenTTL, "REFRESH_TOKEN_TTL must not be shorter than ACCESS_TOKEN_TTL")
+	oneOf("MONITORING_ACCESS", cnf.MonitoringAccess, AccessPublic, AccessAuthenticated, AccessAdmin)
+	check(cnf.MonitoringAccess != AccessAdmin || len(cnf.AdminUserIds) > 0, "ADMIN_USER_IDS must not be empty when MONITORING_ACCESS is admin")
+	check(cnf.WsReadBuffer > 0, "WS_READ_BUFFER must be positive, got %d", cnf.WsReadBuffer)
+	check(cnf.WsWriteBuffer > 0, "WS_WRITE_BUFFER must be positive, got %d", cnf.WsWriteBuffer)
+	check(cnf.WsSendQueueSize > 0, "WS_SEND_QUEUE_SIZE must be positive, got %d", cnf.WsSendQueueSize)
+	oneOf("WS_SEND_QUEUE_POLICY", cnf.WsSendQueuePolicy, SendQueueDropOldest, SendQueueDisconnect)
+	notNegative("WS_PING_PERIOD", int64(cnf.WsPingPeriod))
+	notNegative("WS_PONG_WAIT", int64(cnf.WsPongWait))
+	check(cnf.WsPingPeriod == 0 || cnf.WsPongWait == 0 || cnf.WsPingPeriod < cnf.WsPongWait,
+		"WS_PING_PERIOD must be shorter than WS_PONG_WAIT, otherwise live peers are disconnected")
+	notNegative("WS_WRITE_WAIT", int64(cnf.WsWriteWait))
+	check(cnf.WsMaxMessageSize > 0, "WS_MAX_MESSAGE_SIZE must be positive, got %d", cnf.WsMaxMessageSize)
+	check(cnf.NodeId != "", "NODE_ID must not be empty")
+	oneOf("BROKER_BACKEND", cnf.BrokerBackend, BrokerMemory, BrokerMongo)
+	positive("PRESENCE_TTL", cnf.PresenceTTL)
+	positive("SHUTDOWN_TIMEOUT", cnf.ShutdownTimeout)
+	notNegative("SHUTDOWN_DRAIN_DELAY", int64(cnf.ShutdownDrainDelay))
+	positive("HEALTH_CHECK_TIMEOUT", cnf.HealthCheckTimeout)
+	notNegative("MESSAGES_MIGRATION_TIMEOUT", int64(cnf.MessagesMigrationTimeout))
+	_, err = logger.ParseLevel(cnf.LogLevel)
+	check(err == nil, "LOG_LEVEL must be one of debug, info, warn, error, got %q", cnf.LogLevel)
+	oneOf("LOG_FORMAT", cnf.LogFormat, string(logger.FormatJSON), string(logger.FormatLogfmt))
+	notNegative("TYPING_THROTTLE", int64(cnf.TypingThrottle))
+	notNegative("LOGIN_RATE_LIMIT_PER_MINUTE", int64(cnf.LoginRateLimitPerMinute))
+	notNegative("LOGIN_RATE_LIMIT_BURST", int64(cnf.LoginRateLimitBurst))
+	notNegative("REGISTER_RATE_LIMIT_PER_MINUTE", int64(cnf.RegisterRateLimitPerMinute))
+	notNegative("REGISTER_RATE_LIMIT_BURST", int64(cnf.RegisterRateLimitBurst))
+	notNegative("WS_MESSAGE_RATE_LIMIT_PER_SECOND", int64(cnf.WsMessageRateLimitPerSecond))
+	notNegative("WS_MESSAGE_RATE_LIMIT_BURST", int64(cnf.WsMessageRateLimitBurst))
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
@@ -14,16 +14,18 @@ var ErrConnIdConflict = errors.New("duplication of connection id")
 var ErrConnNotFound = errors.New("connection with provided id not found")
 
 type ConnectionsRepository interface {
-	AddConnection(context.Context, string, ws.ConnHelper, *models.User) error
+	AddConnection(context.Context, *models.Session, ws.ConnHelper) error
 	DeleteConnection(context.Context, string) error
 	CountConnections(context.Context) (int, error)
 	ConnectedClients(context.Context) ([]string, error)
-	GetAllConnections(context.Context) (map[string]ws.ConnHelper, error)
+	GetAllConnections(context.Context) (map[string][]ws.ConnHelper, error)
+	GetUserConnections(context.Context, string) ([]ws.ConnHelper, error)
+	GetConnection(context.Context, string) (ws.ConnHelper, error)
 }
 
 type connectionRecord struct {
-	conn ws.ConnHelper
-	usr  *models.User
+	conn    ws.ConnHelper
+	session *models.Session
 }
 
 type connectionsStorage struct {
@@ -38,15 +40,15 @@ func NewConnectionsRepository() ConnectionsRepository {
 	}
 }
 
-func (r *connectionsStorage) AddConnection(ctx context.Context, id string, connection ws.ConnHelper, usr *models.User) error {
+func (r *connectionsStorage) AddConnection(ctx context.Context, session *models.Session, connection ws.ConnHelper) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	if r.db[id] != nil {
+	if r.db[session.Id] != nil {
 		return ErrConnIdConflict
 	}
-	r.db[id] = &connectionRecord{
-		conn: connection,
-		usr:  usr,
+	r.db[session.Id] = &connectionRecord{
+		conn:    connection,
+		session: session,
 	}
 	return nil
 }
@@ -72,17 +74,40 @@ func (r *connectionsStorage) ConnectedClients(ctx context.Context) ([]string, er
 	defer r.mu.Unlock()
 	var res []string = []string{}
 	for _, record := range r.db {
-		res = append(res, fmt.Sprintf("%s-%s", record.usr.Id, record.usr.UserName))
+		res = append(res, fmt.Sprintf("%s-%s", record.session.UserId, record.session.UserName))
 	}
 	return res, nil
 }
 
-func (r *connectionsStorage) GetAllConnections(ctx context.Context) (map[string]ws.ConnHelper, error) {
+// GetAllConnections returns connections grouped by id of the connected user.
+func (r *connectionsStorage) GetAllConnections(ctx context.Context) (map[string][]ws.ConnHelper, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	conns := make(map[string]ws.ConnHelper)
+	conns := make(map[string][]ws.ConnHelper)
 	for _, record := range r.db {
-		conns[record.usr.Id] = record.conn
+		conns[record.session.UserId] = append(conns[record.session.UserId], record.conn)
 	}
 	return conns, nil
 }
+
+func (r *connectionsStorage) GetUserConnections(ctx context.Context, userId string) ([]ws.ConnHelper, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var conns []ws.ConnHelper
+	for _, record := range r.db {
+		if record.session.UserId == userId {
+			conns = append(conns, record.conn)
+		}
+	}
+	return conns, nil
+}
+
+func (r *connectionsStorage) GetConnection(ctx context.Context, id string) (ws.ConnHelper, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record := r.db[id]
+	if record == nil {
+		return nil, ErrConnNotFound
+	}
+	return record.conn, nil
+}
@@ -30,7 +30,7 @@ func TestAddConnection(t *testing.T) {
 			connId: "someid2",
 			want:   ErrConnIdConflict,
 			prepareRepo: func(cr ConnectionsRepository) ConnectionsRepository {
-				cr.AddConnection(context.Background(), "someid2", ws.NewConn(&websocket.Conn{}), &models.User{})
+				cr.AddConnection(context.Background(), &models.Session{Id: "someid2"}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
 				return cr
 			},
 		},
@@ -40,7 +40,7 @@ func TestAddConnection(t *testing.T) {
 		t.Run(fmt.Sprintf("AddConnection(%v, %v) == %v", context.Background(), testCond.connId, testCond.want), func(t *testing.T) {
 			ctx := context.Background()
 			repo := testCond.prepareRepo(NewConnectionsRepository())
-			got := repo.AddConnection(ctx, testCond.connId, ws.NewConn(&websocket.Conn{}), &models.User{})
+			got := repo.AddConnection(ctx, &models.Session{Id: testCond.connId}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
 
 			assert.Equal(t, testCond.want, got, "AddConnection returned unexpected result: got %v want %v", got, testCond.want)
 		})
@@ -60,7 +60,7 @@ func TestDeleteConnection(t *testing.T) {
 			connId: "someid2",
 			want:   nil,
 			prepareRepo: func(cr ConnectionsRepository) ConnectionsRepository {
-				cr.AddConnection(context.Background(), "someid2", ws.NewConn(&websocket.Conn{}), &models.User{})
+				cr.AddConnection(context.Background(), &models.Session{Id: "someid2"}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
 				return cr
 			},
 		},
@@ -80,7 +80,7 @@ func TestDeleteConnection(t *testing.T) {
 func TestCountConnectionsSuccess(t *testing.T) {
 	ctx := context.Background()
 	repo := NewConnectionsRepository()
-	repo.AddConnection(ctx, "someid", ws.NewConn(&websocket.Conn{}), &models.User{})
+	repo.AddConnection(ctx, &models.Session{Id: "someid"}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
 
 	gotCount, gotErr := repo.CountConnections(ctx)
 
@@ -91,10 +91,7 @@ func TestCountConnectionsSuccess(t *testing.T) {
 func TestConnectedClientsSuccess(t *testing.T) {
 	ctx := context.Background()
 	repo := NewConnectionsRepository()
-	repo.AddConnection(ctx, "someid", ws.NewConn(&websocket.Conn{}), &models.User{
-		Id:       "someid",
-		UserName: "somename",
-	})
+	repo.AddConnection(ctx, &models.Session{Id: "someid", UserId: "someid", UserName: "somename"}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
 	want := []string{"someid-somename"}
 
 	got, gotErr := repo.ConnectedClients(ctx)
@@ -106,15 +103,46 @@ func TestConnectedClientsSuccess(t *testing.T) {
 func TestGetAllConnections(t *testing.T) {
 	ctx := context.Background()
 	repo := NewConnectionsRepository()
-	wc := ws.NewConn(&websocket.Conn{})
-	repo.AddConnection(ctx, "someid", wc, &models.User{
-		Id:       "someid",
-		UserName: "somename",
-	})
-	want := map[string]ws.ConnHelper{"someid": wc}
+	wc := ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{})
+	wc2 := ws.NewConn(&websocket.Conn{}, ws.ProtocolLegacy, ws.ConnOptions{})
+	repo.AddConnection(ctx, &models.Session{Id: "conn1", UserId: "someid", UserName: "somename"}, wc)
+	repo.AddConnection(ctx, &models.Session{Id: "conn2", UserId: "someid", UserName: "somename"}, wc2)
+	want := []ws.ConnHelper{wc, wc2}
 
 	got, gotErr := repo.GetAllConnections(ctx)
 
 	assert.Nil(t, gotErr, "GetAllConnections returned unexpected result: got error %v want %v", gotErr, nil)
-	assert.Equal(t, got, want, "GetAllConnections returned unexpected result: got %v want %v", got, want)
+	assert.ElementsMatch(t, want, got["someid"], "GetAllConnections returned unexpected result: got %v want %v", got, want)
+	assert.Len(t, got, 1, "GetAllConnections returned connections of unexpected users: got %v", got)
+}
+
+func TestGetUserConnections(t *testing.T) {
+	ctx := context.Background()
+	repo := NewConnectionsRepository()
+	usr := &models.User{Id: "someid", UserName: "somename"}
+	wc := ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{})
+	repo.AddConnection(ctx, models.NewSession("conn1", usr, "", ""), wc)
+	repo.AddConnection(ctx, &models.Session{Id: "conn2", UserId: "otherid"}, ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{}))
+	want := []ws.ConnHelper{wc}
+
+	got, gotErr := repo.GetUserConnections(ctx, usr.Id)
+
+	assert.Nil(t, gotErr, "GetUserConnections returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, want, got, "GetUserConnections returned unexpected result: got %v want %v", got, want)
+}
+
+func TestGetConnection(t *testing.T) {
+	ctx := context.Background()
+	repo := NewConnectionsRepository()
+	wc := ws.NewConn(&websocket.Conn{}, ws.ProtocolJSON, ws.ConnOptions{})
+	repo.AddConnection(ctx, &models.Session{Id: "conn1", UserId: "someid"}, wc)
+
+	got, gotErr := repo.GetConnection(ctx, "conn1")
+
+	assert.Nil(t, gotErr, "GetConnection returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, wc, got, "GetConnection returned unexpected result: got %v want %v", got, wc)
+
+	_, gotErr = repo.GetConnection(ctx, "conn2")
+
+	assert.Equal(t, ErrConnNotFound, gotErr, "GetConnection returned unexpected result: got error %v want %v", gotErr, ErrConnNotFound)
 }
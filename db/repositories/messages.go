@@ -2,51 +2,382 @@ package repositories
 
 import (
 	"context"
-	"fmt"
-	"log"
+	"errors"
 
 	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/google/uuid"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrMessageNotFound = errors.New("message not found")
+var ErrMessageChanged = errors.New("message has been changed meanwhile")
+
+// Messages sharing the same time are ordered by id to keep pagination stable.
+var messagesAscOrder = bson.D{{Key: "time", Value: 1}, {Key: "_id", Value: 1}}
+var messagesDescOrder = bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}
+
+// MessagesRepository stores every message once and keeps delivery state of each recipient
+// separately. Messages returned for a recipient are recipient's copies identified by delivery id,
+// their origin id is the id of the shared message.
 type MessagesRepository interface {
 	SaveMessage(context.Context, *models.Message) (string, error)
-	FindUserMessages(context.Context, string) ([]*models.Message, error)
+	SaveDeliveries(context.Context, *models.Message, []string) ([]*models.Message, error)
+	FindUndeliveredMessages(context.Context, string) ([]*models.Message, error)
+	FindDirectMessages(context.Context, string, string) ([]*models.Message, error)
+	FindMessages(context.Context, *models.MessagesQuery) ([]*models.Message, error)
+	FindMessageReceipts(context.Context, string, string) ([]*models.Message, error)
+	MarkMessageDelivered(context.Context, string, int64) error
+	MarkMessageRead(context.Context, string, string, int64) (*models.Message, error)
+	FindMessage(context.Context, string) (*models.Message, error)
+	UpdateMessageContent(context.Context, *models.Message, int) error
+	FindPendingUpdates(context.Context, string) ([]*models.Message, error)
+	MarkUpdateDelivered(context.Context, string, string) error
+	DeleteRecipientDeliveries(context.Context, string) error
+}
+
+// messageRecord is a messages collection document shared by all recipients.
+type messageRecord struct {
+	Id         string                    `bson:"_id"`
+	SenderId   string                    `bson:"senderId"`
+	SenderName string                    `bson:"senderName"`
+	RoomId     string                    `bson:"roomId,omitempty"`
+	Direct     bool                      `bson:"direct,omitempty"`
+	Payload    string                    `bson:"payload"`
+	Time       int64                     `bson:"time"`
+	EditedAt   int64                     `bson:"editedAt,omitempty"`
+	History    []*models.MessageRevision `bson:"history,omitempty"`
+	DeletedAt  int64                     `bson:"deletedAt,omitempty"`
+}
+
+// deliveryRecord is a deliveries collection document with state of recipient's copy.
+// Sender, room, direct flag and time are copied from the message to filter and sort deliveries.
+type deliveryRecord struct {
+	Id            string `bson:"_id"`
+	MessageId     string `bson:"messageId"`
+	RecipientId   string `bson:"recipientId"`
+	SenderId      string `bson:"senderId"`
+	RoomId        string `bson:"roomId,omitempty"`
+	Direct        bool   `bson:"direct,omitempty"`
+	Time          int64  `bson:"time"`
+	DeliveredAt   int64  `bson:"deliveredAt,omitempty"`
+	ReadAt        int64  `bson:"readAt,omitempty"`
+	UpdatePending bool   `bson:"updatePending,omitempty"`
+}
+
+func newMessageRecord(msg *models.Message) *messageRecord {
+	return &messageRecord{
+		Id:         msg.LogicalId(),
+		SenderId:   msg.SenderId,
+		SenderName: msg.SenderName,
+		RoomId:     msg.RoomId,
+		Direct:     msg.Direct,
+		Payload:    msg.Payload,
+		Time:       msg.Time,
+		EditedAt:   msg.EditedAt,
+		History:    msg.History,
+		DeletedAt:  msg.DeletedAt,
+	}
+}
+
+func newDeliveryRecord(id string, msg *models.Message, recipientId string) *deliveryRecord {
+	return &deliveryRecord{
+		Id:          id,
+		MessageId:   msg.LogicalId(),
+		RecipientId: recipientId,
+		SenderId:    msg.SenderId,
+		RoomId:      msg.RoomId,
+		Direct:      msg.Direct,
+		Time:        msg.Time,
+	}
+}
+
+func (mr *messageRecord) message() *models.Message {
+	return &models.Message{
+		Id:         mr.Id,
+		SenderId:   mr.SenderId,
+		SenderName: mr.SenderName,
+		RoomId:     mr.RoomId,
+		Direct:     mr.Direct,
+		Payload:    mr.Payload,
+		Time:       mr.Time,
+		EditedAt:   mr.EditedAt,
+		History:    mr.History,
+		DeletedAt:  mr.DeletedAt,
+	}
+}
+
+// copy returns recipient's copy of the message, content is left empty when message is not known.
+func (dr *deliveryRecord) copy(content *messageRecord) *models.Message {
+	msg := &models.Message{SenderId: dr.SenderId, RoomId: dr.RoomId, Direct: dr.Direct, Time: dr.Time}
+	if content != nil {
+		msg = content.message()
+	}
+	msg.Id = dr.Id
+	msg.OriginId = dr.MessageId
+	msg.RecipientId = dr.RecipientId
+	msg.DeliveredAt = dr.DeliveredAt
+	msg.ReadAt = dr.ReadAt
+	msg.UpdatePending = dr.UpdatePending
+	return msg
 }
 
 type messagesRepository struct {
-	db mongo.MessagesCollection
+	db         mongo.MessagesCollection
+	deliveries mongo.DeliveriesCollection
 }
 
-func NewMessagesRepository(db mongo.MessagesCollection) MessagesRepository {
+func NewMessagesRepository(db mongo.MessagesCollection, deliveries mongo.DeliveriesCollection) MessagesRepository {
 	return &messagesRepository{
-		db: db,
+		db:         db,
+		deliveries: deliveries,
 	}
 }
 
+// SaveMessage stores content of the message shared by all its recipients.
 func (r *messagesRepository) SaveMessage(ctx context.Context, msg *models.Message) (string, error) {
-	res, err := r.db.InsertOne(ctx, msg)
-	if err != nil {
-		log.Printf("Unable to save message data into database. Reason: %s", err.Error())
+	record := newMessageRecord(msg)
+	if _, err := r.db.InsertOne(ctx, record); err != nil {
+		logger.FromContext(ctx).Error("Unable to save message data into database", "err", err)
 		return "", err
 	}
-	return fmt.Sprintf("%v", res), nil
+	return record.Id, nil
+}
+
+// SaveDeliveries creates undelivered copies of the saved message for the recipients with a single write.
+func (r *messagesRepository) SaveDeliveries(ctx context.Context, msg *models.Message, recipientIds []string) ([]*models.Message, error) {
+	if len(recipientIds) == 0 {
+		return nil, nil
+	}
+	content := newMessageRecord(msg)
+	records := make([]interface{}, 0, len(recipientIds))
+	copies := make([]*models.Message, 0, len(recipientIds))
+	for _, recipientId := range recipientIds {
+		record := newDeliveryRecord(uuid.NewString(), msg, recipientId)
+		records = append(records, record)
+		copies = append(copies, record.copy(content))
+	}
+	if _, err := r.deliveries.InsertMany(ctx, records); err != nil {
+		logger.FromContext(ctx).Error("Unable to save message deliveries into database", "err", err)
+		return nil, err
+	}
+	return copies, nil
+}
+
+func (r *messagesRepository) FindUndeliveredMessages(ctx context.Context, id string) ([]*models.Message, error) {
+	filter := bson.M{"recipientId": id, "deliveredAt": bson.M{"$exists": false}}
+	return r.findMessages(ctx, filter, options.Find().SetSort(messagesAscOrder))
+}
+
+func (r *messagesRepository) FindDirectMessages(ctx context.Context, userId string, peerId string) ([]*models.Message, error) {
+	filter := bson.M{
+		"direct": true,
+		"$or": bson.A{
+			bson.M{"senderId": userId, "recipientId": peerId},
+			bson.M{"senderId": peerId, "recipientId": userId},
+		},
+	}
+	return r.findMessages(ctx, filter, options.Find().SetSort(messagesAscOrder))
+}
+
+func (r *messagesRepository) FindMessages(ctx context.Context, q *models.MessagesQuery) ([]*models.Message, error) {
+	filter := bson.M{"recipientId": q.RecipientId}
+	if q.SenderId != "" {
+		filter["senderId"] = q.SenderId
+	}
+	timeRange := bson.M{}
+	if q.From != 0 {
+		timeRange["$gte"] = q.From
+	}
+	if q.To != 0 {
+		timeRange["$lte"] = q.To
+	}
+	if len(timeRange) > 0 {
+		filter["time"] = timeRange
+	}
+	if q.After != nil {
+		filter["$or"] = bson.A{
+			bson.M{"time": bson.M{"$lt": q.After.Time}},
+			bson.M{"time": q.After.Time, "_id": bson.M{"$lt": q.After.Id}},
+		}
+	}
+
+	opts := options.Find().SetSort(messagesDescOrder).SetLimit(int64(q.Limit))
+	return r.findMessages(ctx, filter, opts)
+}
+
+// FindMessageReceipts returns all recipients' copies of the message sent by the sender.
+func (r *messagesRepository) FindMessageReceipts(ctx context.Context, senderId, messageId string) ([]*models.Message, error) {
+	filter := bson.M{"senderId": senderId, "messageId": messageId}
+	return r.findMessages(ctx, filter, options.Find().SetSort(bson.D{{Key: "recipientId", Value: 1}}))
+}
+
+func (r *messagesRepository) MarkMessageDelivered(ctx context.Context, id string, at int64) error {
+	res, err := r.deliveries.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"deliveredAt": at}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to mark message as delivered", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrMessageNotFound
+	}
+	return nil
+}
+
+// MarkMessageRead marks recipient's copy of the message as read and returns it without content.
+// Message read for the second time keeps its original read time.
+func (r *messagesRepository) MarkMessageRead(ctx context.Context, recipientId, messageId string, at int64) (*models.Message, error) {
+	record := &deliveryRecord{}
+	err := r.deliveries.FindOne(ctx, bson.M{"recipientId": recipientId, "messageId": messageId}).Decode(record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMessageNotFound
+		}
+		logger.FromContext(ctx).Error("Unable to find message", "err", err)
+		return nil, err
+	}
+	if record.ReadAt != 0 {
+		return record.copy(nil), nil
+	}
+
+	update := bson.M{"readAt": at}
+	if record.DeliveredAt == 0 {
+		update["deliveredAt"] = at
+		record.DeliveredAt = at
+	}
+	if _, err = r.deliveries.UpdateOne(ctx, bson.M{"_id": record.Id}, bson.M{"$set": update}); err != nil {
+		logger.FromContext(ctx).Error("Unable to mark message as read", "err", err)
+		return nil, err
+	}
+	record.ReadAt = at
+	return record.copy(nil), nil
 }
 
-func (r *messagesRepository) FindUserMessages(ctx context.Context, id string) ([]*models.Message, error) {
-	res, err := r.db.Find(ctx, bson.M{"recipientId": id})
+// FindMessage returns the shared message without recipient's delivery state.
+func (r *messagesRepository) FindMessage(ctx context.Context, messageId string) (*models.Message, error) {
+	record := &messageRecord{}
+	err := r.db.FindOne(ctx, bson.M{"_id": messageId}).Decode(record)
 	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrMessageNotFound
+		}
+		logger.FromContext(ctx).Error("Unable to find message", "err", err)
 		return nil, err
 	}
+	return record.message(), nil
+}
 
-	var messages []*models.Message
-	if err = res.All(ctx, &messages); err != nil {
+// UpdateMessageContent writes payload, history and deletion state of the message unless it was deleted
+// or edited since it was loaded with the revision, ErrMessageChanged is returned then.
+// Copies which were already delivered are marked as waiting for the update notification.
+func (r *messagesRepository) UpdateMessageContent(ctx context.Context, msg *models.Message, revision int) error {
+	update := bson.M{"$set": bson.M{"payload": msg.Payload, "editedAt": msg.EditedAt, "history": msg.History}}
+	if msg.IsDeleted() {
+		update = bson.M{"$set": bson.M{"payload": "", "deletedAt": msg.DeletedAt}, "$unset": bson.M{"history": ""}}
+	}
+	// history has an entry per edit and is not stored until the first one
+	history := bson.M{"$exists": false}
+	if revision > 0 {
+		history = bson.M{"$size": revision}
+	}
+	filter := bson.M{"_id": msg.LogicalId(), "senderId": msg.SenderId, "deletedAt": bson.M{"$exists": false}, "history": history}
+	res, err := r.db.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to update message content", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrMessageChanged
+	}
+
+	filter = bson.M{"messageId": msg.LogicalId(), "deliveredAt": bson.M{"$exists": true}}
+	if _, err = r.deliveries.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"updatePending": true}}); err != nil {
+		logger.FromContext(ctx).Error("Unable to mark message update as pending", "err", err)
+		return err
+	}
+	return nil
+}
+
+// FindPendingUpdates returns delivered copies of the recipient which were edited or deleted since.
+func (r *messagesRepository) FindPendingUpdates(ctx context.Context, recipientId string) ([]*models.Message, error) {
+	filter := bson.M{"recipientId": recipientId, "updatePending": true}
+	return r.findMessages(ctx, filter, options.Find().SetSort(messagesAscOrder))
+}
+
+func (r *messagesRepository) MarkUpdateDelivered(ctx context.Context, recipientId, messageId string) error {
+	filter := bson.M{"recipientId": recipientId, "messageId": messageId}
+	if _, err := r.deliveries.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"updatePending": ""}}); err != nil {
+		logger.FromContext(ctx).Error("Unable to mark message update as delivered", "err", err)
+		return err
+	}
+	return nil
+}
+
+// findMessages finds deliveries matching the filter and joins them with content of their messages.
+func (r *messagesRepository) findMessages(ctx context.Context, filter bson.M, opts ...*options.FindOptions) ([]*models.Message, error) {
+	res, err := r.deliveries.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var deliveries []*deliveryRecord
+	if err = res.All(ctx, &deliveries); err != nil {
 		if err == mongo.ErrNoDocuments {
-			return messages, nil
+			return nil, nil
 		}
 		return nil, err
 	}
+	if len(deliveries) == 0 {
+		return nil, nil
+	}
 
+	contents, err := r.findContents(ctx, deliveries)
+	if err != nil {
+		return nil, err
+	}
+	messages := make([]*models.Message, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		content, ok := contents[delivery.MessageId]
+		if !ok {
+			logger.FromContext(ctx).Warn("Skipping delivery of missing message", "deliveryId", delivery.Id, "messageId", delivery.MessageId)
+			continue
+		}
+		messages = append(messages, delivery.copy(content))
+	}
 	return messages, nil
 }
+
+func (r *messagesRepository) findContents(ctx context.Context, deliveries []*deliveryRecord) (map[string]*messageRecord, error) {
+	ids := bson.A{}
+	seen := map[string]bool{}
+	for _, delivery := range deliveries {
+		if !seen[delivery.MessageId] {
+			seen[delivery.MessageId] = true
+			ids = append(ids, delivery.MessageId)
+		}
+	}
+
+	res, err := r.db.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	var records []*messageRecord
+	if err = res.All(ctx, &records); err != nil && err != mongo.ErrNoDocuments {
+		return nil, err
+	}
+	contents := make(map[string]*messageRecord, len(records))
+	for _, record := range records {
+		contents[record.Id] = record
+	}
+	return contents, nil
+}
+
+// DeleteRecipientDeliveries removes all copies addressed to the recipient, messages stay for other recipients.
+func (r *messagesRepository) DeleteRecipientDeliveries(ctx context.Context, recipientId string) error {
+	if _, err := r.deliveries.DeleteMany(ctx, bson.M{"recipientId": recipientId}); err != nil {
+		logger.FromContext(ctx).Error("Unable to delete deliveries of recipient", "err", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// writeCounter is a collection which only measures write requests and inserted documents.
+type writeCounter struct {
+	mongo.CollectionHelper
+	writes int
+	docs   int
+	bytes  int
+}
+
+func (c *writeCounter) InsertOne(ctx context.Context, doc interface{}) (interface{}, error) {
+	c.writes++
+	return nil, c.count(doc)
+}
+
+func (c *writeCounter) InsertMany(ctx context.Context, docs []interface{}) ([]interface{}, error) {
+	c.writes++
+	for _, doc := range docs {
+		if err := c.count(doc); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (c *writeCounter) count(doc interface{}) error {
+	raw, err := bson.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	c.docs++
+	c.bytes += len(raw)
+	return nil
+}
+
+func benchmarkRecipients(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = uuid.NewString()
+	}
+	return ids
+}
+
+// BenchmarkBroadcastWrites compares write requests, documents and bytes written to deliver a room message
+// as a copy per recipient and as a single message with per-recipient delivery state.
+func BenchmarkBroadcastWrites(b *testing.B) {
+	ctx := context.Background()
+	sender := &models.User{Id: uuid.NewString(), UserName: "sender"}
+	frame := &models.Frame{Id: uuid.NewString(), RoomId: uuid.NewString(), Payload: strings.Repeat("x", 256), Time: 1}
+
+	for _, n := range []int{10, 100, 1000} {
+		recipientIds := benchmarkRecipients(n)
+
+		b.Run(fmt.Sprintf("copies/%d", n), func(b *testing.B) {
+			messages := &writeCounter{}
+			for i := 0; i < b.N; i++ {
+				for _, rId := range recipientIds {
+					msg := models.NewSharedMessage(frame, sender)
+					msg.Id, msg.OriginId, msg.RecipientId = uuid.NewString(), frame.Id, rId
+					if _, err := messages.InsertOne(ctx, msg); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+			b.ReportMetric(float64(messages.writes)/float64(b.N), "writes/op")
+			b.ReportMetric(float64(messages.docs)/float64(b.N), "docs/op")
+			b.ReportMetric(float64(messages.bytes)/float64(b.N), "bytes/op")
+		})
+
+		b.Run(fmt.Sprintf("shared/%d", n), func(b *testing.B) {
+			messages, deliveries := &writeCounter{}, &writeCounter{}
+			repo := NewMessagesRepository(messages, deliveries)
+			for i := 0; i < b.N; i++ {
+				msg := models.NewSharedMessage(frame, sender)
+				if _, err := repo.SaveMessage(ctx, msg); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := repo.SaveDeliveries(ctx, msg, recipientIds); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(messages.writes+deliveries.writes)/float64(b.N), "writes/op")
+			b.ReportMetric(float64(messages.docs+deliveries.docs)/float64(b.N), "docs/op")
+			b.ReportMetric(float64(messages.bytes+deliveries.bytes)/float64(b.N), "bytes/op")
+		})
+	}
+}
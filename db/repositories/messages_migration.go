@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const migrationBatchSize = 500
+
+// legacyCopyFilter matches recipients' copies stored in the messages collection
+// before delivery state was moved to the deliveries collection.
+var legacyCopyFilter = bson.M{"recipientId": bson.M{"$exists": true}}
+
+var legacyCopyFields = bson.M{
+	"originId":      "",
+	"recipientId":   "",
+	"deliveredAt":   "",
+	"readAt":        "",
+	"updatePending": "",
+}
+
+// MigrateMessages runs MigrateMessageCopies on start when MESSAGES_MIGRATION_TIMEOUT is set.
+// Failed or timed out migration is only logged, so the server starts anyway and the next run resumes it.
+func MigrateMessages(ctx context.Context, cnf *config.ServerConfig, messages mongo.MessagesCollection, deliveries mongo.DeliveriesCollection) {
+	if cnf.MessagesMigrationTimeout == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, cnf.MessagesMigrationTimeout)
+	defer cancel()
+	migrated, err := MigrateMessageCopies(ctx, messages, deliveries)
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to migrate messages", "migrated", migrated, "err", err)
+		return
+	}
+	logger.FromContext(ctx).Info("Migrated message copies", "count", migrated)
+}
+
+// MigrateMessageCopies moves delivery state of every recipient's copy into the deliveries
+// collection and keeps a single message per origin id. Copies are migrated one by one and
+// every step is idempotent, so interrupted migration is completed by the next run.
+// It returns number of migrated copies.
+func MigrateMessageCopies(ctx context.Context, messages mongo.MessagesCollection, deliveries mongo.DeliveriesCollection) (int, error) {
+	migrated := 0
+	for {
+		res, err := messages.Find(ctx, legacyCopyFilter, options.Find().SetLimit(migrationBatchSize))
+		if err != nil {
+			return migrated, err
+		}
+		var copies []*models.Message
+		if err = res.All(ctx, &copies); err != nil && err != mongo.ErrNoDocuments {
+			return migrated, err
+		}
+		if len(copies) == 0 {
+			return migrated, nil
+		}
+
+		for _, msg := range copies {
+			if err = migrateMessageCopy(ctx, messages, deliveries, msg); err != nil {
+				logger.FromContext(ctx).Error("Unable to migrate message", "messageId", msg.Id, "err", err)
+				return migrated, err
+			}
+			migrated++
+		}
+	}
+}
+
+func migrateMessageCopy(ctx context.Context, messages mongo.MessagesCollection, deliveries mongo.DeliveriesCollection, msg *models.Message) error {
+	delivery := newDeliveryRecord(msg.Id, msg, msg.RecipientId)
+	delivery.DeliveredAt = msg.DeliveredAt
+	delivery.ReadAt = msg.ReadAt
+	delivery.UpdatePending = msg.UpdatePending
+	if _, err := deliveries.InsertOne(ctx, delivery); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+
+	// copy stored without origin id becomes the shared message itself
+	if msg.Id == msg.LogicalId() {
+		_, err := messages.UpdateOne(ctx, bson.M{"_id": msg.Id}, bson.M{"$unset": legacyCopyFields})
+		return err
+	}
+
+	if _, err := messages.InsertOne(ctx, newMessageRecord(msg)); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	_, err := messages.DeleteMany(ctx, bson.M{"_id": msg.Id})
+	return err
+}
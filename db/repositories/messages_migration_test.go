@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestMigrateMessageCopies(t *testing.T) {
+	unknownErr := errors.New("Unable to insert")
+	copies := []*models.Message{
+		{Id: "c1", OriginId: "1", RecipientId: "r1", SenderId: "s", Payload: "hi", Time: 5, DeliveredAt: 6, ReadAt: 7},
+		{Id: "2", RecipientId: "r2", SenderId: "s", Payload: "hey", Time: 8},
+	}
+	returnBatches := func(ch *mocks.CollectionHelper, batches ...[]*models.Message) {
+		for _, batch := range batches {
+			batch := batch
+			mrh := new(mocks.MultiResultHelper)
+			mrh.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(1).(*[]*models.Message) = batch
+			}).Return(nil)
+			ch.On("Find", mock.Anything, legacyCopyFilter, mock.Anything).Return(mrh, nil).Once()
+		}
+	}
+	testConditions := []struct {
+		tName        string
+		wantCount    int
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper, *mocks.CollectionHelper)
+	}{
+		{
+			tName:     "should move delivery state and keep single message",
+			wantCount: 2,
+			prepareMocks: func(ch, dh *mocks.CollectionHelper) {
+				returnBatches(ch, copies, nil)
+				dh.On("InsertOne", mock.Anything, &deliveryRecord{Id: "c1", MessageId: "1", RecipientId: "r1", SenderId: "s", Time: 5, DeliveredAt: 6, ReadAt: 7}).Return("c1", nil)
+				ch.On("InsertOne", mock.Anything, &messageRecord{Id: "1", SenderId: "s", Payload: "hi", Time: 5}).Return("1", nil)
+				ch.On("DeleteMany", mock.Anything, bson.M{"_id": "c1"}).Return(int64(1), nil)
+				dh.On("InsertOne", mock.Anything, &deliveryRecord{Id: "2", MessageId: "2", RecipientId: "r2", SenderId: "s", Time: 8}).Return("2", nil)
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": "2"}, bson.M{"$unset": legacyCopyFields}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:     "should fail with some error",
+			wantCount: 0,
+			wantErr:   unknownErr,
+			prepareMocks: func(ch, dh *mocks.CollectionHelper) {
+				returnBatches(ch, copies)
+				dh.On("InsertOne", mock.Anything, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch, dh)
+
+			gotCount, gotErr := MigrateMessageCopies(context.Background(), ch, dh)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "MigrateMessageCopies returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantCount, gotCount, "MigrateMessageCopies returned unexpected result: got count %v want %v", gotCount, testCond.wantCount)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMigrateMessages(t *testing.T) {
+	testConditions := []struct {
+		tName        string
+		timeout      time.Duration
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName:        "should skip migration when timeout is not set",
+			prepareMocks: func(ch *mocks.CollectionHelper) {},
+		},
+		{
+			tName:   "should migrate within timeout",
+			timeout: time.Minute,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				mrh := new(mocks.MultiResultHelper)
+				mrh.On("All", mock.Anything, mock.Anything).Return(nil)
+				withDeadline := mock.MatchedBy(func(ctx context.Context) bool {
+					_, ok := ctx.Deadline()
+					return ok
+				})
+				ch.On("Find", withDeadline, legacyCopyFilter, mock.Anything).Return(mrh, nil).Once()
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch)
+			cnf := &config.ServerConfig{MessagesMigrationTimeout: testCond.timeout}
+
+			MigrateMessages(context.Background(), cnf, ch, dh)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+		})
+	}
+}
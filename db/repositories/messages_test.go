@@ -11,10 +11,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func TestSaveMessage(t *testing.T) {
-	fakeMsg := &models.Message{Payload: "hello"}
+	fakeMsg := &models.Message{Id: "1", SenderId: "2", Payload: "hello", Time: 10}
+	fakeRecord := &messageRecord{Id: "1", SenderId: "2", Payload: "hello", Time: 10}
 	unknownErr := errors.New("Unable to save")
 	testConditions := []struct {
 		tName        string
@@ -30,7 +32,7 @@ func TestSaveMessage(t *testing.T) {
 			wantErr: nil,
 			prepareMocks: func(ch *mocks.CollectionHelper) {
 				ctx := context.Background()
-				ch.On("InsertOne", ctx, fakeMsg).Return("1", nil)
+				ch.On("InsertOne", ctx, fakeRecord).Return("1", nil)
 			},
 		},
 		{
@@ -39,7 +41,7 @@ func TestSaveMessage(t *testing.T) {
 			wantErr: unknownErr,
 			prepareMocks: func(ch *mocks.CollectionHelper) {
 				ctx := context.Background()
-				ch.On("InsertOne", ctx, fakeMsg).Return(nil, unknownErr)
+				ch.On("InsertOne", ctx, fakeRecord).Return(nil, unknownErr)
 			},
 		},
 	}
@@ -48,8 +50,9 @@ func TestSaveMessage(t *testing.T) {
 		t.Run(testCond.tName, func(t *testing.T) {
 			ctx := context.Background()
 			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
 			testCond.prepareMocks(ch)
-			repo := NewMessagesRepository(ch)
+			repo := NewMessagesRepository(ch, dh)
 
 			gotId, gotErr := repo.SaveMessage(ctx, testCond.msg)
 
@@ -57,14 +60,80 @@ func TestSaveMessage(t *testing.T) {
 			assert.Equal(t, testCond.wantId, gotId, "SaveMessage returned unexpected result: got Id %v want %v", gotId, testCond.wantId)
 
 			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
 		})
 	}
 }
 
-func TestFindUserMessages(t *testing.T) {
+func TestSaveDeliveries(t *testing.T) {
+	msg := &models.Message{Id: "1", SenderId: "2", SenderName: "bob", RoomId: "3", Payload: "hello", Time: 10}
+	unknownErr := errors.New("Unable to save")
+	testConditions := []struct {
+		tName        string
+		recipientIds []string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName:        "should save deliveries with a single write",
+			recipientIds: []string{"4", "5"},
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+				dh.On("InsertMany", mock.Anything, mock.MatchedBy(func(docs []interface{}) bool {
+					if len(docs) != 2 {
+						return false
+					}
+					first, second := docs[0].(*deliveryRecord), docs[1].(*deliveryRecord)
+					return first.MessageId == "1" && first.RecipientId == "4" && first.RoomId == "3" && first.Time == 10 &&
+						second.RecipientId == "5" && first.Id != second.Id
+				})).Return([]interface{}{"a", "b"}, nil)
+			},
+		},
+		{
+			tName: "should skip database when there are no recipients",
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+			},
+		},
+		{
+			tName:        "should fail with some error",
+			recipientIds: []string{"4"},
+			wantErr:      unknownErr,
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+				dh.On("InsertMany", mock.Anything, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			testCond.prepareMocks(dh)
+			repo := NewMessagesRepository(ch, dh)
+
+			gotCopies, gotErr := repo.SaveDeliveries(context.Background(), msg, testCond.recipientIds)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "SaveDeliveries returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			if testCond.wantErr == nil {
+				assert.Len(t, gotCopies, len(testCond.recipientIds), "SaveDeliveries returned unexpected number of copies")
+			}
+			for i, copy := range gotCopies {
+				assert.Equal(t, testCond.recipientIds[i], copy.RecipientId, "SaveDeliveries returned unexpected result: got recipient %v want %v", copy.RecipientId, testCond.recipientIds[i])
+				assert.Equal(t, "1", copy.LogicalId(), "SaveDeliveries returned unexpected result: got origin %v want %v", copy.LogicalId(), "1")
+				assert.Equal(t, "hello", copy.Payload, "SaveDeliveries returned unexpected result: got payload %v want %v", copy.Payload, "hello")
+			}
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFindUndeliveredMessages(t *testing.T) {
 	errUnableToFind := errors.New("Unable to run find query")
 	errUnableToParse := errors.New("Unable to parse result")
 	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	opts := options.Find().SetSort(messagesAscOrder)
+	filter := bson.M{"recipientId": id, "deliveredAt": bson.M{"$exists": false}}
 	testConditions := []struct {
 		tName        string
 		id           string
@@ -76,35 +145,35 @@ func TestFindUserMessages(t *testing.T) {
 			tName:       "should fail with unable to find error",
 			id:          id,
 			expectedErr: errUnableToFind,
-			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
-				ch.On("Find", mock.Anything, bson.M{"recipientId": id}).Return(nil, errUnableToFind)
+			prepareMocks: func(dh *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				dh.On("Find", mock.Anything, filter, opts).Return(nil, errUnableToFind)
 			},
 		},
 		{
 			tName:       "should return empty list when no documents found",
 			id:          id,
 			expectedRes: []*models.Message(nil),
-			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+			prepareMocks: func(dh *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
 				mrh.On("All", mock.Anything, mock.Anything).Return(mongo.ErrNoDocuments)
-				ch.On("Find", mock.Anything, bson.M{"recipientId": id}).Return(mrh, nil)
+				dh.On("Find", mock.Anything, filter, opts).Return(mrh, nil)
 			},
 		},
 		{
 			tName:       "should fail with unable to parse result error",
 			id:          id,
 			expectedErr: errUnableToParse,
-			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+			prepareMocks: func(dh *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
 				mrh.On("All", mock.Anything, mock.Anything).Return(errUnableToParse)
-				ch.On("Find", mock.Anything, bson.M{"recipientId": id}).Return(mrh, nil)
+				dh.On("Find", mock.Anything, filter, opts).Return(mrh, nil)
 			},
 		},
 		{
 			tName:       "should succesfully return list of messages",
 			id:          id,
 			expectedRes: []*models.Message(nil),
-			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+			prepareMocks: func(dh *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
 				mrh.On("All", mock.Anything, mock.Anything).Return(nil)
-				ch.On("Find", mock.Anything, bson.M{"recipientId": id}).Return(mrh, nil)
+				dh.On("Find", mock.Anything, filter, opts).Return(mrh, nil)
 			},
 		},
 	}
@@ -112,18 +181,344 @@ func TestFindUserMessages(t *testing.T) {
 		t.Run(testCond.tName, func(t *testing.T) {
 			ctx := context.Background()
 			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
 			mrh := new(mocks.MultiResultHelper)
 
-			testCond.prepareMocks(ch, mrh)
-			repo := NewMessagesRepository(ch)
+			testCond.prepareMocks(dh, mrh)
+			repo := NewMessagesRepository(ch, dh)
 
-			gotRes, gotErr := repo.FindUserMessages(ctx, testCond.id)
+			gotRes, gotErr := repo.FindUndeliveredMessages(ctx, testCond.id)
 
-			assert.Equal(t, testCond.expectedErr, gotErr, "FindUserMessages returned unexpected error: got error %v want %v", gotErr, testCond.expectedErr)
-			assert.Equal(t, testCond.expectedRes, gotRes, "FindUserMessages returned unexpected result: got %v want %v", gotRes, testCond.expectedRes)
+			assert.Equal(t, testCond.expectedErr, gotErr, "FindUndeliveredMessages returned unexpected error: got error %v want %v", gotErr, testCond.expectedErr)
+			assert.Equal(t, testCond.expectedRes, gotRes, "FindUndeliveredMessages returned unexpected result: got %v want %v", gotRes, testCond.expectedRes)
 
 			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
 			mrh.AssertExpectations(t)
 		})
 	}
 }
+
+func TestFindDirectMessages(t *testing.T) {
+	errUnableToFind := errors.New("Unable to run find query")
+	userId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	peerId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	filter := bson.M{
+		"direct": true,
+		"$or": bson.A{
+			bson.M{"senderId": userId, "recipientId": peerId},
+			bson.M{"senderId": peerId, "recipientId": userId},
+		},
+	}
+	opts := options.Find().SetSort(messagesAscOrder)
+	testConditions := []struct {
+		tName        string
+		expectedErr  error
+		expectedRes  []*models.Message
+		prepareMocks func(*mocks.CollectionHelper, *mocks.CollectionHelper, *mocks.MultiResultHelper, *mocks.MultiResultHelper)
+	}{
+		{
+			tName:       "should fail with unable to find error",
+			expectedErr: errUnableToFind,
+			prepareMocks: func(ch, dh *mocks.CollectionHelper, drh, mrh *mocks.MultiResultHelper) {
+				dh.On("Find", mock.Anything, filter, opts).Return(nil, errUnableToFind)
+			},
+		},
+		{
+			tName: "should return conversation ordered by time joined with message content",
+			expectedRes: []*models.Message{
+				{Id: "c1", OriginId: "1", RecipientId: peerId, SenderId: userId, Direct: true, Payload: "hi", Time: 1},
+				{Id: "c2", OriginId: "2", RecipientId: userId, SenderId: peerId, Direct: true, Payload: "hey", Time: 2, DeliveredAt: 3},
+			},
+			prepareMocks: func(ch, dh *mocks.CollectionHelper, drh, mrh *mocks.MultiResultHelper) {
+				drh.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(1).(*[]*deliveryRecord) = []*deliveryRecord{
+						{Id: "c1", MessageId: "1", RecipientId: peerId, SenderId: userId, Direct: true, Time: 1},
+						{Id: "c2", MessageId: "2", RecipientId: userId, SenderId: peerId, Direct: true, Time: 2, DeliveredAt: 3},
+					}
+				}).Return(nil)
+				dh.On("Find", mock.Anything, filter, opts).Return(drh, nil)
+				mrh.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(1).(*[]*messageRecord) = []*messageRecord{
+						{Id: "2", SenderId: peerId, Direct: true, Payload: "hey", Time: 2},
+						{Id: "1", SenderId: userId, Direct: true, Payload: "hi", Time: 1},
+					}
+				}).Return(nil)
+				ch.On("Find", mock.Anything, bson.M{"_id": bson.M{"$in": bson.A{"1", "2"}}}).Return(mrh, nil)
+			},
+		},
+	}
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			drh := new(mocks.MultiResultHelper)
+			mrh := new(mocks.MultiResultHelper)
+
+			testCond.prepareMocks(ch, dh, drh, mrh)
+			repo := NewMessagesRepository(ch, dh)
+
+			gotRes, gotErr := repo.FindDirectMessages(ctx, userId, peerId)
+
+			assert.Equal(t, testCond.expectedErr, gotErr, "FindDirectMessages returned unexpected error: got error %v want %v", gotErr, testCond.expectedErr)
+			assert.Equal(t, testCond.expectedRes, gotRes, "FindDirectMessages returned unexpected result: got %v want %v", gotRes, testCond.expectedRes)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+			drh.AssertExpectations(t)
+			mrh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFindMessages(t *testing.T) {
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	senderId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	opts := options.Find().SetSort(messagesDescOrder).SetLimit(10)
+	testConditions := []struct {
+		tName      string
+		query      *models.MessagesQuery
+		wantFilter bson.M
+	}{
+		{
+			tName:      "should find recipient messages",
+			query:      &models.MessagesQuery{RecipientId: recipientId, Limit: 10},
+			wantFilter: bson.M{"recipientId": recipientId},
+		},
+		{
+			tName:      "should filter messages by sender and time range",
+			query:      &models.MessagesQuery{RecipientId: recipientId, SenderId: senderId, From: 100, To: 200, Limit: 10},
+			wantFilter: bson.M{"recipientId": recipientId, "senderId": senderId, "time": bson.M{"$gte": int64(100), "$lte": int64(200)}},
+		},
+		{
+			tName: "should find messages older than cursor",
+			query: &models.MessagesQuery{RecipientId: recipientId, From: 100, Limit: 10, After: &models.MessageCursor{Time: 150, Id: "5"}},
+			wantFilter: bson.M{
+				"recipientId": recipientId,
+				"time":        bson.M{"$gte": int64(100)},
+				"$or": bson.A{
+					bson.M{"time": bson.M{"$lt": int64(150)}},
+					bson.M{"time": int64(150), "_id": bson.M{"$lt": "5"}},
+				},
+			},
+		},
+	}
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			mrh := new(mocks.MultiResultHelper)
+			mrh.On("All", mock.Anything, mock.Anything).Return(nil)
+			dh.On("Find", mock.Anything, testCond.wantFilter, opts).Return(mrh, nil)
+			repo := NewMessagesRepository(ch, dh)
+
+			_, gotErr := repo.FindMessages(ctx, testCond.query)
+
+			assert.Nil(t, gotErr, "FindMessages returned unexpected error: got error %v want %v", gotErr, nil)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+			mrh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMarkMessageDelivered(t *testing.T) {
+	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	unknownErr := errors.New("Unable to update")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should mark message as delivered",
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+				dh.On("UpdateOne", mock.Anything, bson.M{"_id": id}, bson.M{"$set": bson.M{"deliveredAt": int64(10)}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:   "should fail with message not found error",
+			wantErr: ErrMessageNotFound,
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+				dh.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: unknownErr,
+			prepareMocks: func(dh *mocks.CollectionHelper) {
+				dh.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			testCond.prepareMocks(dh)
+			repo := NewMessagesRepository(ch, dh)
+
+			gotErr := repo.MarkMessageDelivered(context.Background(), id, 10)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "MarkMessageDelivered returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMarkMessageRead(t *testing.T) {
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	messageId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	filter := bson.M{"recipientId": recipientId, "messageId": messageId}
+	decodeStored := func(srh *mocks.SingleResultHelper, stored deliveryRecord) {
+		srh.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(0).(*deliveryRecord) = stored
+		}).Return(nil)
+	}
+	testConditions := []struct {
+		tName        string
+		wantMsg      *models.Message
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper, *mocks.SingleResultHelper)
+	}{
+		{
+			tName:   "should mark undelivered message as delivered and read",
+			wantMsg: &models.Message{Id: "copy", OriginId: messageId, RecipientId: recipientId, DeliveredAt: 10, ReadAt: 10},
+			prepareMocks: func(dh *mocks.CollectionHelper, srh *mocks.SingleResultHelper) {
+				decodeStored(srh, deliveryRecord{Id: "copy", MessageId: messageId, RecipientId: recipientId})
+				dh.On("UpdateOne", mock.Anything, bson.M{"_id": "copy"}, bson.M{"$set": bson.M{"readAt": int64(10), "deliveredAt": int64(10)}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:   "should keep time of the first read",
+			wantMsg: &models.Message{Id: "copy", OriginId: messageId, RecipientId: recipientId, DeliveredAt: 5, ReadAt: 7},
+			prepareMocks: func(dh *mocks.CollectionHelper, srh *mocks.SingleResultHelper) {
+				decodeStored(srh, deliveryRecord{Id: "copy", MessageId: messageId, RecipientId: recipientId, DeliveredAt: 5, ReadAt: 7})
+			},
+		},
+		{
+			tName:   "should fail with message not found error",
+			wantErr: ErrMessageNotFound,
+			prepareMocks: func(dh *mocks.CollectionHelper, srh *mocks.SingleResultHelper) {
+				srh.On("Decode", mock.Anything).Return(mongo.ErrNoDocuments)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			srh := new(mocks.SingleResultHelper)
+			dh.On("FindOne", mock.Anything, filter).Return(srh)
+			testCond.prepareMocks(dh, srh)
+			repo := NewMessagesRepository(ch, dh)
+
+			gotMsg, gotErr := repo.MarkMessageRead(context.Background(), recipientId, messageId, 10)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "MarkMessageRead returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantMsg, gotMsg, "MarkMessageRead returned unexpected result: got message %v want %v", gotMsg, testCond.wantMsg)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+			srh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateMessageContent(t *testing.T) {
+	senderId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	messageId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	filter := func(history bson.M) bson.M {
+		return bson.M{"_id": messageId, "senderId": senderId, "deletedAt": bson.M{"$exists": false}, "history": history}
+	}
+	delivered := bson.M{"messageId": messageId, "deliveredAt": bson.M{"$exists": true}}
+	pending := bson.M{"$set": bson.M{"updatePending": true}}
+	history := []*models.MessageRevision{{Payload: "helo", Time: 5}}
+	testConditions := []struct {
+		tName        string
+		msg          *models.Message
+		revision     int
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper, *mocks.CollectionHelper)
+	}{
+		{
+			tName: "should store edited payload and history",
+			msg:   &models.Message{Id: messageId, SenderId: senderId, Payload: "hello", EditedAt: 10, History: history},
+			prepareMocks: func(ch, dh *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, filter(bson.M{"$exists": false}), bson.M{"$set": bson.M{"payload": "hello", "editedAt": int64(10), "history": history}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+				dh.On("UpdateMany", mock.Anything, delivered, pending).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:    "should store tombstone of recipient's copy",
+			msg:      &models.Message{Id: "copy", OriginId: messageId, SenderId: senderId, DeletedAt: 10},
+			revision: 2,
+			prepareMocks: func(ch, dh *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, filter(bson.M{"$size": 2}), bson.M{"$set": bson.M{"payload": "", "deletedAt": int64(10)}, "$unset": bson.M{"history": ""}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+				dh.On("UpdateMany", mock.Anything, delivered, pending).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+		{
+			tName:    "should fail with message changed error when message was changed since it was loaded",
+			msg:      &models.Message{Id: messageId, SenderId: senderId, DeletedAt: 10},
+			revision: 1,
+			wantErr:  ErrMessageChanged,
+			prepareMocks: func(ch, dh *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, filter(bson.M{"$size": 1}), mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			dh := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch, dh)
+			repo := NewMessagesRepository(ch, dh)
+
+			gotErr := repo.UpdateMessageContent(context.Background(), testCond.msg, testCond.revision)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "UpdateMessageContent returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+			dh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMarkUpdateDelivered(t *testing.T) {
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	messageId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	ch := new(mocks.CollectionHelper)
+	dh := new(mocks.CollectionHelper)
+	filter := bson.M{"recipientId": recipientId, "messageId": messageId}
+	dh.On("UpdateOne", mock.Anything, filter, bson.M{"$unset": bson.M{"updatePending": ""}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+	repo := NewMessagesRepository(ch, dh)
+
+	gotErr := repo.MarkUpdateDelivered(context.Background(), recipientId, messageId)
+
+	assert.Nil(t, gotErr, "MarkUpdateDelivered returned unexpected result: got error %v want %v", gotErr, nil)
+	ch.AssertExpectations(t)
+	dh.AssertExpectations(t)
+}
+
+func TestDeleteRecipientDeliveries(t *testing.T) {
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	ch := new(mocks.CollectionHelper)
+	dh := new(mocks.CollectionHelper)
+	dh.On("DeleteMany", mock.Anything, bson.M{"recipientId": recipientId}).Return(int64(3), nil)
+	repo := NewMessagesRepository(ch, dh)
+
+	gotErr := repo.DeleteRecipientDeliveries(context.Background(), recipientId)
+
+	assert.Nil(t, gotErr, "DeleteRecipientDeliveries returned unexpected result: got error %v want %v", gotErr, nil)
+	ch.AssertExpectations(t)
+	dh.AssertExpectations(t)
+}
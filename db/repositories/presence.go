@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+)
+
+// PresenceRepository tracks sessions of users connected to any instance of the server.
+type PresenceRepository interface {
+	AddConnection(context.Context, *models.Session) error
+	DeleteConnection(context.Context, string) error
+	DeleteUserConnections(context.Context, string) error
+	Refresh(context.Context) error
+	OnlineUsers(context.Context) ([]string, error)
+	GetUserSessions(context.Context, string) ([]*models.Session, error)
+}
+
+type presenceStorage struct {
+	db map[string]*models.Session
+	mu *sync.Mutex
+}
+
+// NewPresenceRepository returns presence registry backend matching the broker selected in the server config.
+func NewPresenceRepository(cnf *config.ServerConfig, db mongo.PresenceCollection) PresenceRepository {
+	if cnf.BrokerBackend == config.BrokerMongo {
+		return NewMongoPresenceRepository(db, cnf)
+	}
+	return NewInMemoryPresenceRepository()
+}
+
+// NewInMemoryPresenceRepository knows only about connections of the current instance.
+func NewInMemoryPresenceRepository() PresenceRepository {
+	return &presenceStorage{
+		db: map[string]*models.Session{},
+		mu: &sync.Mutex{},
+	}
+}
+
+func (r *presenceStorage) AddConnection(ctx context.Context, session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.db[session.Id]; ok {
+		return ErrConnIdConflict
+	}
+	r.db[session.Id] = session
+	return nil
+}
+
+func (r *presenceStorage) DeleteConnection(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.db[id]; !ok {
+		return ErrConnNotFound
+	}
+	delete(r.db, id)
+	return nil
+}
+
+func (r *presenceStorage) DeleteUserConnections(ctx context.Context, userId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, session := range r.db {
+		if session.UserId == userId {
+			delete(r.db, id)
+		}
+	}
+	return nil
+}
+
+// Refresh does nothing, in memory connections never expire.
+func (r *presenceStorage) Refresh(ctx context.Context) error {
+	return nil
+}
+
+func (r *presenceStorage) OnlineUsers(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ids := make([]string, 0, len(r.db))
+	for _, session := range r.db {
+		ids = append(ids, session.UserId)
+	}
+	return uniqueSorted(ids), nil
+}
+
+func (r *presenceStorage) GetUserSessions(ctx context.Context, userId string) ([]*models.Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sessions := []*models.Session{}
+	for _, session := range r.db {
+		if session.UserId == userId {
+			sessions = append(sessions, session)
+		}
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
+
+// sortSessions orders sessions from the oldest to the newest one.
+func sortSessions(sessions []*models.Session) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if sessions[i].ConnectedAt != sessions[j].ConnectedAt {
+			return sessions[i].ConnectedAt < sessions[j].ConnectedAt
+		}
+		return sessions[i].Id < sessions[j].Id
+	})
+}
+
+func uniqueSorted(ids []string) []string {
+	sort.Strings(ids)
+	res := []string{}
+	for i, id := range ids {
+		if i == 0 || ids[i-1] != id {
+			res = append(res, id)
+		}
+	}
+	return res
+}
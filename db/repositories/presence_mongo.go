@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const presenceIndexTimeout = 10 * time.Second
+
+// presenceRecord is a presence collection document describing a single session.
+type presenceRecord struct {
+	ConnId      string    `bson:"_id"`
+	UserId      string    `bson:"userId"`
+	UserName    string    `bson:"userName"`
+	UserAgent   string    `bson:"userAgent,omitempty"`
+	RemoteAddr  string    `bson:"remoteAddr,omitempty"`
+	ConnectedAt int64     `bson:"connectedAt"`
+	Node        string    `bson:"node"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
+
+func (pr *presenceRecord) session() *models.Session {
+	return &models.Session{
+		Id:          pr.ConnId,
+		UserId:      pr.UserId,
+		UserName:    pr.UserName,
+		UserAgent:   pr.UserAgent,
+		RemoteAddr:  pr.RemoteAddr,
+		ConnectedAt: pr.ConnectedAt,
+	}
+}
+
+type mongoPresenceStorage struct {
+	db   mongo.PresenceCollection
+	node string
+	ttl  time.Duration
+}
+
+// NewMongoPresenceRepository shares connections of all instances through the presence collection.
+// Every instance refreshes its connections periodically, so connections of a crashed
+// instance are removed by the TTL index on expiresAt.
+func NewMongoPresenceRepository(db mongo.PresenceCollection, cnf *config.ServerConfig) PresenceRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), presenceIndexTimeout)
+	defer cancel()
+	_, err := db.CreateIndex(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		logger.Default().Error("Unable to create TTL index for presence", "err", err)
+	}
+
+	return &mongoPresenceStorage{
+		db:   db,
+		node: cnf.NodeId,
+		ttl:  cnf.PresenceTTL,
+	}
+}
+
+func (r *mongoPresenceStorage) AddConnection(ctx context.Context, session *models.Session) error {
+	_, err := r.db.InsertOne(ctx, &presenceRecord{
+		ConnId:      session.Id,
+		UserId:      session.UserId,
+		UserName:    session.UserName,
+		UserAgent:   session.UserAgent,
+		RemoteAddr:  session.RemoteAddr,
+		ConnectedAt: session.ConnectedAt,
+		Node:        r.node,
+		ExpiresAt:   time.Now().Add(r.ttl),
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrConnIdConflict
+		}
+		logger.FromContext(ctx).Error("Unable to save connection presence", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (r *mongoPresenceStorage) DeleteConnection(ctx context.Context, id string) error {
+	deleted, err := r.db.DeleteMany(ctx, bson.M{"_id": id})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to delete connection presence", "err", err)
+		return err
+	}
+	if deleted == 0 {
+		return ErrConnNotFound
+	}
+	return nil
+}
+
+// DeleteUserConnections removes sessions of the user held by any instance.
+func (r *mongoPresenceStorage) DeleteUserConnections(ctx context.Context, userId string) error {
+	if _, err := r.db.DeleteMany(ctx, bson.M{"userId": userId}); err != nil {
+		logger.FromContext(ctx).Error("Unable to delete user connections presence", "err", err)
+		return err
+	}
+	return nil
+}
+
+// Refresh prolongs presence of all connections held by the current instance.
+func (r *mongoPresenceStorage) Refresh(ctx context.Context) error {
+	_, err := r.db.UpdateMany(ctx, bson.M{"node": r.node}, bson.M{"$set": bson.M{"expiresAt": time.Now().Add(r.ttl)}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to refresh connections presence", "err", err)
+		return err
+	}
+	return nil
+}
+
+// OnlineUsers returns ids of users with at least one live connection on any instance.
+func (r *mongoPresenceStorage) OnlineUsers(ctx context.Context) ([]string, error) {
+	cursor, err := r.db.Find(ctx, bson.M{"expiresAt": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to find online users", "err", err)
+		return nil, err
+	}
+	var records []*presenceRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		logger.FromContext(ctx).Error("Unable to decode online users", "err", err)
+		return nil, err
+	}
+	ids := make([]string, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, record.UserId)
+	}
+	return uniqueSorted(ids), nil
+}
+
+func (r *mongoPresenceStorage) GetUserSessions(ctx context.Context, userId string) ([]*models.Session, error) {
+	cursor, err := r.db.Find(ctx, bson.M{"userId": userId, "expiresAt": bson.M{"$gt": time.Now()}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to find user sessions", "err", err)
+		return nil, err
+	}
+	var records []*presenceRecord
+	if err = cursor.All(ctx, &records); err != nil {
+		logger.FromContext(ctx).Error("Unable to decode user sessions", "err", err)
+		return nil, err
+	}
+	sessions := make([]*models.Session, 0, len(records))
+	for _, record := range records {
+		sessions = append(sessions, record.session())
+	}
+	sortSessions(sessions)
+	return sessions, nil
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var presenceTestConfig = &config.ServerConfig{NodeId: "node1", PresenceTTL: 30 * time.Second}
+
+func TestMongoPresenceAddConnection(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("InsertOne", ctx, mock.MatchedBy(func(record *presenceRecord) bool {
+		return record.ConnId == "c1" && record.UserId == "u1" && record.UserAgent == "curl" && record.Node == "node1" && record.ExpiresAt.After(time.Now())
+	})).Return("c1", nil)
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+
+	gotErr := repo.AddConnection(ctx, &models.Session{Id: "c1", UserId: "u1", UserAgent: "curl"})
+
+	assert.Nil(t, gotErr, "AddConnection returned unexpected result: got %v want %v", gotErr, nil)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoPresenceDeleteConnection(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("DeleteMany", ctx, bson.M{"_id": "c1"}).Return(int64(1), nil).Once()
+	ch.On("DeleteMany", ctx, bson.M{"_id": "c2"}).Return(int64(0), nil).Once()
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+
+	gotErr := repo.DeleteConnection(ctx, "c1")
+	assert.Nil(t, gotErr, "DeleteConnection returned unexpected result: got %v want %v", gotErr, nil)
+
+	gotErr = repo.DeleteConnection(ctx, "c2")
+	assert.Equal(t, ErrConnNotFound, gotErr, "DeleteConnection returned unexpected result: got %v want %v", gotErr, ErrConnNotFound)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoPresenceDeleteUserConnections(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("DeleteMany", ctx, bson.M{"userId": "u1"}).Return(int64(2), nil)
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+
+	gotErr := repo.DeleteUserConnections(ctx, "u1")
+
+	assert.Nil(t, gotErr, "DeleteUserConnections returned unexpected result: got %v want %v", gotErr, nil)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoPresenceRefresh(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("UpdateMany", ctx, bson.M{"node": "node1"}, mock.MatchedBy(func(update bson.M) bool {
+		expiresAt, ok := update["$set"].(bson.M)["expiresAt"].(time.Time)
+		return ok && expiresAt.After(time.Now())
+	})).Return(nil, nil)
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+
+	gotErr := repo.Refresh(ctx)
+
+	assert.Nil(t, gotErr, "Refresh returned unexpected result: got %v want %v", gotErr, nil)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoPresenceOnlineUsers(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	mrh := new(mocks.MultiResultHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("Find", ctx, mock.Anything).Return(mrh, nil)
+	mrh.On("All", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]*presenceRecord) = []*presenceRecord{
+			{ConnId: "c1", UserId: "u2", Node: "node1"},
+			{ConnId: "c2", UserId: "u1", Node: "node2"},
+			{ConnId: "c3", UserId: "u2", Node: "node2"},
+		}
+	}).Return(nil)
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+
+	gotIds, gotErr := repo.OnlineUsers(ctx)
+
+	assert.Nil(t, gotErr, "OnlineUsers returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, []string{"u1", "u2"}, gotIds, "OnlineUsers returned unexpected result: got %v", gotIds)
+
+	ch.AssertExpectations(t)
+	mrh.AssertExpectations(t)
+}
+
+func TestMongoPresenceGetUserSessions(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	mrh := new(mocks.MultiResultHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		return filter["userId"] == "u1"
+	})).Return(mrh, nil)
+	mrh.On("All", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		*args.Get(1).(*[]*presenceRecord) = []*presenceRecord{
+			{ConnId: "c1", UserId: "u1", UserAgent: "chrome", ConnectedAt: 20, Node: "node1"},
+			{ConnId: "c2", UserId: "u1", UserAgent: "firefox", ConnectedAt: 10, Node: "node2"},
+		}
+	}).Return(nil)
+	repo := NewMongoPresenceRepository(ch, presenceTestConfig)
+	want := []*models.Session{
+		{Id: "c2", UserId: "u1", UserAgent: "firefox", ConnectedAt: 10},
+		{Id: "c1", UserId: "u1", UserAgent: "chrome", ConnectedAt: 20},
+	}
+
+	gotSessions, gotErr := repo.GetUserSessions(ctx, "u1")
+
+	assert.Nil(t, gotErr, "GetUserSessions returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, want, gotSessions, "GetUserSessions returned unexpected result: got %v want %v", gotSessions, want)
+
+	ch.AssertExpectations(t)
+	mrh.AssertExpectations(t)
+}
@@ -0,0 +1,88 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewPresenceRepository(t *testing.T) {
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+
+	memoryRepo := NewPresenceRepository(&config.ServerConfig{BrokerBackend: config.BrokerMemory}, ch)
+	mongoRepo := NewPresenceRepository(&config.ServerConfig{BrokerBackend: config.BrokerMongo}, ch)
+
+	assert.IsType(t, &presenceStorage{}, memoryRepo, "NewPresenceRepository returned unexpected backend: got %T", memoryRepo)
+	assert.IsType(t, &mongoPresenceStorage{}, mongoRepo, "NewPresenceRepository returned unexpected backend: got %T", mongoRepo)
+
+	ch.AssertExpectations(t)
+}
+
+func TestPresenceOnlineUsers(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryPresenceRepository()
+	repo.AddConnection(ctx, &models.Session{Id: "c1", UserId: "u2"})
+	repo.AddConnection(ctx, &models.Session{Id: "c2", UserId: "u1"})
+	repo.AddConnection(ctx, &models.Session{Id: "c3", UserId: "u2"})
+
+	gotIds, gotErr := repo.OnlineUsers(ctx)
+
+	assert.Nil(t, gotErr, "OnlineUsers returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, []string{"u1", "u2"}, gotIds, "OnlineUsers returned unexpected result: got %v", gotIds)
+
+	repo.DeleteConnection(ctx, "c1")
+	repo.DeleteConnection(ctx, "c2")
+	gotIds, _ = repo.OnlineUsers(ctx)
+
+	assert.Equal(t, []string{"u2"}, gotIds, "OnlineUsers returned unexpected result after disconnect: got %v", gotIds)
+}
+
+func TestPresenceAddConnection(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryPresenceRepository()
+
+	gotErr := repo.AddConnection(ctx, &models.Session{Id: "c1", UserId: "u1"})
+	assert.Nil(t, gotErr, "AddConnection returned unexpected result: got %v want %v", gotErr, nil)
+
+	gotErr = repo.AddConnection(ctx, &models.Session{Id: "c1", UserId: "u1"})
+	assert.Equal(t, ErrConnIdConflict, gotErr, "AddConnection returned unexpected result: got %v want %v", gotErr, ErrConnIdConflict)
+
+	gotErr = repo.DeleteConnection(ctx, "c2")
+	assert.Equal(t, ErrConnNotFound, gotErr, "DeleteConnection returned unexpected result: got %v want %v", gotErr, ErrConnNotFound)
+}
+
+func TestPresenceGetUserSessions(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryPresenceRepository()
+	first := &models.Session{Id: "c2", UserId: "u1", UserAgent: "firefox", ConnectedAt: 10}
+	second := &models.Session{Id: "c1", UserId: "u1", UserAgent: "chrome", ConnectedAt: 20}
+	repo.AddConnection(ctx, second)
+	repo.AddConnection(ctx, first)
+	repo.AddConnection(ctx, &models.Session{Id: "c3", UserId: "u2"})
+
+	gotSessions, gotErr := repo.GetUserSessions(ctx, "u1")
+
+	assert.Nil(t, gotErr, "GetUserSessions returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, []*models.Session{first, second}, gotSessions, "GetUserSessions returned unexpected result: got %v", gotSessions)
+}
+
+func TestPresenceDeleteUserConnections(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryPresenceRepository()
+	other := &models.Session{Id: "c3", UserId: "u2"}
+	repo.AddConnection(ctx, &models.Session{Id: "c1", UserId: "u1"})
+	repo.AddConnection(ctx, &models.Session{Id: "c2", UserId: "u1"})
+	repo.AddConnection(ctx, other)
+
+	gotErr := repo.DeleteUserConnections(ctx, "u1")
+	assert.Nil(t, gotErr, "DeleteUserConnections returned unexpected result: got %v want %v", gotErr, nil)
+
+	gotUsers, _ := repo.OnlineUsers(ctx)
+	assert.Equal(t, []string{"u2"}, gotUsers, "DeleteUserConnections left unexpected users: got %v want %v", gotUsers, []string{"u2"})
+}
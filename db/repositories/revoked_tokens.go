@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+)
+
+var ErrTokenAlreadyRevoked = errors.New("token has been already revoked")
+
+// RevokedTokensRepository keeps ids of revoked long-lived tokens until they expire on their own.
+// RevokeToken succeeds only for the first revocation of the token, the next ones fail with
+// ErrTokenAlreadyRevoked, so it is the atomic gate of the token rotation.
+type RevokedTokensRepository interface {
+	RevokeToken(context.Context, string, time.Time) error
+	IsTokenRevoked(context.Context, string) (bool, error)
+	DeleteExpiredTokens(context.Context) (int, error)
+}
+
+type revokedTokensStorage struct {
+	db map[string]time.Time
+	mu *sync.Mutex
+}
+
+// NewRevokedTokensRepository returns revoked tokens storage backend selected in the server config.
+func NewRevokedTokensRepository(cnf *config.ServerConfig, db mongo.RevokedTokensCollection) RevokedTokensRepository {
+	if cnf.TokensStorage == config.TokensStorageMongo {
+		return NewMongoRevokedTokensRepository(db)
+	}
+	return NewInMemoryRevokedTokensRepository()
+}
+
+// NewInMemoryRevokedTokensRepository keeps revoked tokens in process memory, so revocation
+// is visible only to the instance which handled it and is lost on restart.
+func NewInMemoryRevokedTokensRepository() RevokedTokensRepository {
+	return &revokedTokensStorage{
+		db: map[string]time.Time{},
+		mu: &sync.Mutex{},
+	}
+}
+
+func (r *revokedTokensStorage) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.db[id]; ok {
+		return ErrTokenAlreadyRevoked
+	}
+	r.db[id] = expiresAt
+	return nil
+}
+
+func (r *revokedTokensStorage) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.db[id]
+	return ok, nil
+}
+
+func (r *revokedTokensStorage) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	deleted := 0
+	for id, expiresAt := range r.db {
+		if !expiresAt.After(now) {
+			delete(r.db, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,75 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// revokedTokenRecord is a revoked_tokens collection document.
+type revokedTokenRecord struct {
+	Id        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+type mongoRevokedTokensStorage struct {
+	db mongo.RevokedTokensCollection
+}
+
+// NewMongoRevokedTokensRepository stores revoked tokens in the revoked_tokens collection which
+// is shared between instances. Records are removed by the TTL index once tokens expire.
+func NewMongoRevokedTokensRepository(db mongo.RevokedTokensCollection) RevokedTokensRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), tokensIndexTimeout)
+	defer cancel()
+	_, err := db.CreateIndex(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		logger.Default().Error("Unable to create TTL index for revoked tokens", "err", err)
+	}
+
+	return &mongoRevokedTokensStorage{
+		db: db,
+	}
+}
+
+// RevokeToken inserts the token id as _id of the record, so only one of concurrent revocations succeeds.
+func (r *mongoRevokedTokensStorage) RevokeToken(ctx context.Context, id string, expiresAt time.Time) error {
+	_, err := r.db.InsertOne(ctx, &revokedTokenRecord{Id: id, ExpiresAt: expiresAt})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrTokenAlreadyRevoked
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to save revoked token into database", "err", err)
+		return err
+	}
+	return nil
+}
+
+func (r *mongoRevokedTokensStorage) IsTokenRevoked(ctx context.Context, id string) (bool, error) {
+	record := &revokedTokenRecord{}
+	err := r.db.FindOne(ctx, bson.M{"_id": id}).Decode(record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		logger.FromContext(ctx).Error("Unable to find revoked token", "err", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// DeleteExpiredTokens removes records which were not yet collected by the TTL monitor.
+func (r *mongoRevokedTokensStorage) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	deleted, err := r.db.DeleteMany(ctx, bson.M{"expiresAt": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to delete expired revoked tokens", "err", err)
+		return 0, err
+	}
+	return int(deleted), nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestNewRevokedTokensRepository(t *testing.T) {
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.MatchedBy(func(model mongo.IndexModel) bool {
+		return *model.Options.ExpireAfterSeconds == 0
+	})).Return("expiresAt_1", nil)
+
+	memoryRepo := NewRevokedTokensRepository(&config.ServerConfig{TokensStorage: config.TokensStorageMemory}, ch)
+	mongoRepo := NewRevokedTokensRepository(&config.ServerConfig{TokensStorage: config.TokensStorageMongo}, ch)
+
+	assert.IsType(t, &revokedTokensStorage{}, memoryRepo, "NewRevokedTokensRepository returned unexpected backend: got %T", memoryRepo)
+	assert.IsType(t, &mongoRevokedTokensStorage{}, mongoRepo, "NewRevokedTokensRepository returned unexpected backend: got %T", mongoRepo)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Minute)
+	unknownErr := errors.New("Unable to save")
+	testConditions := []struct {
+		tName     string
+		insertErr error
+		wantErr   error
+	}{
+		{
+			tName: "should save revoked token",
+		},
+		{
+			tName:     "should fail with already revoked error",
+			insertErr: driver.WriteException{WriteErrors: driver.WriteErrors{{Code: 11000}}},
+			wantErr:   ErrTokenAlreadyRevoked,
+		},
+		{
+			tName:     "should fail with some error",
+			insertErr: unknownErr,
+			wantErr:   unknownErr,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+			ch.On("InsertOne", ctx, &revokedTokenRecord{Id: "jti", ExpiresAt: expiresAt}).Return("jti", testCond.insertErr)
+			repo := NewMongoRevokedTokensRepository(ch)
+
+			gotErr := repo.RevokeToken(ctx, "jti", expiresAt)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "RevokeToken returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMongoIsTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+	unknownErr := errors.New("Unable to find")
+	testConditions := []struct {
+		tName       string
+		decodeErr   error
+		wantRevoked bool
+		wantErr     error
+	}{
+		{
+			tName:       "should report revoked token",
+			wantRevoked: true,
+		},
+		{
+			tName:     "should report active token",
+			decodeErr: mongo.ErrNoDocuments,
+		},
+		{
+			tName:     "should fail with some error",
+			decodeErr: unknownErr,
+			wantErr:   unknownErr,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			srh := new(mocks.SingleResultHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+			ch.On("FindOne", ctx, bson.M{"_id": "jti"}).Return(srh)
+			srh.On("Decode", &revokedTokenRecord{}).Return(testCond.decodeErr)
+			repo := NewMongoRevokedTokensRepository(ch)
+
+			gotRevoked, gotErr := repo.IsTokenRevoked(ctx, "jti")
+
+			assert.Equal(t, testCond.wantErr, gotErr, "IsTokenRevoked returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantRevoked, gotRevoked, "IsTokenRevoked returned unexpected result: got %v want %v", gotRevoked, testCond.wantRevoked)
+
+			ch.AssertExpectations(t)
+			srh.AssertExpectations(t)
+		})
+	}
+}
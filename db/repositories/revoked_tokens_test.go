@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRevokedTokensRepository()
+
+	gotErr := repo.RevokeToken(ctx, "revoked", time.Now().Add(time.Minute))
+	assert.Nil(t, gotErr, "RevokeToken returned unexpected result: got error %v want %v", gotErr, nil)
+	gotErr = repo.RevokeToken(ctx, "revoked", time.Now().Add(time.Minute))
+	assert.Equal(t, ErrTokenAlreadyRevoked, gotErr, "RevokeToken returned unexpected result: got error %v want %v", gotErr, ErrTokenAlreadyRevoked)
+
+	gotRevoked, _ := repo.IsTokenRevoked(ctx, "revoked")
+	assert.True(t, gotRevoked, "IsTokenRevoked returned unexpected result: got %v want %v", gotRevoked, true)
+	gotRevoked, _ = repo.IsTokenRevoked(ctx, "active")
+	assert.False(t, gotRevoked, "IsTokenRevoked returned unexpected result: got %v want %v", gotRevoked, false)
+}
+
+func TestDeleteExpiredRevokedTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := NewInMemoryRevokedTokensRepository()
+	repo.RevokeToken(ctx, "expired", time.Now().Add(-time.Minute))
+	repo.RevokeToken(ctx, "valid", time.Now().Add(time.Minute))
+
+	gotDeleted, gotErr := repo.DeleteExpiredTokens(ctx)
+
+	assert.Nil(t, gotErr, "DeleteExpiredTokens returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, 1, gotDeleted, "DeleteExpiredTokens returned unexpected result: got %v want %v", gotDeleted, 1)
+	gotRevoked, _ := repo.IsTokenRevoked(ctx, "valid")
+	assert.True(t, gotRevoked, "IsTokenRevoked returned unexpected result: got %v want %v", gotRevoked, true)
+}
@@ -0,0 +1,154 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const roomsIndexTimeout = 10 * time.Second
+
+var ErrRoomNotFound = errors.New("room not found")
+var ErrRoomWithNameAlreadyExists = errors.New("room with provided name already exists")
+
+type RoomsRepository interface {
+	SaveRoom(context.Context, *models.Room) (string, error)
+	FindRoomById(context.Context, string) (*models.Room, error)
+	FindRoomByName(context.Context, string) (*models.Room, error)
+	FindRooms(context.Context) ([]*models.Room, error)
+	AddMember(context.Context, string, string) error
+	RemoveMember(context.Context, string, string) error
+	RemoveUser(context.Context, string) error
+}
+
+type roomsRepository struct {
+	db mongo.RoomsCollection
+}
+
+// NewRoomsRepository ensures the unique index on room name, so rooms created concurrently
+// by different instances can't share the name.
+func NewRoomsRepository(db mongo.RoomsCollection) RoomsRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), roomsIndexTimeout)
+	defer cancel()
+	_, err := db.CreateIndex(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		logger.Default().Error("Unable to create unique index for room names", "err", err)
+	}
+
+	return &roomsRepository{
+		db: db,
+	}
+}
+
+func (r *roomsRepository) SaveRoom(ctx context.Context, room *models.Room) (string, error) {
+	res, err := r.db.InsertOne(ctx, room)
+	if mongo.IsDuplicateKeyError(err) {
+		return "", ErrRoomWithNameAlreadyExists
+	}
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to save room data into database", "err", err)
+		return "", err
+	}
+	return fmt.Sprintf("%v", res), nil
+}
+
+func (r *roomsRepository) FindRoomById(ctx context.Context, id string) (*models.Room, error) {
+	return r.findRoom(ctx, bson.M{"_id": id})
+}
+
+func (r *roomsRepository) FindRoomByName(ctx context.Context, name string) (*models.Room, error) {
+	return r.findRoom(ctx, bson.M{"name": name})
+}
+
+func (r *roomsRepository) FindRooms(ctx context.Context) ([]*models.Room, error) {
+	res, err := r.db.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	var rooms []*models.Room
+	if err = res.All(ctx, &rooms); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return rooms, nil
+		}
+		return nil, err
+	}
+
+	return rooms, nil
+}
+
+func (r *roomsRepository) AddMember(ctx context.Context, roomId string, userId string) error {
+	return r.updateMembers(ctx, roomId, bson.M{"$addToSet": bson.M{"members": userId}})
+}
+
+func (r *roomsRepository) RemoveMember(ctx context.Context, roomId string, userId string) error {
+	return r.updateMembers(ctx, roomId, bson.M{"$pull": bson.M{"members": userId}})
+}
+
+// RemoveUser takes the user out of every room. Rooms owned by the user pass to the earliest
+// remaining member, rooms left without members are deleted.
+func (r *roomsRepository) RemoveUser(ctx context.Context, userId string) error {
+	lg := logger.FromContext(ctx)
+	if _, err := r.db.UpdateMany(ctx, bson.M{"members": userId}, bson.M{"$pull": bson.M{"members": userId}}); err != nil {
+		lg.Error("Unable to remove user from rooms", "err", err)
+		return err
+	}
+	res, err := r.db.Find(ctx, bson.M{"ownerId": userId})
+	if err != nil {
+		lg.Error("Unable to find rooms of user", "err", err)
+		return err
+	}
+	var rooms []*models.Room
+	if err = res.All(ctx, &rooms); err != nil && err != mongo.ErrNoDocuments {
+		lg.Error("Unable to decode rooms of user", "err", err)
+		return err
+	}
+	for _, room := range rooms {
+		if len(room.Members) == 0 {
+			// somebody may join the room meanwhile, such room is passed on the next removal of its owner
+			_, err = r.db.DeleteMany(ctx, bson.M{"_id": room.Id, "members": bson.M{"$size": 0}})
+		} else {
+			_, err = r.db.UpdateOne(ctx, bson.M{"_id": room.Id, "ownerId": userId}, bson.M{"$set": bson.M{"ownerId": room.Members[0]}})
+		}
+		if err != nil {
+			lg.Error("Unable to pass room of user", "roomId", room.Id, "err", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *roomsRepository) findRoom(ctx context.Context, filter bson.M) (*models.Room, error) {
+	var room models.Room
+	err := r.db.FindOne(ctx, filter).Decode(&room)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrRoomNotFound
+		}
+		logger.FromContext(ctx).Error("Unable to find room", "err", err)
+		return nil, err
+	}
+	return &room, nil
+}
+
+func (r *roomsRepository) updateMembers(ctx context.Context, roomId string, update bson.M) error {
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": roomId}, update)
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to update room members", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrRoomNotFound
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	driver "go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSaveRoom(t *testing.T) {
+	fakeRoom := &models.Room{Id: "1", Name: "general"}
+	unknownErr := errors.New("Unable to save")
+	testConditions := []struct {
+		tName        string
+		room         *models.Room
+		wantId       string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName:  "should successfully save room",
+			room:   fakeRoom,
+			wantId: "1",
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("InsertOne", mock.Anything, fakeRoom).Return("1", nil)
+			},
+		},
+		{
+			tName:   "should fail when room with such name exists",
+			room:    fakeRoom,
+			wantErr: ErrRoomWithNameAlreadyExists,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("InsertOne", mock.Anything, fakeRoom).Return(nil, driver.WriteException{WriteErrors: driver.WriteErrors{{Code: 11000}}})
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			room:    fakeRoom,
+			wantErr: unknownErr,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("InsertOne", mock.Anything, fakeRoom).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("name_1", nil)
+			testCond.prepareMocks(ch)
+			repo := NewRoomsRepository(ch)
+
+			gotId, gotErr := repo.SaveRoom(ctx, testCond.room)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "SaveRoom returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantId, gotId, "SaveRoom returned unexpected result: got Id %v want %v", gotId, testCond.wantId)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestNewRoomsRepositoryCreatesUniqueNameIndex(t *testing.T) {
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.MatchedBy(func(model mongo.IndexModel) bool {
+		return *model.Options.Unique && model.Keys.(bson.D)[0].Key == "name"
+	})).Return("name_1", nil)
+
+	NewRoomsRepository(ch)
+
+	ch.AssertExpectations(t)
+}
+
+func TestFindRoomByIdNotFound(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("name_1", nil)
+	srh := new(mocks.SingleResultHelper)
+	ch.On("FindOne", ctx, bson.M{"_id": "1"}).Return(srh)
+	srh.On("Decode", &models.Room{}).Return(mongo.ErrNoDocuments)
+	repo := NewRoomsRepository(ch)
+
+	gotRoom, gotErr := repo.FindRoomById(ctx, "1")
+
+	assert.Nil(t, gotRoom, "FindRoomById returned unexpected result: got room %v want %v", gotRoom, nil)
+	assert.Equal(t, ErrRoomNotFound, gotErr, "FindRoomById returned unexpected result: got error %v want %v", gotErr, ErrRoomNotFound)
+
+	ch.AssertExpectations(t)
+	srh.AssertExpectations(t)
+}
+
+func TestFindRooms(t *testing.T) {
+	errUnableToFind := errors.New("Unable to run find query")
+	testConditions := []struct {
+		tName        string
+		expectedErr  error
+		expectedRes  []*models.Room
+		prepareMocks func(*mocks.CollectionHelper, *mocks.MultiResultHelper)
+	}{
+		{
+			tName:       "should fail with unable to find error",
+			expectedErr: errUnableToFind,
+			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				ch.On("Find", mock.Anything, bson.M{}).Return(nil, errUnableToFind)
+			},
+		},
+		{
+			tName:       "should return empty list when no documents found",
+			expectedRes: []*models.Room(nil),
+			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				mrh.On("All", mock.Anything, mock.Anything).Return(mongo.ErrNoDocuments)
+				ch.On("Find", mock.Anything, bson.M{}).Return(mrh, nil)
+			},
+		},
+	}
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("name_1", nil)
+			mrh := new(mocks.MultiResultHelper)
+
+			testCond.prepareMocks(ch, mrh)
+			repo := NewRoomsRepository(ch)
+
+			gotRes, gotErr := repo.FindRooms(ctx)
+
+			assert.Equal(t, testCond.expectedErr, gotErr, "FindRooms returned unexpected error: got error %v want %v", gotErr, testCond.expectedErr)
+			assert.Equal(t, testCond.expectedRes, gotRes, "FindRooms returned unexpected result: got %v want %v", gotRes, testCond.expectedRes)
+
+			ch.AssertExpectations(t)
+			mrh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRoomMembership(t *testing.T) {
+	roomId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	userId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	unknownErr := errors.New("Unable to update")
+	testConditions := []struct {
+		tName        string
+		call         func(RoomsRepository) error
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should add member to the room",
+			call: func(rr RoomsRepository) error {
+				return rr.AddMember(context.Background(), roomId, userId)
+			},
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": roomId}, bson.M{"$addToSet": bson.M{"members": userId}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName: "should remove member from the room",
+			call: func(rr RoomsRepository) error {
+				return rr.RemoveMember(context.Background(), roomId, userId)
+			},
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": roomId}, bson.M{"$pull": bson.M{"members": userId}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName: "should fail with room not found error",
+			call: func(rr RoomsRepository) error {
+				return rr.AddMember(context.Background(), roomId, userId)
+			},
+			wantErr: ErrRoomNotFound,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": roomId}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+		{
+			tName: "should fail with some error",
+			call: func(rr RoomsRepository) error {
+				return rr.RemoveMember(context.Background(), roomId, userId)
+			},
+			wantErr: unknownErr,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": roomId}, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("name_1", nil)
+			testCond.prepareMocks(ch)
+			repo := NewRoomsRepository(ch)
+
+			gotErr := testCond.call(repo)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "membership update returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRemoveUserFromRooms(t *testing.T) {
+	userId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	unknownErr := errors.New("Unable to update")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper, *mocks.MultiResultHelper)
+	}{
+		{
+			tName: "should leave rooms, pass owned room and delete empty room",
+			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				ch.On("UpdateMany", mock.Anything, bson.M{"members": userId}, bson.M{"$pull": bson.M{"members": userId}}).Return(&mongo.UpdateResult{MatchedCount: 2}, nil)
+				ch.On("Find", mock.Anything, bson.M{"ownerId": userId}).Return(mrh, nil)
+				mrh.On("All", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(1).(*[]*models.Room) = []*models.Room{
+						{Id: "r1", OwnerId: userId, Members: []string{"u2", "u3"}},
+						{Id: "r2", OwnerId: userId, Members: []string{}},
+					}
+				}).Return(nil)
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": "r1", "ownerId": userId}, bson.M{"$set": bson.M{"ownerId": "u2"}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+				ch.On("DeleteMany", mock.Anything, bson.M{"_id": "r2", "members": bson.M{"$size": 0}}).Return(int64(1), nil)
+			},
+		},
+		{
+			tName: "should leave rooms when user owns none",
+			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				ch.On("UpdateMany", mock.Anything, bson.M{"members": userId}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+				ch.On("Find", mock.Anything, bson.M{"ownerId": userId}).Return(mrh, nil)
+				mrh.On("All", mock.Anything, mock.Anything).Return(mongo.ErrNoDocuments)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: unknownErr,
+			prepareMocks: func(ch *mocks.CollectionHelper, mrh *mocks.MultiResultHelper) {
+				ch.On("UpdateMany", mock.Anything, bson.M{"members": userId}, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("name_1", nil)
+			mrh := new(mocks.MultiResultHelper)
+			testCond.prepareMocks(ch, mrh)
+			repo := NewRoomsRepository(ch)
+
+			gotErr := repo.RemoveUser(context.Background(), userId)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "RemoveUser returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+			mrh.AssertExpectations(t)
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/models"
 )
 
@@ -16,6 +17,7 @@ var ErrTokenExpired = errors.New("token has been expired")
 type TokensRepository interface {
 	SaveToken(context.Context, string, *models.User) error
 	GetUserByToken(context.Context, string) (*models.User, error)
+	DeleteExpiredTokens(context.Context) (int, error)
 }
 
 type inMemoryRecord struct {
@@ -25,26 +27,35 @@ type inMemoryRecord struct {
 
 type tokensStorage struct {
 	db  map[string]*inMemoryRecord
-	ttl int
+	ttl time.Duration
 	mu  *sync.Mutex
 }
 
-func NewTokensRepository(config *config.ServerConfig) TokensRepository {
+// NewTokensRepository returns tokens storage backend selected in the server config.
+func NewTokensRepository(cnf *config.ServerConfig, db mongo.TokensCollection) TokensRepository {
+	if cnf.TokensStorage == config.TokensStorageMongo {
+		return NewMongoTokensRepository(db, cnf)
+	}
+	return NewInMemoryTokensRepository(cnf)
+}
+
+// NewInMemoryTokensRepository keeps tokens in process memory, so they can be
+// used only by the instance which issued them and are lost on restart.
+func NewInMemoryTokensRepository(config *config.ServerConfig) TokensRepository {
 	return &tokensStorage{
 		db:  map[string]*inMemoryRecord{},
-		ttl: config.TokenTTLInSeconds,
+		ttl: config.TokenTTL,
 		mu:  &sync.Mutex{},
 	}
 }
 
 func (r *tokensStorage) SaveToken(ctx context.Context, token string, user *models.User) error {
-	// TODO: Use real storage for tokens
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	now := int(time.Now().Unix())
 	r.db[token] = &inMemoryRecord{
 		user:      user,
-		expiresAt: int(now + r.ttl),
+		expiresAt: now + int(r.ttl/time.Second),
 	}
 	return nil
 }
@@ -64,3 +75,17 @@ func (r *tokensStorage) GetUserByToken(ctx context.Context, token string) (*mode
 	delete(r.db, token)
 	return record.user, nil
 }
+
+func (r *tokensStorage) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := int(time.Now().Unix())
+	deleted := 0
+	for token, record := range r.db {
+		if record.expiresAt <= now {
+			delete(r.db, token)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,89 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const tokensIndexTimeout = 10 * time.Second
+
+// tokenRecord is a tokens collection document. Only public user data is stored with the token.
+type tokenRecord struct {
+	Token     string    `bson:"_id"`
+	UserId    string    `bson:"userId"`
+	UserName  string    `bson:"userName"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
+
+type mongoTokensStorage struct {
+	db  mongo.TokensCollection
+	ttl time.Duration
+}
+
+// NewMongoTokensRepository stores tokens in the tokens collection which is shared
+// between instances. Expired tokens are removed by the TTL index on expiresAt.
+func NewMongoTokensRepository(db mongo.TokensCollection, config *config.ServerConfig) TokensRepository {
+	ctx, cancel := context.WithTimeout(context.Background(), tokensIndexTimeout)
+	defer cancel()
+	_, err := db.CreateIndex(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		logger.Default().Error("Unable to create TTL index for tokens", "err", err)
+	}
+
+	return &mongoTokensStorage{
+		db:  db,
+		ttl: config.TokenTTL,
+	}
+}
+
+func (r *mongoTokensStorage) SaveToken(ctx context.Context, token string, user *models.User) error {
+	_, err := r.db.InsertOne(ctx, &tokenRecord{
+		Token:     token,
+		UserId:    user.Id,
+		UserName:  user.UserName,
+		ExpiresAt: time.Now().Add(r.ttl),
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to save token into database", "err", err)
+		return err
+	}
+	return nil
+}
+
+// GetUserByToken consumes the token, so it can be used only once across all instances.
+// TTL monitor removes documents periodically, that is why expiration is checked here as well.
+func (r *mongoTokensStorage) GetUserByToken(ctx context.Context, token string) (*models.User, error) {
+	record := &tokenRecord{}
+	err := r.db.FindOneAndDelete(ctx, bson.M{"_id": token}).Decode(record)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrTokenNotFound
+		}
+		logger.FromContext(ctx).Error("Unable to find token", "err", err)
+		return nil, err
+	}
+	if !record.ExpiresAt.After(time.Now()) {
+		return nil, ErrTokenExpired
+	}
+	return &models.User{Id: record.UserId, UserName: record.UserName}, nil
+}
+
+// DeleteExpiredTokens removes expired tokens which were not yet collected by the TTL monitor.
+func (r *mongoTokensStorage) DeleteExpiredTokens(ctx context.Context) (int, error) {
+	deleted, err := r.db.DeleteMany(ctx, bson.M{"expiresAt": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to delete expired tokens", "err", err)
+		return 0, err
+	}
+	return int(deleted), nil
+}
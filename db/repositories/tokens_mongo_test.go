@@ -0,0 +1,130 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNewTokensRepository(t *testing.T) {
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.MatchedBy(func(model mongo.IndexModel) bool {
+		return *model.Options.ExpireAfterSeconds == 0
+	})).Return("expiresAt_1", nil)
+
+	memoryRepo := NewTokensRepository(&config.ServerConfig{TokensStorage: config.TokensStorageMemory}, ch)
+	mongoRepo := NewTokensRepository(&config.ServerConfig{TokensStorage: config.TokensStorageMongo}, ch)
+
+	assert.IsType(t, &tokensStorage{}, memoryRepo, "NewTokensRepository returned unexpected backend: got %T", memoryRepo)
+	assert.IsType(t, &mongoTokensStorage{}, mongoRepo, "NewTokensRepository returned unexpected backend: got %T", mongoRepo)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoSaveToken(t *testing.T) {
+	ctx := context.Background()
+	usr := &models.User{Id: "someid", UserName: "foo", Password: "secret"}
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("InsertOne", ctx, mock.MatchedBy(func(record *tokenRecord) bool {
+		return record.Token == "token" && record.UserId == usr.Id && record.UserName == usr.UserName && record.ExpiresAt.After(time.Now())
+	})).Return("token", nil)
+	repo := NewMongoTokensRepository(ch, &config.ServerConfig{TokenTTL: 10 * time.Second})
+
+	gotErr := repo.SaveToken(ctx, "token", usr)
+
+	assert.Nil(t, gotErr, "SaveToken returned unexpected result: got %v want %v", gotErr, nil)
+
+	ch.AssertExpectations(t)
+}
+
+func TestMongoGetUserByToken(t *testing.T) {
+	fakeToken := "51cdef8e-283b-4333-ade9-74ab2c7ca8fa"
+	unknownErr := errors.New("Unable to find")
+	testConditions := []struct {
+		tName        string
+		wantUsr      *models.User
+		wantErr      error
+		prepareMocks func(*mocks.SingleResultHelper)
+	}{
+		{
+			tName:   "should return user of valid token",
+			wantUsr: &models.User{Id: "someid", UserName: "foo"},
+			prepareMocks: func(srh *mocks.SingleResultHelper) {
+				srh.On("Decode", &tokenRecord{}).Run(func(args mock.Arguments) {
+					*args.Get(0).(*tokenRecord) = tokenRecord{Token: fakeToken, UserId: "someid", UserName: "foo", ExpiresAt: time.Now().Add(time.Minute)}
+				}).Return(nil)
+			},
+		},
+		{
+			tName:   "should fail with token expired error",
+			wantErr: ErrTokenExpired,
+			prepareMocks: func(srh *mocks.SingleResultHelper) {
+				srh.On("Decode", &tokenRecord{}).Run(func(args mock.Arguments) {
+					*args.Get(0).(*tokenRecord) = tokenRecord{Token: fakeToken, UserId: "someid", ExpiresAt: time.Now().Add(-time.Minute)}
+				}).Return(nil)
+			},
+		},
+		{
+			tName:   "should fail with token not found error",
+			wantErr: ErrTokenNotFound,
+			prepareMocks: func(srh *mocks.SingleResultHelper) {
+				srh.On("Decode", &tokenRecord{}).Return(mongo.ErrNoDocuments)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: unknownErr,
+			prepareMocks: func(srh *mocks.SingleResultHelper) {
+				srh.On("Decode", &tokenRecord{}).Return(unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			ch := new(mocks.CollectionHelper)
+			srh := new(mocks.SingleResultHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+			ch.On("FindOneAndDelete", ctx, bson.M{"_id": fakeToken}).Return(srh)
+			testCond.prepareMocks(srh)
+			repo := NewMongoTokensRepository(ch, &config.ServerConfig{TokenTTL: 10 * time.Second})
+
+			gotUsr, gotErr := repo.GetUserByToken(ctx, fakeToken)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "GetUserByToken returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantUsr, gotUsr, "Invalid user returned: got %v want %v", gotUsr, testCond.wantUsr)
+
+			ch.AssertExpectations(t)
+			srh.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMongoDeleteExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("expiresAt_1", nil)
+	ch.On("DeleteMany", ctx, mock.MatchedBy(func(filter bson.M) bool {
+		_, ok := filter["expiresAt"].(bson.M)["$lte"].(time.Time)
+		return ok
+	})).Return(int64(2), nil)
+	repo := NewMongoTokensRepository(ch, &config.ServerConfig{TokenTTL: 10 * time.Second})
+
+	gotPurged, gotErr := repo.DeleteExpiredTokens(ctx)
+
+	assert.Nil(t, gotErr, "DeleteExpiredTokens returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, 2, gotPurged, "DeleteExpiredTokens returned unexpected result: got %v want %v", gotPurged, 2)
+
+	ch.AssertExpectations(t)
+}
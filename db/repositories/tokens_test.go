@@ -3,7 +3,9 @@ package repositories
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/models"
@@ -20,7 +22,7 @@ type testGetUserByTokenData struct {
 func TestSaveToken(t *testing.T) {
 
 	ctx := context.Background()
-	repo := NewTokensRepository(&config.ServerConfig{TokenTTLInSeconds: 10})
+	repo := NewInMemoryTokensRepository(&config.ServerConfig{TokenTTL: 10 * time.Second})
 
 	gotErr := repo.SaveToken(ctx, "token", &models.User{})
 
@@ -36,7 +38,7 @@ func TestGetUserByToken(t *testing.T) {
 			wantErr: nil,
 			wantUsr: fakeUsr,
 			composeRepo: func() TokensRepository {
-				tr := NewTokensRepository(&config.ServerConfig{TokenTTLInSeconds: 10})
+				tr := NewInMemoryTokensRepository(&config.ServerConfig{TokenTTL: 10 * time.Second})
 				tr.SaveToken(context.Background(), fakeToken, fakeUsr)
 				return tr
 			},
@@ -46,7 +48,7 @@ func TestGetUserByToken(t *testing.T) {
 			wantErr: ErrTokenNotFound,
 			wantUsr: nil,
 			composeRepo: func() TokensRepository {
-				return NewTokensRepository(&config.ServerConfig{TokenTTLInSeconds: 10})
+				return NewInMemoryTokensRepository(&config.ServerConfig{TokenTTL: 10 * time.Second})
 			},
 		},
 		{
@@ -54,7 +56,7 @@ func TestGetUserByToken(t *testing.T) {
 			wantErr: ErrTokenExpired,
 			wantUsr: nil,
 			composeRepo: func() TokensRepository {
-				tr := NewTokensRepository(&config.ServerConfig{TokenTTLInSeconds: -1})
+				tr := NewInMemoryTokensRepository(&config.ServerConfig{TokenTTL: -time.Second})
 				tr.SaveToken(context.Background(), fakeToken, fakeUsr)
 				return tr
 			},
@@ -73,3 +75,22 @@ func TestGetUserByToken(t *testing.T) {
 		})
 	}
 }
+
+func TestDeleteExpiredTokens(t *testing.T) {
+	ctx := context.Background()
+	now := int(time.Now().Unix())
+	repo := &tokensStorage{
+		db: map[string]*inMemoryRecord{
+			"expired1": {user: &models.User{}, expiresAt: now - 1},
+			"expired2": {user: &models.User{}, expiresAt: now},
+			"valid":    {user: &models.User{}, expiresAt: now + 10},
+		},
+		mu: &sync.Mutex{},
+	}
+
+	gotPurged, gotErr := repo.DeleteExpiredTokens(ctx)
+
+	assert.Nil(t, gotErr, "DeleteExpiredTokens returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, 2, gotPurged, "DeleteExpiredTokens returned unexpected result: got %v want %v", gotPurged, 2)
+	assert.Contains(t, repo.db, "valid", "DeleteExpiredTokens purged valid token")
+}
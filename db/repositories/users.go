@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 
 	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -16,8 +16,13 @@ var ErrUserWithNameAlreadyExists = errors.New("user with provided name already e
 
 type UsersRepository interface {
 	SaveUser(context.Context, *models.User) (string, error)
+	FindUserById(context.Context, string) (*models.User, error)
 	FindUserByName(context.Context, string) (*models.User, error)
 	FindUsersNotInIdList(context.Context, []string) ([]*models.User, error)
+	UpdateUserPassword(context.Context, string, string) error
+	ChangeUserPassword(context.Context, string, string, int64) error
+	UpdateUserProfile(context.Context, string, *models.ProfileUpdate, int64) error
+	DeleteUser(context.Context, string) error
 }
 
 type usersRepository struct {
@@ -36,23 +41,28 @@ func (r *usersRepository) SaveUser(ctx context.Context, user *models.User) (stri
 	}
 	res, err := r.db.InsertOne(ctx, user)
 	if err != nil {
-		log.Printf("Unable to save user data into database. Reason: %s", err.Error())
+		logger.FromContext(ctx).Error("Unable to save user data into database", "err", err)
 		return "", err
 	}
 	return fmt.Sprintf("%v", res), nil
 }
 
+func (r *usersRepository) FindUserById(ctx context.Context, id string) (*models.User, error) {
+	return r.findUser(ctx, map[string]string{"_id": id})
+}
+
 func (r *usersRepository) FindUserByName(ctx context.Context, name string) (*models.User, error) {
+	return r.findUser(ctx, map[string]string{"userName": name})
+}
+
+func (r *usersRepository) findUser(ctx context.Context, filter map[string]string) (*models.User, error) {
 	var user models.User
-	err := r.db.FindOne(
-		ctx,
-		map[string]string{"userName": name},
-	).Decode(&user)
+	err := r.db.FindOne(ctx, filter).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, ErrUserNotFound
 		}
-		log.Printf("Unable to find user. Reason: %s", err.Error())
+		logger.FromContext(ctx).Error("Unable to find user", "err", err)
 		return nil, err
 	}
 	return &user, nil
@@ -77,3 +87,66 @@ func (r *usersRepository) FindUsersNotInIdList(ctx context.Context, ids []string
 
 	return users, nil
 }
+
+// UpdateUserPassword replaces stored password hash of the user, e.g. when the hash is upgraded on login.
+func (r *usersRepository) UpdateUserPassword(ctx context.Context, id, passwordHash string) error {
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": passwordHash}})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to update user password", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ChangeUserPassword replaces password hash on user's request, the time of the change
+// is stored both as the profile update and as the password change time.
+func (r *usersRepository) ChangeUserPassword(ctx context.Context, id, passwordHash string, changedAt int64) error {
+	set := bson.M{"password": passwordHash, "updatedAt": changedAt, "passwordChangedAt": changedAt}
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to change user password", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateUserProfile sets provided fields of the profile and the time of the update.
+func (r *usersRepository) UpdateUserProfile(ctx context.Context, id string, update *models.ProfileUpdate, updatedAt int64) error {
+	set := bson.M{"updatedAt": updatedAt}
+	if update.DisplayName != nil {
+		set["displayName"] = *update.DisplayName
+	}
+	if update.AvatarUrl != nil {
+		set["avatarUrl"] = *update.AvatarUrl
+	}
+	if update.StatusText != nil {
+		set["statusText"] = *update.StatusText
+	}
+	res, err := r.db.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to update user profile", "err", err)
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *usersRepository) DeleteUser(ctx context.Context, id string) error {
+	deleted, err := r.db.DeleteMany(ctx, bson.M{"_id": id})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to delete user", "err", err)
+		return err
+	}
+	if deleted == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
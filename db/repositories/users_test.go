@@ -163,3 +163,176 @@ func TestFindUsersNotInIdList(t *testing.T) {
 		})
 	}
 }
+
+func TestFindUserById(t *testing.T) {
+	ctx := context.Background()
+	c := new(mocks.CollectionHelper)
+	srh := new(mocks.SingleResultHelper)
+	c.On("FindOne", ctx, map[string]string{"_id": "someid"}).Return(srh, nil)
+	srh.On("Decode", &models.User{}).Return(mongo.ErrNoDocuments)
+	repo := NewUsersRepository(c)
+
+	gotUsr, gotErr := repo.FindUserById(ctx, "someid")
+
+	assert.Nil(t, gotUsr, "FindUserById returned unexpected result: got user %v want %v", gotUsr, nil)
+	assert.Equal(t, ErrUserNotFound, gotErr, "FindUserById returned unexpected result: got success instead of %v", ErrUserNotFound)
+
+	c.AssertExpectations(t)
+	srh.AssertExpectations(t)
+}
+
+func TestUpdateUserPassword(t *testing.T) {
+	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	unknownErr := errors.New("Unable to update")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should replace password hash",
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, bson.M{"$set": bson.M{"password": "hash"}}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:   "should fail with user not found error",
+			wantErr: ErrUserNotFound,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: unknownErr,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch)
+			repo := NewUsersRepository(ch)
+
+			gotErr := repo.UpdateUserPassword(context.Background(), id, "hash")
+
+			assert.Equal(t, testCond.wantErr, gotErr, "UpdateUserPassword returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangeUserPassword(t *testing.T) {
+	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	unknownErr := errors.New("Unable to update")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should replace password hash and store time of the change",
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				set := bson.M{"password": "hash", "updatedAt": int64(10), "passwordChangedAt": int64(10)}
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, bson.M{"$set": set}).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:   "should fail with user not found error",
+			wantErr: ErrUserNotFound,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: unknownErr,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(nil, unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch)
+			repo := NewUsersRepository(ch)
+
+			gotErr := repo.ChangeUserPassword(context.Background(), id, "hash", 10)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "ChangeUserPassword returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateUserProfile(t *testing.T) {
+	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	displayName, statusText := "Foo", ""
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should set provided fields",
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				set := bson.M{"$set": bson.M{"displayName": "Foo", "statusText": "", "updatedAt": int64(100)}}
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, set).Return(&mongo.UpdateResult{MatchedCount: 1}, nil)
+			},
+		},
+		{
+			tName:   "should fail with user not found error",
+			wantErr: ErrUserNotFound,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("UpdateOne", mock.Anything, bson.M{"_id": id}, mock.Anything).Return(&mongo.UpdateResult{}, nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			testCond.prepareMocks(ch)
+			repo := NewUsersRepository(ch)
+
+			gotErr := repo.UpdateUserProfile(context.Background(), id, &models.ProfileUpdate{DisplayName: &displayName, StatusText: &statusText}, 100)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "UpdateUserProfile returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	id := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
+	testConditions := []struct {
+		tName   string
+		deleted int64
+		wantErr error
+	}{
+		{tName: "should delete user", deleted: 1},
+		{tName: "should fail with user not found error", wantErr: ErrUserNotFound},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			ch.On("DeleteMany", mock.Anything, bson.M{"_id": id}).Return(testCond.deleted, nil)
+			repo := NewUsersRepository(ch)
+
+			gotErr := repo.DeleteUser(context.Background(), id)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "DeleteUser returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package broker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+)
+
+// Broker publishes chat events to every instance of the server.
+// Subscribed handlers process events for connections held by the current instance.
+type Broker interface {
+	Publish(context.Context, *models.Event) error
+	Subscribe(func(context.Context, *models.Event) error)
+	Run(context.Context) error
+}
+
+// NewBroker returns broker backend selected in the server config.
+func NewBroker(cnf *config.ServerConfig, db mongo.EventsCollection) Broker {
+	if cnf.BrokerBackend == config.BrokerMongo {
+		return NewMongoBroker(db, cnf)
+	}
+	return NewInMemoryBroker()
+}
+
+type inMemoryBroker struct {
+	handlers []func(context.Context, *models.Event) error
+	mu       *sync.RWMutex
+}
+
+// NewInMemoryBroker delivers events only within the current process,
+// it is suitable for a single instance deployment.
+func NewInMemoryBroker() Broker {
+	return &inMemoryBroker{
+		mu: &sync.RWMutex{},
+	}
+}
+
+// Publish runs subscribed handlers synchronously and returns the first failure.
+func (b *inMemoryBroker) Publish(ctx context.Context, event *models.Event) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	var firstErr error
+	for _, handle := range handlers {
+		if err := handle(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (b *inMemoryBroker) Subscribe(handler func(context.Context, *models.Event) error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Run has nothing to receive from other instances, it waits until the context is cancelled.
+func (b *inMemoryBroker) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
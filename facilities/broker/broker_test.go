@@ -0,0 +1,47 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewBroker(t *testing.T) {
+	ch := new(mocks.CollectionHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("createdAt_1", nil)
+
+	memoryBackend := NewBroker(&config.ServerConfig{BrokerBackend: config.BrokerMemory}, ch)
+	mongoBackend := NewBroker(&config.ServerConfig{BrokerBackend: config.BrokerMongo}, ch)
+
+	assert.IsType(t, &inMemoryBroker{}, memoryBackend, "NewBroker returned unexpected backend: got %T", memoryBackend)
+	assert.IsType(t, &mongoBroker{}, mongoBackend, "NewBroker returned unexpected backend: got %T", mongoBackend)
+
+	ch.AssertExpectations(t)
+}
+
+func TestInMemoryPublish(t *testing.T) {
+	ctx := context.Background()
+	errUnableToHandle := errors.New("Unable to handle event")
+	event := models.NewDirectEvent(&models.Message{Id: "m1"})
+	var received []*models.Event
+	b := NewInMemoryBroker()
+	b.Subscribe(func(ctx context.Context, e *models.Event) error {
+		received = append(received, e)
+		return errUnableToHandle
+	})
+	b.Subscribe(func(ctx context.Context, e *models.Event) error {
+		received = append(received, e)
+		return nil
+	})
+
+	gotErr := b.Publish(ctx, event)
+
+	assert.Equal(t, errUnableToHandle, gotErr, "Publish returned unexpected result: got %v want %v", gotErr, errUnableToHandle)
+	assert.Equal(t, []*models.Event{event, event}, received, "Event was not delivered to every handler: got %v", received)
+}
@@ -0,0 +1,126 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	eventsIndexTimeout = 10 * time.Second
+	eventsRetention    = 5 * time.Minute
+	watchRetryDelay    = 5 * time.Second
+)
+
+// eventRecord is an events collection document.
+type eventRecord struct {
+	Id        string        `bson:"_id"`
+	Node      string        `bson:"node"`
+	CreatedAt time.Time     `bson:"createdAt"`
+	Event     *models.Event `bson:"event"`
+}
+
+// eventChange is an insert notification received from the change stream.
+type eventChange struct {
+	FullDocument *eventRecord `bson:"fullDocument"`
+}
+
+type mongoBroker struct {
+	db    mongo.EventsCollection
+	node  string
+	local Broker
+	retry time.Duration
+}
+
+// NewMongoBroker exchanges events through the events collection change stream,
+// so MongoDB must be deployed as a replica set. Events are removed by the TTL index
+// on createdAt as soon as all instances had a chance to receive them.
+func NewMongoBroker(db mongo.EventsCollection, cnf *config.ServerConfig) Broker {
+	ctx, cancel := context.WithTimeout(context.Background(), eventsIndexTimeout)
+	defer cancel()
+	_, err := db.CreateIndex(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "createdAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(eventsRetention.Seconds())),
+	})
+	if err != nil {
+		logger.Default().Error("Unable to create TTL index for events", "err", err)
+	}
+
+	return &mongoBroker{
+		db:    db,
+		node:  cnf.NodeId,
+		local: NewInMemoryBroker(),
+		retry: watchRetryDelay,
+	}
+}
+
+// Publish handles event on the current instance immediately and stores it for the others.
+func (b *mongoBroker) Publish(ctx context.Context, event *models.Event) error {
+	localErr := b.local.Publish(ctx, event)
+	_, err := b.db.InsertOne(ctx, &eventRecord{
+		Id:        uuid.NewString(),
+		Node:      b.node,
+		CreatedAt: time.Now(),
+		Event:     event,
+	})
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to publish event", "type", event.Type, "err", err)
+		return err
+	}
+	return localErr
+}
+
+func (b *mongoBroker) Subscribe(handler func(context.Context, *models.Event) error) {
+	b.local.Subscribe(handler)
+}
+
+// Run handles events published by other instances until the context is cancelled.
+// Change stream is reopened when it fails.
+func (b *mongoBroker) Run(ctx context.Context) error {
+	for {
+		err := b.watch(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			logger.FromContext(ctx).Error("Events change stream failed", "err", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(b.retry):
+		}
+	}
+}
+
+func (b *mongoBroker) watch(ctx context.Context) error {
+	stream, err := b.db.Watch(ctx, bson.A{
+		bson.M{"$match": bson.M{
+			"operationType":     "insert",
+			"fullDocument.node": bson.M{"$ne": b.node},
+		}},
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		change := &eventChange{}
+		if err = stream.Decode(change); err != nil || change.FullDocument == nil || change.FullDocument.Event == nil {
+			logger.FromContext(ctx).Error("Unable to decode event from change stream", "err", err)
+			continue
+		}
+		if err = b.local.Publish(ctx, change.FullDocument.Event); err != nil {
+			logger.FromContext(ctx).Error("Unable to handle event", "type", change.FullDocument.Event.Type, "err", err)
+		}
+	}
+	return stream.Err()
+}
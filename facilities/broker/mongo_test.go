@@ -0,0 +1,95 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var brokerTestConfig = &config.ServerConfig{NodeId: "node1", BrokerBackend: config.BrokerMongo}
+
+func TestMongoPublish(t *testing.T) {
+	ctx := context.Background()
+	errUnableToInsert := errors.New("Unable to insert event")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.CollectionHelper)
+	}{
+		{
+			tName: "should store event for other instances",
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("InsertOne", ctx, mock.MatchedBy(func(record *eventRecord) bool {
+					return record.Id != "" && record.Node == "node1" && record.Event.Type == models.EventTypeDirect
+				})).Return("id", nil)
+			},
+		},
+		{
+			tName:   "should fail with unable to insert error",
+			wantErr: errUnableToInsert,
+			prepareMocks: func(ch *mocks.CollectionHelper) {
+				ch.On("InsertOne", ctx, mock.Anything).Return(nil, errUnableToInsert)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ch := new(mocks.CollectionHelper)
+			ch.On("CreateIndex", mock.Anything, mock.Anything).Return("createdAt_1", nil)
+			testCond.prepareMocks(ch)
+			handled := 0
+			b := NewMongoBroker(ch, brokerTestConfig)
+			b.Subscribe(func(ctx context.Context, e *models.Event) error {
+				handled++
+				return nil
+			})
+
+			gotErr := b.Publish(ctx, models.NewDirectEvent(&models.Message{Id: "m1"}))
+
+			assert.Equal(t, testCond.wantErr, gotErr, "Publish returned unexpected result: got %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, 1, handled, "Event was not handled locally")
+
+			ch.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMongoRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	event := models.NewDirectEvent(&models.Message{Id: "m1"})
+	ch := new(mocks.CollectionHelper)
+	cs := new(mocks.ChangeStreamHelper)
+	ch.On("CreateIndex", mock.Anything, mock.Anything).Return("createdAt_1", nil)
+	ch.On("Watch", ctx, mock.Anything).Return(cs, nil)
+	cs.On("Next", ctx).Return(true).Once()
+	cs.On("Next", ctx).Return(false)
+	cs.On("Decode", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*eventChange).FullDocument = &eventRecord{Id: "e1", Node: "node2", Event: event}
+	}).Return(nil)
+	cs.On("Err").Return(nil)
+	cs.On("Close", mock.Anything).Return(nil)
+	b := NewMongoBroker(ch, brokerTestConfig).(*mongoBroker)
+	b.retry = time.Millisecond
+	var received *models.Event
+	b.Subscribe(func(ctx context.Context, e *models.Event) error {
+		received = e
+		cancel()
+		return nil
+	})
+
+	gotErr := b.Run(ctx)
+
+	assert.Nil(t, gotErr, "Run returned unexpected result: got %v want %v", gotErr, nil)
+	assert.Equal(t, event, received, "Run delivered unexpected event: got %v want %v", received, event)
+
+	ch.AssertExpectations(t)
+	cs.AssertExpectations(t)
+}
@@ -0,0 +1,15 @@
+package logging
+
+import (
+	"os"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/pkg/logger"
+)
+
+// NewLogger writes entries to the standard output with the level and format from the config.
+// Level is validated when the config is loaded, unknown one falls back to info.
+func NewLogger(cnf *config.ServerConfig) *logger.Logger {
+	level, _ := logger.ParseLevel(cnf.LogLevel)
+	return logger.New(os.Stdout, level, logger.Format(cnf.LogFormat))
+}
@@ -6,12 +6,14 @@ import (
 	"github.com/andriystech/lgc/config"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 )
 
 type ClientHelper interface {
 	Database(string) DatabaseHelper
 	Connect(context.Context) error
 	Disconnect(context.Context) error
+	Ping(context.Context) error
 }
 
 type mongoClient struct {
@@ -37,3 +39,8 @@ func (mc *mongoClient) Connect(ctx context.Context) error {
 func (mc *mongoClient) Disconnect(ctx context.Context) error {
 	return mc.cl.Disconnect(ctx)
 }
+
+// Ping checks that the primary of the deployment is reachable.
+func (mc *mongoClient) Ping(ctx context.Context) error {
+	return mc.cl.Ping(ctx, readpref.Primary())
+}
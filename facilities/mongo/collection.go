@@ -2,15 +2,24 @@ package mongo
 
 import (
 	"context"
+	"time"
 
 	"github.com/andriystech/lgc/config"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CollectionHelper interface {
-	Find(context.Context, interface{}) (MultiResultHelper, error)
+	Find(context.Context, interface{}, ...*options.FindOptions) (MultiResultHelper, error)
 	FindOne(context.Context, interface{}) SingleResultHelper
+	FindOneAndDelete(context.Context, interface{}) SingleResultHelper
+	CreateIndex(context.Context, IndexModel) (string, error)
 	InsertOne(context.Context, interface{}) (interface{}, error)
+	InsertMany(context.Context, []interface{}) ([]interface{}, error)
+	UpdateOne(context.Context, interface{}, interface{}) (*UpdateResult, error)
+	UpdateMany(context.Context, interface{}, interface{}) (*UpdateResult, error)
+	DeleteMany(context.Context, interface{}) (int64, error)
+	Watch(context.Context, interface{}) (ChangeStreamHelper, error)
 }
 
 type MessagesCollection CollectionHelper
@@ -19,18 +28,56 @@ func NewMessagesCollection(client ClientHelper, config *config.ServerConfig) Mes
 	return client.Database(config.DbName).Collection("messages")
 }
 
+type DeliveriesCollection CollectionHelper
+
+func NewDeliveriesCollection(client ClientHelper, config *config.ServerConfig) DeliveriesCollection {
+	return client.Database(config.DbName).Collection("message_deliveries")
+}
+
 type UsersCollection CollectionHelper
 
 func NewUsersCollection(client ClientHelper, config *config.ServerConfig) UsersCollection {
 	return client.Database(config.DbName).Collection("users")
 }
 
+type RoomsCollection CollectionHelper
+
+func NewRoomsCollection(client ClientHelper, config *config.ServerConfig) RoomsCollection {
+	return client.Database(config.DbName).Collection("rooms")
+}
+
+type RevokedTokensCollection CollectionHelper
+
+func NewRevokedTokensCollection(client ClientHelper, config *config.ServerConfig) RevokedTokensCollection {
+	return client.Database(config.DbName).Collection("revoked_tokens")
+}
+
+type TokensCollection CollectionHelper
+
+func NewTokensCollection(client ClientHelper, config *config.ServerConfig) TokensCollection {
+	return client.Database(config.DbName).Collection("tokens")
+}
+
+type EventsCollection CollectionHelper
+
+func NewEventsCollection(client ClientHelper, config *config.ServerConfig) EventsCollection {
+	return client.Database(config.DbName).Collection("events")
+}
+
+type PresenceCollection CollectionHelper
+
+func NewPresenceCollection(client ClientHelper, config *config.ServerConfig) PresenceCollection {
+	return client.Database(config.DbName).Collection("presence")
+}
+
 type mongoCollection struct {
 	coll *mongo.Collection
 }
 
-func (mc *mongoCollection) Find(ctx context.Context, filter interface{}) (MultiResultHelper, error) {
-	multiResult, err := mc.coll.Find(ctx, filter)
+func (mc *mongoCollection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (MultiResultHelper, error) {
+	start := time.Now()
+	multiResult, err := mc.coll.Find(ctx, filter, opts...)
+	mc.observe("find", start, err)
 	if err != nil {
 		return nil, err
 	}
@@ -38,11 +85,77 @@ func (mc *mongoCollection) Find(ctx context.Context, filter interface{}) (MultiR
 }
 
 func (mc *mongoCollection) FindOne(ctx context.Context, filter interface{}) SingleResultHelper {
+	start := time.Now()
 	singleResult := mc.coll.FindOne(ctx, filter)
+	mc.observe("findOne", start, singleResult.Err())
 	return &mongoSingleResult{sr: singleResult}
 }
 
+func (mc *mongoCollection) FindOneAndDelete(ctx context.Context, filter interface{}) SingleResultHelper {
+	start := time.Now()
+	singleResult := mc.coll.FindOneAndDelete(ctx, filter)
+	mc.observe("findOneAndDelete", start, singleResult.Err())
+	return &mongoSingleResult{sr: singleResult}
+}
+
+func (mc *mongoCollection) CreateIndex(ctx context.Context, model IndexModel) (string, error) {
+	start := time.Now()
+	name, err := mc.coll.Indexes().CreateOne(ctx, model)
+	mc.observe("createIndex", start, err)
+	return name, err
+}
+
 func (mc *mongoCollection) InsertOne(ctx context.Context, document interface{}) (interface{}, error) {
-	id, err := mc.coll.InsertOne(ctx, document)
-	return id.InsertedID, err
+	start := time.Now()
+	res, err := mc.coll.InsertOne(ctx, document)
+	mc.observe("insertOne", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return res.InsertedID, nil
+}
+
+func (mc *mongoCollection) InsertMany(ctx context.Context, documents []interface{}) ([]interface{}, error) {
+	start := time.Now()
+	res, err := mc.coll.InsertMany(ctx, documents)
+	mc.observe("insertMany", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return res.InsertedIDs, nil
+}
+
+func (mc *mongoCollection) UpdateOne(ctx context.Context, filter interface{}, update interface{}) (*UpdateResult, error) {
+	start := time.Now()
+	res, err := mc.coll.UpdateOne(ctx, filter, update)
+	mc.observe("updateOne", start, err)
+	return res, err
+}
+
+func (mc *mongoCollection) UpdateMany(ctx context.Context, filter interface{}, update interface{}) (*UpdateResult, error) {
+	start := time.Now()
+	res, err := mc.coll.UpdateMany(ctx, filter, update)
+	mc.observe("updateMany", start, err)
+	return res, err
+}
+
+func (mc *mongoCollection) DeleteMany(ctx context.Context, filter interface{}) (int64, error) {
+	start := time.Now()
+	res, err := mc.coll.DeleteMany(ctx, filter)
+	mc.observe("deleteMany", start, err)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+// Watch opens change stream of the collection, it requires replica set deployment.
+func (mc *mongoCollection) Watch(ctx context.Context, pipeline interface{}) (ChangeStreamHelper, error) {
+	start := time.Now()
+	stream, err := mc.coll.Watch(ctx, pipeline)
+	mc.observe("watch", start, err)
+	if err != nil {
+		return nil, err
+	}
+	return &mongoChangeStream{cs: stream}, nil
 }
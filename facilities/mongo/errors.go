@@ -3,3 +3,8 @@ package mongo
 import "go.mongodb.org/mongo-driver/mongo"
 
 var ErrNoDocuments = mongo.ErrNoDocuments
+
+// IsDuplicateKeyError reports whether err is caused by a unique index violation.
+func IsDuplicateKeyError(err error) bool {
+	return mongo.IsDuplicateKeyError(err)
+}
@@ -0,0 +1,28 @@
+package mongo
+
+import (
+	"time"
+
+	"github.com/andriystech/lgc/pkg/metrics"
+)
+
+var (
+	operationDuration = metrics.NewHistogram(
+		"lgc_mongo_operation_duration_seconds",
+		"Latency of MongoDB operations by collection and operation.",
+		metrics.DefaultBuckets,
+		"collection", "operation",
+	)
+	operationErrors = metrics.NewCounter(
+		"lgc_mongo_operation_errors_total",
+		"Failed MongoDB operations by collection and operation, missing documents are not failures.",
+		"collection", "operation",
+	)
+)
+
+func (mc *mongoCollection) observe(operation string, start time.Time, err error) {
+	operationDuration.ObserveSince(start, mc.coll.Name(), operation)
+	if err != nil && err != ErrNoDocuments {
+		operationErrors.Inc(mc.coll.Name(), operation)
+	}
+}
@@ -6,6 +6,10 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+type UpdateResult = mongo.UpdateResult
+
+type IndexModel = mongo.IndexModel
+
 type SingleResultHelper interface {
 	Decode(v interface{}) error
 }
@@ -29,3 +33,30 @@ type mongoMultiResult struct {
 func (mr *mongoMultiResult) All(ctx context.Context, v interface{}) error {
 	return mr.mc.All(ctx, v)
 }
+
+type ChangeStreamHelper interface {
+	Next(context.Context) bool
+	Decode(v interface{}) error
+	Err() error
+	Close(context.Context) error
+}
+
+type mongoChangeStream struct {
+	cs *mongo.ChangeStream
+}
+
+func (cs *mongoChangeStream) Next(ctx context.Context) bool {
+	return cs.cs.Next(ctx)
+}
+
+func (cs *mongoChangeStream) Decode(v interface{}) error {
+	return cs.cs.Decode(v)
+}
+
+func (cs *mongoChangeStream) Err() error {
+	return cs.cs.Err()
+}
+
+func (cs *mongoChangeStream) Close(ctx context.Context) error {
+	return cs.cs.Close(ctx)
+}
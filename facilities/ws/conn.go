@@ -1,41 +1,207 @@
 package ws
 
 import (
+	"context"
 	"sync"
+	"time"
 
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/gorilla/websocket"
 )
 
+// Close codes sent to the peer when server closes the connection.
+const (
+	CloseNormal          = websocket.CloseNormalClosure
+	CloseGoingAway       = websocket.CloseGoingAway
+	ClosePolicyViolation = websocket.ClosePolicyViolation
+	CloseTryAgainLater   = websocket.CloseTryAgainLater
+)
+
+const defaultCloseWait = time.Second
+
+// ConnHelper is safe for concurrent use, reads never block writes.
+// Connection should be read by a single goroutine, writes are queued and sent by the connection writer.
 type ConnHelper interface {
-	Close()
+	Close() error
+	CloseWithReason(int, string) error
+	Done() <-chan struct{}
+	Flush(context.Context) error
+	Protocol() Protocol
 	ReadMessage() (int, []byte, error)
 	WriteMessage(int, []byte) error
+	QueueDepth() int
+}
+
+// ConnOptions configures outbound queue, keepalive and logger of a connection.
+// Peer which does not answer pings within PongWait is considered dead, zero values disable the limits.
+// Connection without logger writes to the default one.
+type ConnOptions struct {
+	Queue          QueueOptions
+	PingPeriod     time.Duration
+	PongWait       time.Duration
+	WriteWait      time.Duration
+	MaxMessageSize int64
+	Logger         *logger.Logger
 }
 
 type websocketConnection struct {
-	c  *websocket.Conn
-	mu *sync.Mutex
+	c        *websocket.Conn
+	readMu   *sync.Mutex
+	protocol Protocol
+	queue    *sendQueue
+	opts     ConnOptions
+	closed   chan struct{}
+	once     *sync.Once
+	closeErr error
 }
 
-func NewConn(c *websocket.Conn) ConnHelper {
-	return &websocketConnection{
-		c:  c,
-		mu: &sync.Mutex{},
+// NewConn wraps web socket connection and starts its writer,
+// messages are written to the socket in the order they were queued.
+func NewConn(c *websocket.Conn, protocol Protocol, opts ConnOptions) ConnHelper {
+	wc := &websocketConnection{
+		c:        c,
+		readMu:   &sync.Mutex{},
+		protocol: protocol,
+		queue:    newSendQueue(opts.Queue),
+		opts:     opts,
+		closed:   make(chan struct{}),
+		once:     &sync.Once{},
+	}
+	if wc.opts.Logger == nil {
+		wc.opts.Logger = logger.Default()
+	}
+	if opts.MaxMessageSize > 0 {
+		c.SetReadLimit(opts.MaxMessageSize)
+	}
+	if opts.PongWait > 0 {
+		wc.extendReadDeadline()
+		c.SetPongHandler(func(string) error {
+			wc.extendReadDeadline()
+			return nil
+		})
 	}
+	go wc.writeLoop()
+	return wc
 }
 
-func (wc *websocketConnection) Close() {
-	wc.c.Close()
+// Close closes the connection with normal closure code.
+func (wc *websocketConnection) Close() error {
+	return wc.CloseWithReason(CloseNormal, "")
 }
 
+// CloseWithReason stops the writer, discards queued messages and sends close frame to the peer.
+// Only the first call closes the connection, the rest return its result.
+func (wc *websocketConnection) CloseWithReason(code int, reason string) error {
+	wc.once.Do(func() {
+		close(wc.closed)
+		wc.queue.close()
+		wait := wc.opts.WriteWait
+		if wait <= 0 {
+			wait = defaultCloseWait
+		}
+		// peer may be gone already, so failure to say goodbye is not an error
+		_ = wc.c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wait))
+		wc.closeErr = wc.c.Close()
+	})
+	return wc.closeErr
+}
+
+// Done is closed once the connection is closed by any side.
+func (wc *websocketConnection) Done() <-chan struct{} {
+	return wc.closed
+}
+
+func (wc *websocketConnection) Protocol() Protocol {
+	return wc.protocol
+}
+
+// ReadMessage fails when the peer was silent longer than the pong wait,
+// so read loop of a dead peer ends and its connection is cleaned up.
 func (wc *websocketConnection) ReadMessage() (int, []byte, error) {
-	wc.mu.Lock()
-	defer wc.mu.Unlock()
-	return wc.c.ReadMessage()
+	wc.readMu.Lock()
+	defer wc.readMu.Unlock()
+	mt, data, err := wc.c.ReadMessage()
+	if err != nil {
+		wc.Close()
+	}
+	return mt, data, err
 }
 
+// WriteMessage queues the message without waiting for the socket. Slow consumer
+// is disconnected when its queue is full and disconnect policy is configured.
 func (wc *websocketConnection) WriteMessage(mt int, msg []byte) error {
-	wc.mu.Lock()
-	defer wc.mu.Unlock()
-	return wc.c.WriteMessage(mt, msg)
+	err := wc.queue.push(&outboundMessage{messageType: mt, data: msg})
+	if err == ErrSlowConsumer {
+		wc.opts.Logger.Warn("Closing slow web socket consumer", "remoteAddr", wc.c.RemoteAddr().String(), "queueDepth", wc.queue.depth())
+		wc.CloseWithReason(CloseTryAgainLater, "slow consumer")
+	}
+	return err
+}
+
+// Flush waits until messages queued before the call are written to the socket.
+// Flush may time out when its marker is dropped from overflowed queue.
+func (wc *websocketConnection) Flush(ctx context.Context) error {
+	flushed := make(chan struct{})
+	if err := wc.queue.push(&outboundMessage{flushed: flushed}); err != nil {
+		return err
+	}
+	select {
+	case <-flushed:
+		return nil
+	case <-wc.closed:
+		return ErrConnClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (wc *websocketConnection) QueueDepth() int {
+	return wc.queue.depth()
+}
+
+// writeLoop is the only writer of the socket, it pings the peer periodically and stops
+// when connection is closed or the socket write fails.
+func (wc *websocketConnection) writeLoop() {
+	var ping <-chan time.Time
+	if wc.opts.PingPeriod > 0 {
+		ticker := time.NewTicker(wc.opts.PingPeriod)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		var err error
+		select {
+		case <-wc.closed:
+			return
+		case msg := <-wc.queue.ch:
+			wc.queue.pop()
+			if msg.flushed != nil {
+				close(msg.flushed)
+				continue
+			}
+			err = wc.write(msg.messageType, msg.data)
+		case <-ping:
+			err = wc.write(websocket.PingMessage, nil)
+		}
+		if err != nil {
+			wc.opts.Logger.Error("Unable to write to web socket", "err", err)
+			wc.Close()
+			return
+		}
+	}
+}
+
+func (wc *websocketConnection) write(mt int, data []byte) error {
+	if wc.opts.WriteWait > 0 {
+		if err := wc.c.SetWriteDeadline(time.Now().Add(wc.opts.WriteWait)); err != nil {
+			return err
+		}
+	}
+	return wc.c.WriteMessage(mt, data)
+}
+
+func (wc *websocketConnection) extendReadDeadline() {
+	if err := wc.c.SetReadDeadline(time.Now().Add(wc.opts.PongWait)); err != nil {
+		wc.opts.Logger.Error("Unable to set web socket read deadline", "err", err)
+	}
 }
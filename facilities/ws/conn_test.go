@@ -0,0 +1,196 @@
+package ws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+var keepaliveOptions = ConnOptions{
+	PingPeriod: 20 * time.Millisecond,
+	PongWait:   100 * time.Millisecond,
+	WriteWait:  100 * time.Millisecond,
+}
+
+// serveConn returns client and server sides of a wrapped connection,
+// server side stays open until it is closed by the test or the peer.
+func serveConn(t *testing.T, opts ConnOptions) (*websocket.Conn, ConnHelper) {
+	accepted := make(chan ConnHelper, 1)
+	upgrader := &websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := NewConn(c, ProtocolJSON, opts)
+		accepted <- conn
+		<-conn.Done()
+	}))
+	t.Cleanup(server.Close)
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.Nil(t, err, "%v", err)
+	t.Cleanup(func() { client.Close() })
+
+	conn := <-accepted
+	t.Cleanup(func() { conn.Close() })
+	return client, conn
+}
+
+// readAsync reads from the connection in background and passes result of the first read.
+func readAsync(conn ConnHelper) <-chan error {
+	readErr := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadMessage()
+		readErr <- err
+	}()
+	return readErr
+}
+
+func waitFor(t *testing.T, ch <-chan error, failure string) error {
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(time.Second):
+		t.Fatal(failure)
+		return nil
+	}
+}
+
+func TestConnDropsSilentPeer(t *testing.T) {
+	// client does not read, so pings are never answered
+	_, conn := serveConn(t, keepaliveOptions)
+
+	err := waitFor(t, readAsync(conn), "silent peer was not dropped")
+
+	assert.NotNil(t, err, "ReadMessage returned unexpected result: got %v want error", err)
+	select {
+	case <-conn.Done():
+	default:
+		t.Error("connection of silent peer is not done")
+	}
+}
+
+func TestConnKeepsAnsweringPeer(t *testing.T) {
+	client, conn := serveConn(t, keepaliveOptions)
+	go func() {
+		for {
+			if _, _, err := client.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	readErr := readAsync(conn)
+
+	time.Sleep(3 * keepaliveOptions.PongWait)
+	err := client.WriteMessage(websocket.TextMessage, []byte("ping"))
+	assert.Nil(t, err, "%v", err)
+
+	err = waitFor(t, readErr, "message of live peer was not received")
+	assert.Nil(t, err, "ReadMessage returned unexpected result: got %v want nil", err)
+}
+
+func TestConnLimitsMessageSize(t *testing.T) {
+	client, conn := serveConn(t, ConnOptions{MaxMessageSize: 8})
+	readErr := readAsync(conn)
+
+	err := client.WriteMessage(websocket.TextMessage, []byte("too long message"))
+	assert.Nil(t, err, "%v", err)
+
+	err = waitFor(t, readErr, "oversized message was accepted")
+	assert.Equal(t, websocket.ErrReadLimit, err, "ReadMessage returned unexpected result: got %v want %v", err, websocket.ErrReadLimit)
+}
+
+func TestConnWritesWhileReading(t *testing.T) {
+	const writers, messages = 8, 25
+	client, conn := serveConn(t, ConnOptions{})
+	readAsync(conn)
+
+	wg := &sync.WaitGroup{}
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for m := 0; m < messages; m++ {
+				err := conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("%d-%d", w, m)))
+				assert.Nil(t, err, "WriteMessage returned unexpected result: got %v want nil", err)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	received := map[string]bool{}
+	for len(received) < writers*messages {
+		_, data, err := client.ReadMessage()
+		if !assert.Nil(t, err, "messages were not delivered while connection was read: got %d want %d", len(received), writers*messages) {
+			return
+		}
+		received[string(data)] = true
+	}
+}
+
+func TestConnConcurrentClose(t *testing.T) {
+	client, conn := serveConn(t, ConnOptions{})
+	readErr := readAsync(conn)
+
+	wg := &sync.WaitGroup{}
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			conn.WriteMessage(websocket.TextMessage, []byte("bye"))
+			errs[i] = conn.CloseWithReason(ClosePolicyViolation, "kicked")
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		assert.Equal(t, errs[0], err, "CloseWithReason returned unexpected result: got %v want %v", err, errs[0])
+	}
+	err := conn.WriteMessage(websocket.TextMessage, []byte("late"))
+	assert.Equal(t, ErrConnClosed, err, "WriteMessage returned unexpected result: got %v want %v", err, ErrConnClosed)
+	assert.NotNil(t, waitFor(t, readErr, "read was not interrupted by close"), "ReadMessage returned unexpected result: want error")
+
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		_, _, err = client.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+	assert.True(t, websocket.IsCloseError(err, ClosePolicyViolation), "peer received unexpected close: got %v want %d", err, ClosePolicyViolation)
+}
+
+func TestConnFlush(t *testing.T) {
+	client, conn := serveConn(t, ConnOptions{})
+	for i := 0; i < 10; i++ {
+		conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprint(i)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := conn.Flush(ctx)
+
+	assert.Nil(t, err, "Flush returned unexpected result: got %v want nil", err)
+	assert.Equal(t, 0, conn.QueueDepth(), "queue has unexpected depth: got %v want 0", conn.QueueDepth())
+	client.SetReadDeadline(time.Now().Add(time.Second))
+	for i := 0; i < 10; i++ {
+		_, data, err := client.ReadMessage()
+		assert.Nil(t, err, "%v", err)
+		assert.Equal(t, fmt.Sprint(i), string(data), "peer received unexpected message: got %v want %v", string(data), i)
+	}
+
+	conn.Close()
+	err = conn.Flush(ctx)
+	assert.Equal(t, ErrConnClosed, err, "Flush returned unexpected result: got %v want %v", err, ErrConnClosed)
+}
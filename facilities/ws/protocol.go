@@ -0,0 +1,53 @@
+package ws
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+var ErrUnsupportedProtocol = errors.New("unsupported web socket protocol")
+
+// Protocol defines how frames are encoded on the wire for a single connection.
+type Protocol string
+
+const (
+	// ProtocolJSON exchanges versioned JSON envelopes, clients opt in to it.
+	ProtocolJSON Protocol = "json"
+	// ProtocolLegacy exchanges bare text payloads, it is used by default so clients built
+	// before envelopes existed keep working.
+	ProtocolLegacy Protocol = "legacy"
+)
+
+const protocolQueryParam = "protocol"
+
+// ProtocolFromRequest returns protocol selected by client with 'protocol' query parameter or,
+// when it is absent, the first supported one offered in Sec-WebSocket-Protocol header.
+// Clients selecting neither get the legacy protocol.
+func ProtocolFromRequest(r *http.Request) (Protocol, error) {
+	switch p := Protocol(r.URL.Query().Get(protocolQueryParam)); p {
+	case "":
+	case ProtocolJSON, ProtocolLegacy:
+		return p, nil
+	default:
+		return "", ErrUnsupportedProtocol
+	}
+	for _, p := range websocket.Subprotocols(r) {
+		if p := Protocol(p); p == ProtocolJSON || p == ProtocolLegacy {
+			return p, nil
+		}
+	}
+	return ProtocolLegacy, nil
+}
+
+// subprotocolHeader confirms protocol to the client when it was offered as a subprotocol,
+// otherwise clients which offered some subprotocols may reject the connection.
+func subprotocolHeader(r *http.Request, protocol Protocol) http.Header {
+	for _, p := range websocket.Subprotocols(r) {
+		if Protocol(p) == protocol {
+			return http.Header{"Sec-Websocket-Protocol": {p}}
+		}
+	}
+	return nil
+}
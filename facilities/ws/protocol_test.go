@@ -0,0 +1,73 @@
+package ws
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProtocolFromRequest(t *testing.T) {
+	testConditions := []struct {
+		tName        string
+		url          string
+		subprotocols string
+		wantProtocol Protocol
+		wantHeader   http.Header
+		wantErr      error
+	}{
+		{
+			tName:        "should default to legacy protocol",
+			url:          "/chat/ws.rtm.start",
+			wantProtocol: ProtocolLegacy,
+		},
+		{
+			tName:        "should select json protocol with query parameter",
+			url:          "/chat/ws.rtm.start?protocol=json",
+			wantProtocol: ProtocolJSON,
+		},
+		{
+			tName:        "should select json protocol with subprotocol and confirm it",
+			url:          "/chat/ws.rtm.start",
+			subprotocols: "chat, json",
+			wantProtocol: ProtocolJSON,
+			wantHeader:   http.Header{"Sec-Websocket-Protocol": {"json"}},
+		},
+		{
+			tName:        "should prefer query parameter over subprotocol",
+			url:          "/chat/ws.rtm.start?protocol=legacy",
+			subprotocols: "json",
+			wantProtocol: ProtocolLegacy,
+		},
+		{
+			tName:        "should ignore unknown subprotocols",
+			url:          "/chat/ws.rtm.start",
+			subprotocols: "chat",
+			wantProtocol: ProtocolLegacy,
+		},
+		{
+			tName:   "should fail with unsupported protocol error",
+			url:     "/chat/ws.rtm.start?protocol=xml",
+			wantErr: ErrUnsupportedProtocol,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, testCond.url, nil)
+			if testCond.subprotocols != "" {
+				r.Header.Set("Sec-Websocket-Protocol", testCond.subprotocols)
+			}
+
+			gotProtocol, gotErr := ProtocolFromRequest(r)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "ProtocolFromRequest returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantProtocol, gotProtocol, "ProtocolFromRequest returned unexpected result: got %v want %v", gotProtocol, testCond.wantProtocol)
+			if gotErr == nil {
+				gotHeader := subprotocolHeader(r, gotProtocol)
+				assert.Equal(t, testCond.wantHeader, gotHeader, "subprotocolHeader returned unexpected result: got %v want %v", gotHeader, testCond.wantHeader)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package ws
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/andriystech/lgc/config"
+)
+
+var ErrSlowConsumer = errors.New("outbound queue of the connection is full")
+var ErrConnClosed = errors.New("connection is closed")
+
+const defaultSendQueueSize = 256
+
+// QueueOptions configures outbound queue of a connection.
+// Metrics are shared by connections to report totals, new ones are created when nil.
+type QueueOptions struct {
+	Size    int
+	Policy  string
+	Metrics *QueueMetrics
+}
+
+// QueueStats is a snapshot of outbound queues of all connections sharing the metrics.
+type QueueStats struct {
+	Capacity     int
+	Queued       int64
+	Dropped      int64
+	Disconnected int64
+}
+
+// QueueMetrics counts messages waiting in outbound queues and the ones lost because of full queues.
+type QueueMetrics struct {
+	capacity     int
+	queued       int64
+	dropped      int64
+	disconnected int64
+}
+
+func NewQueueMetrics(capacity int) *QueueMetrics {
+	return &QueueMetrics{capacity: capacity}
+}
+
+func (m *QueueMetrics) Stats() QueueStats {
+	return QueueStats{
+		Capacity:     m.capacity,
+		Queued:       atomic.LoadInt64(&m.queued),
+		Dropped:      atomic.LoadInt64(&m.dropped),
+		Disconnected: atomic.LoadInt64(&m.disconnected),
+	}
+}
+
+// outboundMessage is either a message for the peer or a flush marker closed by the writer.
+type outboundMessage struct {
+	messageType int
+	data        []byte
+	flushed     chan struct{}
+}
+
+// sendQueue is a bounded queue of outbound messages drained by the connection writer.
+type sendQueue struct {
+	ch      chan *outboundMessage
+	policy  string
+	metrics *QueueMetrics
+	closed  bool
+	mu      *sync.Mutex
+}
+
+func newSendQueue(opts QueueOptions) *sendQueue {
+	if opts.Size <= 0 {
+		opts.Size = defaultSendQueueSize
+	}
+	if opts.Metrics == nil {
+		opts.Metrics = NewQueueMetrics(opts.Size)
+	}
+	return &sendQueue{
+		ch:      make(chan *outboundMessage, opts.Size),
+		policy:  opts.Policy,
+		metrics: opts.Metrics,
+		mu:      &sync.Mutex{},
+	}
+}
+
+// push never blocks. When the queue is full the oldest message is dropped,
+// or ErrSlowConsumer is returned if the queue is configured to disconnect slow consumers.
+func (q *sendQueue) push(msg *outboundMessage) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrConnClosed
+	}
+	for {
+		select {
+		case q.ch <- msg:
+			atomic.AddInt64(&q.metrics.queued, 1)
+			return nil
+		default:
+		}
+		if q.policy == config.SendQueueDisconnect {
+			atomic.AddInt64(&q.metrics.disconnected, 1)
+			return ErrSlowConsumer
+		}
+		select {
+		case <-q.ch:
+			atomic.AddInt64(&q.metrics.queued, -1)
+			atomic.AddInt64(&q.metrics.dropped, 1)
+		default:
+		}
+	}
+}
+
+// pop is called by the writer once the message was received from the channel.
+func (q *sendQueue) pop() {
+	atomic.AddInt64(&q.metrics.queued, -1)
+}
+
+func (q *sendQueue) depth() int {
+	return len(q.ch)
+}
+
+// close rejects further messages and discards the ones which were not written.
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	for {
+		select {
+		case <-q.ch:
+			atomic.AddInt64(&q.metrics.queued, -1)
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package ws
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func fillQueue(q *sendQueue, count int) []error {
+	errs := []error{}
+	for i := 0; i < count; i++ {
+		errs = append(errs, q.push(&outboundMessage{messageType: 1, data: []byte(fmt.Sprint(i))}))
+	}
+	return errs
+}
+
+func TestSendQueueDropOldest(t *testing.T) {
+	metrics := NewQueueMetrics(2)
+	q := newSendQueue(QueueOptions{Size: 2, Policy: config.SendQueueDropOldest, Metrics: metrics})
+
+	for _, err := range fillQueue(q, 5) {
+		assert.Nil(t, err, "push returned unexpected error: got %v want nil", err)
+	}
+
+	first, second := <-q.ch, <-q.ch
+	assert.Equal(t, "3", string(first.data), "queue kept unexpected message: got %v want 3", string(first.data))
+	assert.Equal(t, "4", string(second.data), "queue kept unexpected message: got %v want 4", string(second.data))
+	want := QueueStats{Capacity: 2, Queued: 2, Dropped: 3}
+	assert.Equal(t, want, metrics.Stats(), "metrics returned unexpected stats: got %v want %v", metrics.Stats(), want)
+}
+
+func TestSendQueueDisconnect(t *testing.T) {
+	metrics := NewQueueMetrics(2)
+	q := newSendQueue(QueueOptions{Size: 2, Policy: config.SendQueueDisconnect, Metrics: metrics})
+
+	errs := fillQueue(q, 3)
+
+	assert.Nil(t, errs[1], "push returned unexpected error: got %v want nil", errs[1])
+	assert.Equal(t, ErrSlowConsumer, errs[2], "push returned unexpected error: got %v want %v", errs[2], ErrSlowConsumer)
+	assert.Equal(t, 2, q.depth(), "queue has unexpected depth: got %v want 2", q.depth())
+	want := QueueStats{Capacity: 2, Queued: 2, Disconnected: 1}
+	assert.Equal(t, want, metrics.Stats(), "metrics returned unexpected stats: got %v want %v", metrics.Stats(), want)
+}
+
+func TestSendQueueClose(t *testing.T) {
+	metrics := NewQueueMetrics(4)
+	q := newSendQueue(QueueOptions{Size: 4, Metrics: metrics})
+	fillQueue(q, 3)
+
+	q.close()
+	err := q.push(&outboundMessage{})
+
+	assert.Equal(t, ErrConnClosed, err, "push returned unexpected error: got %v want %v", err, ErrConnClosed)
+	assert.Equal(t, 0, q.depth(), "queue has unexpected depth: got %v want 0", q.depth())
+	assert.Equal(t, int64(0), metrics.Stats().Queued, "metrics returned unexpected queued count: got %v want 0", metrics.Stats().Queued)
+}
@@ -4,23 +4,37 @@ import (
 	"net/http"
 
 	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/gorilla/websocket"
 )
 
 type UpgraderHelper interface {
 	Upgrade(http.ResponseWriter, *http.Request) (ConnHelper, error)
+	QueueStats() QueueStats
 }
 
 type websocketUpgrader struct {
 	updater *websocket.Upgrader
+	options ConnOptions
 }
 
 func (wu *websocketUpgrader) Upgrade(w http.ResponseWriter, r *http.Request) (ConnHelper, error) {
-	conn, err := wu.updater.Upgrade(w, r, nil)
+	protocol, err := ProtocolFromRequest(r)
 	if err != nil {
 		return nil, err
 	}
-	return NewConn(conn), nil
+	conn, err := wu.updater.Upgrade(w, r, subprotocolHeader(r, protocol))
+	if err != nil {
+		return nil, err
+	}
+	opts := wu.options
+	opts.Logger = logger.FromContext(r.Context())
+	return NewConn(conn, protocol, opts), nil
+}
+
+// QueueStats reports outbound queues of all connections established by the upgrader.
+func (wu *websocketUpgrader) QueueStats() QueueStats {
+	return wu.options.Queue.Metrics.Stats()
 }
 
 func NewUpgrader(cg *config.ServerConfig) UpgraderHelper {
@@ -33,5 +47,16 @@ func NewUpgrader(cg *config.ServerConfig) UpgraderHelper {
 				return true
 			},
 		},
+		options: ConnOptions{
+			Queue: QueueOptions{
+				Size:    cg.WsSendQueueSize,
+				Policy:  cg.WsSendQueuePolicy,
+				Metrics: NewQueueMetrics(cg.WsSendQueueSize),
+			},
+			PingPeriod:     cg.WsPingPeriod,
+			PongWait:       cg.WsPongWait,
+			WriteWait:      cg.WsWriteWait,
+			MaxMessageSize: cg.WsMaxMessageSize,
+		},
 	}
 }
@@ -2,24 +2,56 @@ package main
 
 import (
 	"context"
-	"time"
+	"errors"
+	"flag"
+	"os"
 
 	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/logging"
 	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/pkg/logger"
 )
 
 func main() {
-	serverConfig := config.GetServerConfig()
-	ctx, cancel := context.WithTimeout(
-		context.Background(),
-		time.Duration(serverConfig.DbConnectionTimeoutInSeconds),
-	)
+	serverConfig, cmd, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		logger.Default().Error("Unable to load config", "err", err)
+		os.Exit(2)
+	}
+	if cmd.PrintConfig {
+		if err = config.Print(os.Stdout, serverConfig); err != nil {
+			logger.Default().Error("Unable to print config", "err", err)
+			os.Exit(1)
+		}
+		return
+	}
+	lg := logging.NewLogger(serverConfig)
+	logger.SetDefault(lg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), serverConfig.DbConnectionTimeout)
 	defer cancel()
 	db, err := mongo.NewClient(serverConfig)
 	db.Connect(ctx)
 	if err != nil {
 		panic(err)
 	}
-	defer db.Disconnect(ctx)
-	NewServer(db).Run()
+	repositories.MigrateMessages(
+		logger.NewContext(context.Background(), lg),
+		serverConfig,
+		mongo.NewMessagesCollection(db, serverConfig),
+		mongo.NewDeliveriesCollection(db, serverConfig),
+	)
+	if err = NewServer(db, serverConfig, lg).Run(); err != nil {
+		lg.Error("Server stopped", "err", err)
+	}
+
+	disconnectCtx, cancelDisconnect := context.WithTimeout(context.Background(), serverConfig.ShutdownTimeout)
+	defer cancelDisconnect()
+	if err = db.Disconnect(disconnectCtx); err != nil {
+		lg.Error("Unable to disconnect from database", "err", err)
+	}
 }
@@ -57,3 +57,17 @@ func (_m *ClientHelper) Disconnect(_a0 context.Context) error {
 
 	return r0
 }
+
+// Ping provides a mock function with given fields: _a0
+func (_m *ClientHelper) Ping(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
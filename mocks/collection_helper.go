@@ -7,6 +7,7 @@ import (
 
 	mongo "github.com/andriystech/lgc/facilities/mongo"
 	mock "github.com/stretchr/testify/mock"
+	options "go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CollectionHelper is an autogenerated mock type for the CollectionHelper type
@@ -14,13 +15,62 @@ type CollectionHelper struct {
 	mock.Mock
 }
 
-// Find provides a mock function with given fields: _a0, _a1
-func (_m *CollectionHelper) Find(_a0 context.Context, _a1 interface{}) (mongo.MultiResultHelper, error) {
+// CreateIndex provides a mock function with given fields: _a0, _a1
+func (_m *CollectionHelper) CreateIndex(_a0 context.Context, _a1 mongo.IndexModel) (string, error) {
 	ret := _m.Called(_a0, _a1)
 
-	var r0 mongo.MultiResultHelper
-	if rf, ok := ret.Get(0).(func(context.Context, interface{}) mongo.MultiResultHelper); ok {
+	var r0 string
+	if rf, ok := ret.Get(0).(func(context.Context, mongo.IndexModel) string); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, mongo.IndexModel) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// DeleteMany provides a mock function with given fields: _a0, _a1
+func (_m *CollectionHelper) DeleteMany(_a0 context.Context, _a1 interface{}) (int64, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 int64
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) int64); ok {
 		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(int64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Find provides a mock function with given fields: _a0, _a1, opts
+func (_m *CollectionHelper) Find(_a0 context.Context, _a1 interface{}, opts ...*options.FindOptions) (mongo.MultiResultHelper, error) {
+	_va := make([]interface{}, len(opts))
+	for _i := range opts {
+		_va[_i] = opts[_i]
+	}
+	var _ca []interface{}
+	_ca = append(_ca, _a0, _a1)
+	_ca = append(_ca, _va...)
+	ret := _m.Called(_ca...)
+
+	var r0 mongo.MultiResultHelper
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, ...*options.FindOptions) mongo.MultiResultHelper); ok {
+		r0 = rf(_a0, _a1, opts...)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(mongo.MultiResultHelper)
@@ -28,8 +78,8 @@ func (_m *CollectionHelper) Find(_a0 context.Context, _a1 interface{}) (mongo.Mu
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
-		r1 = rf(_a0, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, ...*options.FindOptions) error); ok {
+		r1 = rf(_a0, _a1, opts...)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -53,6 +103,45 @@ func (_m *CollectionHelper) FindOne(_a0 context.Context, _a1 interface{}) mongo.
 	return r0
 }
 
+// FindOneAndDelete provides a mock function with given fields: _a0, _a1
+func (_m *CollectionHelper) FindOneAndDelete(_a0 context.Context, _a1 interface{}) mongo.SingleResultHelper {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 mongo.SingleResultHelper
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) mongo.SingleResultHelper); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(mongo.SingleResultHelper)
+		}
+	}
+
+	return r0
+}
+
+// InsertMany provides a mock function with given fields: _a0, _a1
+func (_m *CollectionHelper) InsertMany(_a0 context.Context, _a1 []interface{}) ([]interface{}, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []interface{}
+	if rf, ok := ret.Get(0).(func(context.Context, []interface{}) []interface{}); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]interface{})
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, []interface{}) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // InsertOne provides a mock function with given fields: _a0, _a1
 func (_m *CollectionHelper) InsertOne(_a0 context.Context, _a1 interface{}) (interface{}, error) {
 	ret := _m.Called(_a0, _a1)
@@ -75,3 +164,72 @@ func (_m *CollectionHelper) InsertOne(_a0 context.Context, _a1 interface{}) (int
 
 	return r0, r1
 }
+
+// UpdateMany provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CollectionHelper) UpdateMany(_a0 context.Context, _a1 interface{}, _a2 interface{}) (*mongo.UpdateResult, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *mongo.UpdateResult
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) *mongo.UpdateResult); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mongo.UpdateResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpdateOne provides a mock function with given fields: _a0, _a1, _a2
+func (_m *CollectionHelper) UpdateOne(_a0 context.Context, _a1 interface{}, _a2 interface{}) (*mongo.UpdateResult, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *mongo.UpdateResult
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}, interface{}) *mongo.UpdateResult); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mongo.UpdateResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}, interface{}) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// Watch provides a mock function with given fields: _a0, _a1
+func (_m *CollectionHelper) Watch(_a0 context.Context, _a1 interface{}) (mongo.ChangeStreamHelper, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 mongo.ChangeStreamHelper
+	if rf, ok := ret.Get(0).(func(context.Context, interface{}) mongo.ChangeStreamHelper); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(mongo.ChangeStreamHelper)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, interface{}) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -2,7 +2,12 @@
 
 package mocks
 
-import mock "github.com/stretchr/testify/mock"
+import (
+	context "context"
+
+	ws "github.com/andriystech/lgc/facilities/ws"
+	mock "github.com/stretchr/testify/mock"
+)
 
 // ConnHelper is an autogenerated mock type for the ConnHelper type
 type ConnHelper struct {
@@ -10,8 +15,89 @@ type ConnHelper struct {
 }
 
 // Close provides a mock function with given fields:
-func (_m *ConnHelper) Close() {
-	_m.Called()
+func (_m *ConnHelper) Close() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// CloseWithReason provides a mock function with given fields: _a0, _a1
+func (_m *ConnHelper) CloseWithReason(_a0 int, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Done provides a mock function with given fields:
+func (_m *ConnHelper) Done() <-chan struct{} {
+	ret := _m.Called()
+
+	var r0 <-chan struct{}
+	if rf, ok := ret.Get(0).(func() <-chan struct{}); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(<-chan struct{})
+		}
+	}
+
+	return r0
+}
+
+// Flush provides a mock function with given fields: _a0
+func (_m *ConnHelper) Flush(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Protocol provides a mock function with given fields:
+func (_m *ConnHelper) Protocol() ws.Protocol {
+	ret := _m.Called()
+
+	var r0 ws.Protocol
+	if rf, ok := ret.Get(0).(func() ws.Protocol); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(ws.Protocol)
+	}
+
+	return r0
+}
+
+// QueueDepth provides a mock function with given fields:
+func (_m *ConnHelper) QueueDepth() int {
+	ret := _m.Called()
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func() int); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
 }
 
 // ReadMessage provides a mock function with given fields:
@@ -5,10 +5,9 @@ package mocks
 import (
 	context "context"
 
+	ws "github.com/andriystech/lgc/facilities/ws"
 	models "github.com/andriystech/lgc/models"
 	mock "github.com/stretchr/testify/mock"
-
-	ws "github.com/andriystech/lgc/facilities/ws"
 )
 
 // ConnectionsRepository is an autogenerated mock type for the ConnectionsRepository type
@@ -16,13 +15,13 @@ type ConnectionsRepository struct {
 	mock.Mock
 }
 
-// AddConnection provides a mock function with given fields: _a0, _a1, _a2, _a3
-func (_m *ConnectionsRepository) AddConnection(_a0 context.Context, _a1 string, _a2 ws.ConnHelper, _a3 *models.User) error {
-	ret := _m.Called(_a0, _a1, _a2, _a3)
+// AddConnection provides a mock function with given fields: _a0, _a1, _a2
+func (_m *ConnectionsRepository) AddConnection(_a0 context.Context, _a1 *models.Session, _a2 ws.ConnHelper) error {
+	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, ws.ConnHelper, *models.User) error); ok {
-		r0 = rf(_a0, _a1, _a2, _a3)
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Session, ws.ConnHelper) error); ok {
+		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -89,15 +88,15 @@ func (_m *ConnectionsRepository) DeleteConnection(_a0 context.Context, _a1 strin
 }
 
 // GetAllConnections provides a mock function with given fields: _a0
-func (_m *ConnectionsRepository) GetAllConnections(_a0 context.Context) (map[string]ws.ConnHelper, error) {
+func (_m *ConnectionsRepository) GetAllConnections(_a0 context.Context) (map[string][]ws.ConnHelper, error) {
 	ret := _m.Called(_a0)
 
-	var r0 map[string]ws.ConnHelper
-	if rf, ok := ret.Get(0).(func(context.Context) map[string]ws.ConnHelper); ok {
+	var r0 map[string][]ws.ConnHelper
+	if rf, ok := ret.Get(0).(func(context.Context) map[string][]ws.ConnHelper); ok {
 		r0 = rf(_a0)
 	} else {
 		if ret.Get(0) != nil {
-			r0 = ret.Get(0).(map[string]ws.ConnHelper)
+			r0 = ret.Get(0).(map[string][]ws.ConnHelper)
 		}
 	}
 
@@ -110,3 +109,49 @@ func (_m *ConnectionsRepository) GetAllConnections(_a0 context.Context) (map[str
 
 	return r0, r1
 }
+
+// GetConnection provides a mock function with given fields: _a0, _a1
+func (_m *ConnectionsRepository) GetConnection(_a0 context.Context, _a1 string) (ws.ConnHelper, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 ws.ConnHelper
+	if rf, ok := ret.Get(0).(func(context.Context, string) ws.ConnHelper); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(ws.ConnHelper)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetUserConnections provides a mock function with given fields: _a0, _a1
+func (_m *ConnectionsRepository) GetUserConnections(_a0 context.Context, _a1 string) ([]ws.ConnHelper, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []ws.ConnHelper
+	if rf, ok := ret.Get(0).(func(context.Context, string) []ws.ConnHelper); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]ws.ConnHelper)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
@@ -14,8 +14,114 @@ type MessagesRepository struct {
 	mock.Mock
 }
 
-// FindUserMessages provides a mock function with given fields: _a0, _a1
-func (_m *MessagesRepository) FindUserMessages(_a0 context.Context, _a1 string) ([]*models.Message, error) {
+// DeleteRecipientDeliveries provides a mock function with given fields: _a0, _a1
+func (_m *MessagesRepository) DeleteRecipientDeliveries(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindDirectMessages provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) FindDirectMessages(_a0 context.Context, _a1 string, _a2 string) ([]*models.Message, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*models.Message); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindMessage provides a mock function with given fields: _a0, _a1
+func (_m *MessagesRepository) FindMessage(_a0 context.Context, _a1 string) (*models.Message, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.Message); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindMessageReceipts provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) FindMessageReceipts(_a0 context.Context, _a1 string, _a2 string) ([]*models.Message, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) []*models.Message); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindMessages provides a mock function with given fields: _a0, _a1
+func (_m *MessagesRepository) FindMessages(_a0 context.Context, _a1 *models.MessagesQuery) ([]*models.Message, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, *models.MessagesQuery) []*models.Message); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.MessagesQuery) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FindPendingUpdates provides a mock function with given fields: _a0, _a1
+func (_m *MessagesRepository) FindPendingUpdates(_a0 context.Context, _a1 string) ([]*models.Message, error) {
 	ret := _m.Called(_a0, _a1)
 
 	var r0 []*models.Message
@@ -37,6 +143,103 @@ func (_m *MessagesRepository) FindUserMessages(_a0 context.Context, _a1 string)
 	return r0, r1
 }
 
+// FindUndeliveredMessages provides a mock function with given fields: _a0, _a1
+func (_m *MessagesRepository) FindUndeliveredMessages(_a0 context.Context, _a1 string) ([]*models.Message, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string) []*models.Message); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkMessageDelivered provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) MarkMessageDelivered(_a0 context.Context, _a1 string, _a2 int64) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int64) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// MarkMessageRead provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *MessagesRepository) MarkMessageRead(_a0 context.Context, _a1 string, _a2 string, _a3 int64) (*models.Message, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) *models.Message); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, int64) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// MarkUpdateDelivered provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) MarkUpdateDelivered(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveDeliveries provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) SaveDeliveries(_a0 context.Context, _a1 *models.Message, _a2 []string) ([]*models.Message, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 []*models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Message, []string) []*models.Message); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.Message, []string) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // SaveMessage provides a mock function with given fields: _a0, _a1
 func (_m *MessagesRepository) SaveMessage(_a0 context.Context, _a1 *models.Message) (string, error) {
 	ret := _m.Called(_a0, _a1)
@@ -57,3 +260,17 @@ func (_m *MessagesRepository) SaveMessage(_a0 context.Context, _a1 *models.Messa
 
 	return r0, r1
 }
+
+// UpdateMessageContent provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MessagesRepository) UpdateMessageContent(_a0 context.Context, _a1 *models.Message, _a2 int) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Message, int) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -37,6 +37,29 @@ func (_m *TokenService) GenerateToken(_a0 context.Context, _a1 *models.User) (*m
 	return r0, r1
 }
 
+// GetUserByAccessToken provides a mock function with given fields: _a0, _a1
+func (_m *TokenService) GetUserByAccessToken(_a0 context.Context, _a1 string) (*models.User, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserByToken provides a mock function with given fields: _a0, _a1
 func (_m *TokenService) GetUserByToken(_a0 context.Context, _a1 string) (*models.User, error) {
 	ret := _m.Called(_a0, _a1)
@@ -59,3 +82,63 @@ func (_m *TokenService) GetUserByToken(_a0 context.Context, _a1 string) (*models
 
 	return r0, r1
 }
+
+// IssueTokens provides a mock function with given fields: _a0, _a1
+func (_m *TokenService) IssueTokens(_a0 context.Context, _a1 *models.User) (*models.TokenPair, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.TokenPair
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) *models.TokenPair); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TokenPair)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RefreshTokens provides a mock function with given fields: _a0, _a1
+func (_m *TokenService) RefreshTokens(_a0 context.Context, _a1 string) (*models.TokenPair, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.TokenPair
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.TokenPair); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.TokenPair)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RevokeRefreshToken provides a mock function with given fields: _a0, _a1
+func (_m *TokenService) RevokeRefreshToken(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -14,6 +14,27 @@ type TokensRepository struct {
 	mock.Mock
 }
 
+// DeleteExpiredTokens provides a mock function with given fields: _a0
+func (_m *TokensRepository) DeleteExpiredTokens(_a0 context.Context) (int, error) {
+	ret := _m.Called(_a0)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func(context.Context) int); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUserByToken provides a mock function with given fields: _a0, _a1
 func (_m *TokensRepository) GetUserByToken(_a0 context.Context, _a1 string) (*models.User, error) {
 	ret := _m.Called(_a0, _a1)
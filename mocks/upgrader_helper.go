@@ -14,6 +14,20 @@ type UpgraderHelper struct {
 	mock.Mock
 }
 
+// QueueStats provides a mock function with given fields:
+func (_m *UpgraderHelper) QueueStats() ws.QueueStats {
+	ret := _m.Called()
+
+	var r0 ws.QueueStats
+	if rf, ok := ret.Get(0).(func() ws.QueueStats); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(ws.QueueStats)
+	}
+
+	return r0
+}
+
 // Upgrade provides a mock function with given fields: _a0, _a1
 func (_m *UpgraderHelper) Upgrade(_a0 http.ResponseWriter, _a1 *http.Request) (ws.ConnHelper, error) {
 	ret := _m.Called(_a0, _a1)
@@ -14,6 +14,80 @@ type UserService struct {
 	mock.Mock
 }
 
+// ChangePassword provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *UserService) ChangePassword(_a0 context.Context, _a1 string, _a2 string, _a3 string) (*models.User, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, string) *models.User); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CheckPassword provides a mock function with given fields: _a0, _a1
+func (_m *UserService) CheckPassword(_a0 *models.User, _a1 string) bool {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*models.User, string) bool); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: _a0, _a1, _a2
+func (_m *UserService) DeleteUser(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindUserById provides a mock function with given fields: _a0, _a1
+func (_m *UserService) FindUserById(_a0 context.Context, _a1 string) (*models.User, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindUserByName provides a mock function with given fields: _a0, _a1
 func (_m *UserService) FindUserByName(_a0 context.Context, _a1 string) (*models.User, error) {
 	ret := _m.Called(_a0, _a1)
@@ -80,3 +154,40 @@ func (_m *UserService) SaveUser(_a0 context.Context, _a1 *models.User) (string,
 
 	return r0, r1
 }
+
+// UpdateProfile provides a mock function with given fields: _a0, _a1, _a2
+func (_m *UserService) UpdateProfile(_a0 context.Context, _a1 string, _a2 *models.ProfileUpdate) (*models.User, error) {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ProfileUpdate) *models.User); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string, *models.ProfileUpdate) error); ok {
+		r1 = rf(_a0, _a1, _a2)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// UpgradePasswordHash provides a mock function with given fields: _a0, _a1, _a2
+func (_m *UserService) UpgradePasswordHash(_a0 context.Context, _a1 *models.User, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -14,6 +14,57 @@ type UsersRepository struct {
 	mock.Mock
 }
 
+// ChangeUserPassword provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *UsersRepository) ChangeUserPassword(_a0 context.Context, _a1 string, _a2 string, _a3 int64) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, int64) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteUser provides a mock function with given fields: _a0, _a1
+func (_m *UsersRepository) DeleteUser(_a0 context.Context, _a1 string) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// FindUserById provides a mock function with given fields: _a0, _a1
+func (_m *UsersRepository) FindUserById(_a0 context.Context, _a1 string) (*models.User, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 *models.User
+	if rf, ok := ret.Get(0).(func(context.Context, string) *models.User); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.User)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindUserByName provides a mock function with given fields: _a0, _a1
 func (_m *UsersRepository) FindUserByName(_a0 context.Context, _a1 string) (*models.User, error) {
 	ret := _m.Called(_a0, _a1)
@@ -80,3 +131,31 @@ func (_m *UsersRepository) SaveUser(_a0 context.Context, _a1 *models.User) (stri
 
 	return r0, r1
 }
+
+// UpdateUserPassword provides a mock function with given fields: _a0, _a1, _a2
+func (_m *UsersRepository) UpdateUserPassword(_a0 context.Context, _a1 string, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// UpdateUserProfile provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *UsersRepository) UpdateUserProfile(_a0 context.Context, _a1 string, _a2 *models.ProfileUpdate, _a3 int64) error {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *models.ProfileUpdate, int64) error); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
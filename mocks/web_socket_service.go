@@ -6,11 +6,9 @@ import (
 	context "context"
 	http "net/http"
 
-	mock "github.com/stretchr/testify/mock"
-
-	models "github.com/andriystech/lgc/models"
-
 	ws "github.com/andriystech/lgc/facilities/ws"
+	models "github.com/andriystech/lgc/models"
+	mock "github.com/stretchr/testify/mock"
 )
 
 // WebSocketService is an autogenerated mock type for the WebSocketService type
@@ -18,6 +16,57 @@ type WebSocketService struct {
 	mock.Mock
 }
 
+// CloseSession provides a mock function with given fields: _a0, _a1, _a2
+func (_m *WebSocketService) CloseSession(_a0 context.Context, _a1 *models.User, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// DeleteMessage provides a mock function with given fields: _a0, _a1, _a2
+func (_m *WebSocketService) DeleteMessage(_a0 context.Context, _a1 *models.User, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// EditMessage provides a mock function with given fields: _a0, _a1, _a2, _a3
+func (_m *WebSocketService) EditMessage(_a0 context.Context, _a1 *models.User, _a2 string, _a3 string) (*models.Message, error) {
+	ret := _m.Called(_a0, _a1, _a2, _a3)
+
+	var r0 *models.Message
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string, string) *models.Message); ok {
+		r0 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*models.Message)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User, string, string) error); ok {
+		r1 = rf(_a0, _a1, _a2, _a3)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetActiveConnectionsCount provides a mock function with given fields: _a0
 func (_m *WebSocketService) GetActiveConnectionsCount(_a0 context.Context) (int, error) {
 	ret := _m.Called(_a0)
@@ -62,6 +111,43 @@ func (_m *WebSocketService) GetActiveUsers(_a0 context.Context) ([]string, error
 	return r0, r1
 }
 
+// GetSendQueueStats provides a mock function with given fields: _a0
+func (_m *WebSocketService) GetSendQueueStats(_a0 context.Context) ws.QueueStats {
+	ret := _m.Called(_a0)
+
+	var r0 ws.QueueStats
+	if rf, ok := ret.Get(0).(func(context.Context) ws.QueueStats); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(ws.QueueStats)
+	}
+
+	return r0
+}
+
+// GetUserSessions provides a mock function with given fields: _a0, _a1
+func (_m *WebSocketService) GetUserSessions(_a0 context.Context, _a1 *models.User) ([]*models.Session, error) {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 []*models.Session
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) []*models.Session); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]*models.Session)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, *models.User) error); ok {
+		r1 = rf(_a0, _a1)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // LoadUserMessages provides a mock function with given fields: _a0, _a1, _a2
 func (_m *WebSocketService) LoadUserMessages(_a0 context.Context, _a1 *models.User, _a2 ws.ConnHelper) error {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -76,6 +162,20 @@ func (_m *WebSocketService) LoadUserMessages(_a0 context.Context, _a1 *models.Us
 	return r0
 }
 
+// MarkMessageRead provides a mock function with given fields: _a0, _a1, _a2
+func (_m *WebSocketService) MarkMessageRead(_a0 context.Context, _a1 *models.User, _a2 string) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // NewConnection provides a mock function with given fields: _a0, _a1, _a2
 func (_m *WebSocketService) NewConnection(_a0 http.ResponseWriter, _a1 *http.Request, _a2 *models.User) error {
 	ret := _m.Called(_a0, _a1, _a2)
@@ -90,12 +190,45 @@ func (_m *WebSocketService) NewConnection(_a0 http.ResponseWriter, _a1 *http.Req
 	return r0
 }
 
+// RemoveUser provides a mock function with given fields: _a0, _a1
+func (_m *WebSocketService) RemoveUser(_a0 context.Context, _a1 *models.User) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Run provides a mock function with given fields: _a0
+func (_m *WebSocketService) Run(_a0 context.Context) {
+	_m.Called(_a0)
+}
+
 // SaveUnreadMessages provides a mock function with given fields: _a0, _a1, _a2
-func (_m *WebSocketService) SaveUnreadMessages(_a0 context.Context, _a1 *models.User, _a2 string) error {
+func (_m *WebSocketService) SaveUnreadMessages(_a0 context.Context, _a1 *models.User, _a2 *models.Frame) error {
 	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, *models.User, string) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *models.User, *models.Frame) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SendDirectMessage provides a mock function with given fields: _a0, _a1, _a2
+func (_m *WebSocketService) SendDirectMessage(_a0 context.Context, _a1 *models.Frame, _a2 *models.User) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Frame, *models.User) error); ok {
 		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
@@ -105,11 +238,11 @@ func (_m *WebSocketService) SaveUnreadMessages(_a0 context.Context, _a1 *models.
 }
 
 // SendMessageToAllConnections provides a mock function with given fields: _a0, _a1, _a2
-func (_m *WebSocketService) SendMessageToAllConnections(_a0 context.Context, _a1 string, _a2 *models.User) error {
+func (_m *WebSocketService) SendMessageToAllConnections(_a0 context.Context, _a1 *models.Frame, _a2 *models.User) error {
 	ret := _m.Called(_a0, _a1, _a2)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, *models.User) error); ok {
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Frame, *models.User) error); ok {
 		r0 = rf(_a0, _a1, _a2)
 	} else {
 		r0 = ret.Error(0)
@@ -117,3 +250,31 @@ func (_m *WebSocketService) SendMessageToAllConnections(_a0 context.Context, _a1
 
 	return r0
 }
+
+// SendTypingIndicator provides a mock function with given fields: _a0, _a1, _a2
+func (_m *WebSocketService) SendTypingIndicator(_a0 context.Context, _a1 *models.Frame, _a2 *models.User) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *models.Frame, *models.User) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Shutdown provides a mock function with given fields: _a0
+func (_m *WebSocketService) Shutdown(_a0 context.Context) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -0,0 +1,110 @@
+package models
+
+const (
+	EventTypeBroadcast = "broadcast"
+	EventTypeDirect    = "direct"
+	EventTypeReceipt   = "receipt"
+	EventTypeKick      = "kick"
+	EventTypePresence  = "presence"
+	EventTypeTyping    = "typing"
+	EventTypeUpdate    = "update"
+)
+
+// Event is a chat event distributed between server instances by the broker.
+// Every instance handles the event for connections it holds.
+// Broadcast events carry inbound frame, room broadcasts are limited to RecipientIds.
+// Direct and receipt events carry the stored message, kick events carry id of the session to close.
+// Presence and typing events carry outbound frame which is written as is and never stored.
+// Update events carry edited or deleted message and ids of all its recipients.
+type Event struct {
+	Type         string   `bson:"type"`
+	SenderId     string   `bson:"senderId,omitempty"`
+	SenderName   string   `bson:"senderName,omitempty"`
+	RecipientIds []string `bson:"recipientIds,omitempty"`
+	Frame        *Frame   `bson:"frame,omitempty"`
+	Message      *Message `bson:"message,omitempty"`
+	SessionId    string   `bson:"sessionId,omitempty"`
+}
+
+// NewBroadcastEvent creates event of the message sent from the session, other sessions of the sender receive it as well.
+func NewBroadcastEvent(frame *Frame, sender *User, sessionId string, recipientIds []string) *Event {
+	return &Event{
+		Type:         EventTypeBroadcast,
+		SenderId:     sender.Id,
+		SenderName:   sender.UserName,
+		RecipientIds: recipientIds,
+		Frame:        frame,
+		SessionId:    sessionId,
+	}
+}
+
+func NewDirectEvent(msg *Message) *Event {
+	return &Event{
+		Type:    EventTypeDirect,
+		Message: msg,
+	}
+}
+
+func NewReceiptEvent(msg *Message) *Event {
+	return &Event{
+		Type:    EventTypeReceipt,
+		Message: msg,
+	}
+}
+
+func NewKickEvent(sessionId string) *Event {
+	return &Event{
+		Type:      EventTypeKick,
+		SessionId: sessionId,
+	}
+}
+
+func NewPresenceEvent(frame *Frame, usr *User) *Event {
+	return &Event{
+		Type:       EventTypePresence,
+		SenderId:   usr.Id,
+		SenderName: usr.UserName,
+		Frame:      frame,
+	}
+}
+
+func NewTypingEvent(frame *Frame, sender *User, recipientIds []string) *Event {
+	return &Event{
+		Type:         EventTypeTyping,
+		SenderId:     sender.Id,
+		SenderName:   sender.UserName,
+		RecipientIds: recipientIds,
+		Frame:        frame,
+	}
+}
+
+func NewUpdateEvent(msg *Message, recipientIds []string) *Event {
+	return &Event{
+		Type:         EventTypeUpdate,
+		SenderId:     msg.SenderId,
+		SenderName:   msg.SenderName,
+		RecipientIds: recipientIds,
+		Message:      msg,
+	}
+}
+
+// Sender returns public data of the user who published the broadcast.
+func (e *Event) Sender() *User {
+	return &User{Id: e.SenderId, UserName: e.SenderName}
+}
+
+// IsRecipient reports whether the user should receive the broadcast.
+func (e *Event) IsRecipient(userId string) bool {
+	if e.Frame != nil && e.Frame.RecipientId != "" {
+		return e.Frame.RecipientId == userId
+	}
+	if e.Frame == nil || e.Frame.RoomId == "" {
+		return true
+	}
+	for _, id := range e.RecipientIds {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
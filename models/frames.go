@@ -0,0 +1,146 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// FrameVersion is a version of the web socket envelope format supported by the server.
+const FrameVersion = 1
+
+const FramePayloadMaxLength = 4096
+
+const (
+	FrameTypeMessage  = "message"
+	FrameTypeAck      = "ack"
+	FrameTypeError    = "error"
+	FrameTypeRead     = "read"
+	FrameTypeReceipt  = "receipt"
+	FrameTypePresence = "presence"
+	FrameTypeTyping   = "typing"
+	FrameTypeEdit     = "edit"
+	FrameTypeDelete   = "delete"
+)
+
+// Statuses carried by presence frames.
+const (
+	PresenceJoined = "joined"
+	PresenceLeft   = "left"
+)
+
+// Frame is a JSON envelope exchanged over the web socket in both directions.
+// Inbound message frames target a room (RoomId), a single user (RecipientId) or
+// everyone when both are empty. Outbound frames always carry sender and timestamp.
+// Inbound read frames carry id of the message which was read by the client.
+// Presence and typing frames are ephemeral, they are never stored.
+// Edit and delete frames change the message with the given id in both directions.
+// Error frames rejecting inbound frames over the rate limit carry seconds to wait in RetryAfter.
+type Frame struct {
+	Version     int    `json:"v"`
+	Type        string `json:"type"`
+	Id          string `json:"id,omitempty"`
+	ReplyTo     string `json:"replyTo,omitempty"`
+	SenderId    string `json:"senderId,omitempty"`
+	SenderName  string `json:"senderName,omitempty"`
+	RoomId      string `json:"roomId,omitempty"`
+	RecipientId string `json:"recipientId,omitempty"`
+	Time        int64  `json:"time,omitempty"`
+	EditedAt    int64  `json:"editedAt,omitempty"`
+	Payload     string `json:"payload,omitempty"`
+	RetryAfter  int64  `json:"retryAfter,omitempty"`
+}
+
+func NewMessageFrame(msg *Message) *Frame {
+	frame := &Frame{
+		Version:    FrameVersion,
+		Type:       FrameTypeMessage,
+		Id:         msg.LogicalId(),
+		SenderId:   msg.SenderId,
+		SenderName: msg.SenderName,
+		RoomId:     msg.RoomId,
+		Time:       msg.Time,
+		EditedAt:   msg.EditedAt,
+		Payload:    msg.Payload,
+	}
+	if msg.Direct {
+		frame.RecipientId = msg.RecipientId
+	}
+	return frame
+}
+
+// NewUpdateFrame notifies clients that the message was edited or deleted.
+// Delete frame carries deletion time instead of the payload.
+func NewUpdateFrame(msg *Message) *Frame {
+	frame := NewMessageFrame(msg)
+	if msg.IsDeleted() {
+		frame.Type = FrameTypeDelete
+		frame.Time = msg.DeletedAt
+		frame.EditedAt = 0
+		frame.Payload = ""
+		return frame
+	}
+	frame.Type = FrameTypeEdit
+	return frame
+}
+
+func NewAckFrame(id, replyTo string) *Frame {
+	return &Frame{
+		Version: FrameVersion,
+		Type:    FrameTypeAck,
+		Id:      id,
+		ReplyTo: replyTo,
+	}
+}
+
+func NewErrorFrame(replyTo, reason string) *Frame {
+	return &Frame{
+		Version: FrameVersion,
+		Type:    FrameTypeError,
+		ReplyTo: replyTo,
+		Payload: reason,
+	}
+}
+
+// NewRateLimitFrame rejects the inbound frame telling the client how many seconds to wait.
+func NewRateLimitFrame(replyTo, reason string, retryAfter time.Duration) *Frame {
+	frame := NewErrorFrame(replyTo, reason)
+	frame.RetryAfter = int64(math.Ceil(retryAfter.Seconds()))
+	return frame
+}
+
+// NewReceiptFrame notifies sender that the recipient has read the message.
+func NewReceiptFrame(msg *Message) *Frame {
+	return &Frame{
+		Version:     FrameVersion,
+		Type:        FrameTypeReceipt,
+		Id:          msg.LogicalId(),
+		RoomId:      msg.RoomId,
+		RecipientId: msg.RecipientId,
+		Time:        msg.ReadAt,
+	}
+}
+
+// NewPresenceFrame notifies clients that the user came online or went offline.
+func NewPresenceFrame(usr *User, status string, time int64) *Frame {
+	return &Frame{
+		Version:    FrameVersion,
+		Type:       FrameTypePresence,
+		SenderId:   usr.Id,
+		SenderName: usr.UserName,
+		Time:       time,
+		Payload:    status,
+	}
+}
+
+// NewTypingFrame notifies target of the inbound typing frame that the sender is typing.
+func NewTypingFrame(frame *Frame, sender *User, time int64) *Frame {
+	return &Frame{
+		Version:     FrameVersion,
+		Type:        FrameTypeTyping,
+		SenderId:    sender.Id,
+		SenderName:  sender.UserName,
+		RoomId:      frame.RoomId,
+		RecipientId: frame.RecipientId,
+		Time:        time,
+	}
+}
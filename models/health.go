@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Health statuses of the server and its dependencies.
+const (
+	HealthStatusOk           = "ok"
+	HealthStatusFail         = "fail"
+	HealthStatusShuttingDown = "shutting_down"
+)
+
+// DependencyHealth is a result of checking a single dependency, e.g. the database.
+type DependencyHealth struct {
+	Status  string
+	Latency time.Duration
+	Error   string
+}
+
+// Health is an overall status of the server with results of its dependency checks.
+type Health struct {
+	Status       string
+	Dependencies map[string]*DependencyHealth
+}
+
+func (h *Health) IsOk() bool {
+	return h.Status == HealthStatusOk
+}
@@ -2,22 +2,89 @@ package models
 
 import "time"
 
+// MessageRevision is a payload which the message had before it was edited.
+type MessageRevision struct {
+	Payload string `bson:"payload"`
+	Time    int64  `bson:"time"`
+}
+
+// Message is either the message shared by all recipients or recipient's copy of it
+// which has its own id and delivery state and refers to the shared one by origin id. Deleted message is kept as a tombstone
+// without payload and history. UpdatePending marks delivered copies whose recipient
+// has not been notified about the last edit or deletion yet.
 type Message struct {
-	Id          string `bson:"_id"`
-	RecipientId string `bson:"recipientId"`
-	SenderId    string `bson:"senderId"`
-	SenderName  string `bson:"senderName"`
-	Payload     string `bson:"payload"`
-	Time        int64  `bson:"time"`
+	Id            string             `bson:"_id"`
+	OriginId      string             `bson:"originId,omitempty"`
+	RecipientId   string             `bson:"recipientId"`
+	SenderId      string             `bson:"senderId"`
+	SenderName    string             `bson:"senderName"`
+	RoomId        string             `bson:"roomId,omitempty"`
+	Direct        bool               `bson:"direct,omitempty"`
+	Payload       string             `bson:"payload"`
+	Time          int64              `bson:"time"`
+	DeliveredAt   int64              `bson:"deliveredAt,omitempty"`
+	ReadAt        int64              `bson:"readAt,omitempty"`
+	EditedAt      int64              `bson:"editedAt,omitempty"`
+	History       []*MessageRevision `bson:"history,omitempty"`
+	DeletedAt     int64              `bson:"deletedAt,omitempty"`
+	UpdatePending bool               `bson:"updatePending,omitempty"`
 }
 
-func NewMessage(id, sId, sName, rId, payload string) *Message {
+func NewMessage(id, sId, sName, rId, roomId, payload string) *Message {
 	return &Message{
 		Id:          id,
 		RecipientId: rId,
 		SenderId:    sId,
 		SenderName:  sName,
+		RoomId:      roomId,
 		Payload:     payload,
 		Time:        time.Now().Unix(),
 	}
 }
+
+// NewSharedMessage creates the message received in the frame which is stored once for all recipients.
+func NewSharedMessage(frame *Frame, sender *User) *Message {
+	msg := NewMessage(frame.Id, sender.Id, sender.UserName, "", frame.RoomId, frame.Payload)
+	msg.Direct = frame.RecipientId != ""
+	if frame.Time != 0 {
+		msg.Time = frame.Time
+	}
+	return msg
+}
+
+// LogicalId returns id shared by all recipients' copies of the message.
+// Messages stored before copies were linked by origin id fall back to their own id.
+func (m *Message) LogicalId() string {
+	if m.OriginId != "" {
+		return m.OriginId
+	}
+	return m.Id
+}
+
+// Edit replaces payload of the message keeping the previous one in the history.
+func (m *Message) Edit(payload string, at int64) {
+	since := m.Time
+	if m.EditedAt != 0 {
+		since = m.EditedAt
+	}
+	m.History = append(m.History, &MessageRevision{Payload: m.Payload, Time: since})
+	m.Payload = payload
+	m.EditedAt = at
+}
+
+// Delete turns the message into a tombstone.
+func (m *Message) Delete(at int64) {
+	m.Payload = ""
+	m.History = nil
+	m.DeletedAt = at
+}
+
+// Revision counts edits of the message, a change is stored only while the message keeps
+// the revision it was loaded with.
+func (m *Message) Revision() int {
+	return len(m.History)
+}
+
+func (m *Message) IsDeleted() bool {
+	return m.DeletedAt != 0
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const MessagesPageDefaultLimit = 50
+const MessagesPageMaxLimit = 100
+
+var ErrInvalidMessageCursor = errors.New("message cursor is malformed")
+
+// MessageCursor points at the last message of a page, next page starts right after it.
+type MessageCursor struct {
+	Time int64
+	Id   string
+}
+
+func NewMessageCursor(msg *Message) *MessageCursor {
+	return &MessageCursor{Time: msg.Time, Id: msg.Id}
+}
+
+// Encode returns opaque cursor representation which is safe to pass in query string.
+func (c *MessageCursor) Encode() string {
+	raw := strconv.FormatInt(c.Time, 10) + ":" + c.Id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeMessageCursor(s string) (*MessageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidMessageCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 || len(parts[1]) == 0 {
+		return nil, ErrInvalidMessageCursor
+	}
+	t, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidMessageCursor
+	}
+	return &MessageCursor{Time: t, Id: parts[1]}, nil
+}
+
+// MessagesQuery describes a page of recipient's messages ordered from the newest to the oldest.
+// Zero From and To mean the time range is not limited from that side.
+type MessagesQuery struct {
+	RecipientId string
+	SenderId    string
+	From        int64
+	To          int64
+	Limit       int
+	After       *MessageCursor
+}
+
+type MessagesPage struct {
+	Messages   []*Message
+	NextCursor *MessageCursor
+}
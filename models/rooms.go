@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+const RoomNameMinLength = 3
+
+type Room struct {
+	Id        string   `bson:"_id"`
+	Name      string   `bson:"name"`
+	OwnerId   string   `bson:"ownerId"`
+	Members   []string `bson:"members"`
+	CreatedAt int64    `bson:"createdAt"`
+}
+
+func NewRoom(id, name, ownerId string) *Room {
+	return &Room{
+		Id:        id,
+		Name:      name,
+		OwnerId:   ownerId,
+		Members:   []string{ownerId},
+		CreatedAt: time.Now().Unix(),
+	}
+}
+
+func (r *Room) HasMember(userId string) bool {
+	for _, id := range r.Members {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
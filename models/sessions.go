@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Session describes a single web socket connection of the user,
+// a user connected from several devices or tabs has several sessions.
+type Session struct {
+	Id          string `bson:"_id"`
+	UserId      string `bson:"userId"`
+	UserName    string `bson:"userName"`
+	UserAgent   string `bson:"userAgent,omitempty"`
+	RemoteAddr  string `bson:"remoteAddr,omitempty"`
+	ConnectedAt int64  `bson:"connectedAt"`
+}
+
+func NewSession(id string, usr *User, userAgent, remoteAddr string) *Session {
+	return &Session{
+		Id:          id,
+		UserId:      usr.Id,
+		UserName:    usr.UserName,
+		UserAgent:   userAgent,
+		RemoteAddr:  remoteAddr,
+		ConnectedAt: time.Now().Unix(),
+	}
+}
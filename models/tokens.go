@@ -9,3 +9,12 @@ func NewToken(token string) *Token {
 		Payload: token,
 	}
 }
+
+// TokenPair is a short-lived access token for authenticated requests together with
+// a long-lived refresh token exchangeable for a new pair.
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	// ExpiresIn is lifetime of the access token in seconds.
+	ExpiresIn int
+}
@@ -1,19 +1,49 @@
 package models
 
+import "time"
+
 const NameMinLength = 3
 
 const PasswordMinLength = 6
 
+const DisplayNameMaxLength = 64
+
+const StatusTextMaxLength = 140
+
+const AvatarUrlMaxLength = 2048
+
+// User keeps unix time of the last password change, tokens issued before it are not accepted.
 type User struct {
-	Id       string `bson:"_id"`
-	UserName string `bson:"userName"`
-	Password string `bson:"password"`
+	Id                string `bson:"_id"`
+	UserName          string `bson:"userName"`
+	Password          string `bson:"password"`
+	DisplayName       string `bson:"displayName,omitempty"`
+	AvatarUrl         string `bson:"avatarUrl,omitempty"`
+	StatusText        string `bson:"statusText,omitempty"`
+	CreatedAt         int64  `bson:"createdAt,omitempty"`
+	UpdatedAt         int64  `bson:"updatedAt,omitempty"`
+	PasswordChangedAt int64  `bson:"passwordChangedAt,omitempty"`
 }
 
 func NewUser(id, name, password string) *User {
+	now := time.Now().Unix()
 	return &User{
-		Id:       id,
-		UserName: name,
-		Password: password,
+		Id:        id,
+		UserName:  name,
+		Password:  password,
+		CreatedAt: now,
+		UpdatedAt: now,
 	}
 }
+
+// ProfileUpdate changes only provided fields of a profile, empty value clears the field.
+type ProfileUpdate struct {
+	DisplayName *string
+	AvatarUrl   *string
+	StatusText  *string
+}
+
+// IsEmpty reports whether the update does not change any field.
+func (pu *ProfileUpdate) IsEmpty() bool {
+	return pu.DisplayName == nil && pu.AvatarUrl == nil && pu.StatusText == nil
+}
@@ -1,29 +1,242 @@
 // Package hasher wraps stl package crypto by providing ability to implement specific
 // cryptographic operations (creating hashes for passwords etc).
+//
+// Password hashes are produced by a salted key derivation function (argon2id, scrypt
+// or bcrypt) and stored as self-describing PHC-style strings, so parameters of each
+// hash can be tuned without breaking already stored ones. Unsalted sha512 hashes
+// produced by previous versions are still accepted by CheckPasswordHash and reported
+// by NeedsRehash so they can be upgraded on the next successful login.
 package hasher
 
 import (
+	"crypto/rand"
 	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
 )
 
-// HashPassword returns sha512 hash string for provided password
-func HashPassword(password string) (string, error) {
-	hash := sha512.New()
-	if _, err := hash.Write([]byte(password)); err != nil {
-		return "", err
+// Algorithm is a key derivation function used for hashing passwords.
+type Algorithm string
+
+// Supported password hashing algorithms.
+const (
+	Argon2id Algorithm = "argon2id"
+	Bcrypt   Algorithm = "bcrypt"
+	Scrypt   Algorithm = "scrypt"
+)
+
+// legacySHA512 identifies unsalted hex encoded sha512 hashes, they can be checked
+// but are never produced.
+const legacySHA512 Algorithm = "sha512"
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported password hashing algorithm")
+var ErrMalformedHash = errors.New("malformed password hash")
+
+// Options holds the algorithm used for new hashes together with its cost parameters.
+type Options struct {
+	Algorithm Algorithm
+	// BcryptCost is a base-2 logarithm of bcrypt iterations count.
+	BcryptCost int
+	// Argon2Time is a number of passes over argon2id memory.
+	Argon2Time uint32
+	// Argon2Memory is a size of argon2id memory in KiB.
+	Argon2Memory uint32
+	// Argon2Threads is a degree of argon2id parallelism.
+	Argon2Threads uint8
+	// ScryptLogN is a base-2 logarithm of scrypt CPU/memory cost.
+	ScryptLogN uint8
+	ScryptR    int
+	ScryptP    int
+	// SaltLength and KeyLength are sizes in bytes of a random salt and derived key
+	// for argon2id and scrypt, bcrypt uses fixed sizes.
+	SaltLength int
+	KeyLength  int
+}
+
+// DefaultOptions follow OWASP recommendations for interactive logins.
+var DefaultOptions = Options{
+	Algorithm:     Argon2id,
+	BcryptCost:    bcrypt.DefaultCost,
+	Argon2Time:    1,
+	Argon2Memory:  64 * 1024,
+	Argon2Threads: 4,
+	ScryptLogN:    15,
+	ScryptR:       8,
+	ScryptP:       1,
+	SaltLength:    16,
+	KeyLength:     32,
+}
+
+// Hasher creates and verifies password hashes.
+type Hasher interface {
+	// HashPassword returns encoded hash of provided password.
+	HashPassword(password string) (string, error)
+	// CheckPasswordHash checks if provided password matches encoded hash of any supported algorithm.
+	CheckPasswordHash(password, hash string) bool
+	// NeedsRehash reports whether hash was produced by another algorithm or with
+	// other parameters than the hasher currently uses.
+	NeedsRehash(hash string) bool
+}
+
+type hasher struct {
+	opts Options
+}
+
+// New returns hasher producing hashes with provided options.
+func New(opts Options) Hasher {
+	return &hasher{
+		opts: opts,
 	}
+}
+
+var std = New(DefaultOptions)
 
-	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+// HashPassword returns hash string for provided password using DefaultOptions
+func HashPassword(password string) (string, error) {
+	return std.HashPassword(password)
 }
 
-// CheckPasswordHash checks if provided password is equal to sha512 hash string
-// In case there was error when composing password hash false value will be returned
+// CheckPasswordHash checks if provided password matches hash string
+// In case hash is malformed or produced by unknown algorithm false value will be returned
 func CheckPasswordHash(password, hash string) bool {
-	passwordHash, err := HashPassword(password)
-	if err != nil {
+	return std.CheckPasswordHash(password, hash)
+}
+
+// NeedsRehash reports whether hash should be replaced with one produced with DefaultOptions
+func NeedsRehash(hash string) bool {
+	return std.NeedsRehash(hash)
+}
+
+func (h *hasher) HashPassword(password string) (string, error) {
+	switch h.opts.Algorithm {
+	case Argon2id:
+		salt, err := newSalt(h.opts.SaltLength)
+		if err != nil {
+			return "", err
+		}
+		key := argon2.IDKey([]byte(password), salt, h.opts.Argon2Time, h.opts.Argon2Memory, h.opts.Argon2Threads, uint32(h.opts.KeyLength))
+		params := fmt.Sprintf("m=%d,t=%d,p=%d", h.opts.Argon2Memory, h.opts.Argon2Time, h.opts.Argon2Threads)
+		return encodePHC(Argon2id, fmt.Sprintf("v=%d", argon2.Version), params, salt, key), nil
+	case Scrypt:
+		salt, err := newSalt(h.opts.SaltLength)
+		if err != nil {
+			return "", err
+		}
+		key, err := scrypt.Key([]byte(password), salt, 1<<h.opts.ScryptLogN, h.opts.ScryptR, h.opts.ScryptP, h.opts.KeyLength)
+		if err != nil {
+			return "", err
+		}
+		params := fmt.Sprintf("ln=%d,r=%d,p=%d", h.opts.ScryptLogN, h.opts.ScryptR, h.opts.ScryptP)
+		return encodePHC(Scrypt, "", params, salt, key), nil
+	case Bcrypt:
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), h.opts.BcryptCost)
+		if err != nil {
+			return "", err
+		}
+		return string(hash), nil
+	}
+	return "", ErrUnsupportedAlgorithm
+}
+
+func (h *hasher) CheckPasswordHash(password, hash string) bool {
+	switch identify(hash) {
+	case Argon2id:
+		phc, err := decodePHC(hash, Argon2id)
+		if err != nil || phc.version != fmt.Sprintf("v=%d", argon2.Version) {
+			return false
+		}
+		m, t, p := phc.params["m"], phc.params["t"], phc.params["p"]
+		if m <= 0 || t <= 0 || p <= 0 || p > 255 {
+			return false
+		}
+		key := argon2.IDKey([]byte(password), phc.salt, uint32(t), uint32(m), uint8(p), uint32(len(phc.key)))
+		return subtle.ConstantTimeCompare(key, phc.key) == 1
+	case Scrypt:
+		phc, err := decodePHC(hash, Scrypt)
+		if err != nil {
+			return false
+		}
+		ln, r, p := phc.params["ln"], phc.params["r"], phc.params["p"]
+		if ln <= 0 || ln > 62 {
+			return false
+		}
+		key, err := scrypt.Key([]byte(password), phc.salt, 1<<ln, r, p, len(phc.key))
+		if err != nil {
+			return false
+		}
+		return subtle.ConstantTimeCompare(key, phc.key) == 1
+	case Bcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+	case legacySHA512:
+		sum := sha512.Sum512([]byte(password))
+		return subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(strings.ToLower(hash))) == 1
+	}
+	return false
+}
+
+func (h *hasher) NeedsRehash(hash string) bool {
+	if identify(hash) != h.opts.Algorithm {
+		return true
+	}
+	switch h.opts.Algorithm {
+	case Argon2id:
+		phc, err := decodePHC(hash, Argon2id)
+		return err != nil ||
+			phc.version != fmt.Sprintf("v=%d", argon2.Version) ||
+			phc.params["m"] != int(h.opts.Argon2Memory) ||
+			phc.params["t"] != int(h.opts.Argon2Time) ||
+			phc.params["p"] != int(h.opts.Argon2Threads) ||
+			len(phc.salt) != h.opts.SaltLength ||
+			len(phc.key) != h.opts.KeyLength
+	case Scrypt:
+		phc, err := decodePHC(hash, Scrypt)
+		return err != nil ||
+			phc.params["ln"] != int(h.opts.ScryptLogN) ||
+			phc.params["r"] != h.opts.ScryptR ||
+			phc.params["p"] != h.opts.ScryptP ||
+			len(phc.salt) != h.opts.SaltLength ||
+			len(phc.key) != h.opts.KeyLength
+	case Bcrypt:
+		cost, err := bcrypt.Cost([]byte(hash))
+		return err != nil || cost != h.opts.BcryptCost
+	}
+	return true
+}
+
+// identify returns algorithm of encoded hash or empty string if it is unknown.
+func identify(hash string) Algorithm {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return Argon2id
+	case strings.HasPrefix(hash, "$scrypt$"):
+		return Scrypt
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		return Bcrypt
+	case isLegacyHash(hash):
+		return legacySHA512
+	}
+	return ""
+}
+
+func isLegacyHash(hash string) bool {
+	if len(hash) != sha512.Size*2 {
 		return false
 	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
 
-	return passwordHash == hash
+func newSalt(length int) ([]byte, error) {
+	salt := make([]byte, length)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
 }
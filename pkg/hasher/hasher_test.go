@@ -1,17 +1,134 @@
 package hasher
 
-import "testing"
+import (
+	"strings"
+	"testing"
+)
+
+const legacyHelloHash = "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
+
+// cheapOptions keeps tests fast, production code should rely on DefaultOptions.
+func cheapOptions(algorithm Algorithm) Options {
+	opts := DefaultOptions
+	opts.Algorithm = algorithm
+	opts.BcryptCost = 4
+	opts.Argon2Memory = 1024
+	opts.Argon2Threads = 1
+	opts.ScryptLogN = 4
+	return opts
+}
 
 func TestHashPassword(t *testing.T) {
-	want := "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
-	if got, err := HashPassword("hello"); want != got && err == nil {
-		t.Errorf("HashPassword() = %q, want %q", got, want)
+	want := "$argon2id$v=19$m=65536,t=1,p=4$"
+	got, err := HashPassword("hello")
+	if err != nil || !strings.HasPrefix(got, want) {
+		t.Errorf("HashPassword() = %q, %v, want prefix %q", got, err, want)
+	}
+	if !CheckPasswordHash("hello", got) {
+		t.Errorf("CheckPasswordHash() = %t, want %t", false, true)
 	}
 }
 
 func TestCheckPasswordHash(t *testing.T) {
-	want := "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
+	want := legacyHelloHash
 	if got := CheckPasswordHash("hello", want); !got {
 		t.Errorf("CheckPasswordHash() = %t, want %t", got, true)
 	}
 }
+
+func TestHasherRoundTrip(t *testing.T) {
+	testConditions := []struct {
+		algorithm  Algorithm
+		wantPrefix string
+	}{
+		{algorithm: Argon2id, wantPrefix: "$argon2id$v=19$m=1024,t=1,p=1$"},
+		{algorithm: Scrypt, wantPrefix: "$scrypt$ln=4,r=8,p=1$"},
+		{algorithm: Bcrypt, wantPrefix: "$2a$04$"},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(string(testCond.algorithm), func(t *testing.T) {
+			h := New(cheapOptions(testCond.algorithm))
+
+			hash, err := h.HashPassword("hello")
+			if err != nil || !strings.HasPrefix(hash, testCond.wantPrefix) {
+				t.Fatalf("HashPassword() = %q, %v, want prefix %q", hash, err, testCond.wantPrefix)
+			}
+			if other, _ := h.HashPassword("hello"); other == hash {
+				t.Errorf("HashPassword() returned equal hashes %q for two calls, want salted", hash)
+			}
+			if !h.CheckPasswordHash("hello", hash) {
+				t.Errorf("CheckPasswordHash(%q) = %t, want %t", "hello", false, true)
+			}
+			if h.CheckPasswordHash("hellO", hash) {
+				t.Errorf("CheckPasswordHash(%q) = %t, want %t", "hellO", true, false)
+			}
+			if h.NeedsRehash(hash) {
+				t.Errorf("NeedsRehash() = %t, want %t", true, false)
+			}
+		})
+	}
+}
+
+func TestCheckPasswordHashAcrossAlgorithms(t *testing.T) {
+	scryptHash, _ := New(cheapOptions(Scrypt)).HashPassword("hello")
+	bcryptHash, _ := New(cheapOptions(Bcrypt)).HashPassword("hello")
+	h := New(cheapOptions(Argon2id))
+
+	testConditions := []struct {
+		tName string
+		hash  string
+		want  bool
+	}{
+		{tName: "legacy sha512", hash: legacyHelloHash, want: true},
+		{tName: "uppercase legacy sha512", hash: strings.ToUpper(legacyHelloHash), want: true},
+		{tName: "scrypt", hash: scryptHash, want: true},
+		{tName: "bcrypt", hash: bcryptHash, want: true},
+		{tName: "unknown algorithm", hash: "$md5$abc$def", want: false},
+		{tName: "malformed argon2id", hash: "$argon2id$v=19$m=1024$salt", want: false},
+		{tName: "empty", hash: "", want: false},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			if got := h.CheckPasswordHash("hello", testCond.hash); got != testCond.want {
+				t.Errorf("CheckPasswordHash() = %t, want %t", got, testCond.want)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	opts := cheapOptions(Argon2id)
+	opts.Argon2Time = 2
+	h := New(opts)
+	current, _ := h.HashPassword("hello")
+	weaker, _ := New(cheapOptions(Argon2id)).HashPassword("hello")
+	bcryptHash, _ := New(cheapOptions(Bcrypt)).HashPassword("hello")
+
+	testConditions := []struct {
+		tName string
+		hash  string
+		want  bool
+	}{
+		{tName: "current parameters", hash: current, want: false},
+		{tName: "legacy sha512", hash: legacyHelloHash, want: true},
+		{tName: "other algorithm", hash: bcryptHash, want: true},
+		{tName: "other parameters", hash: weaker, want: true},
+		{tName: "malformed", hash: "$argon2id$", want: true},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			if got := h.NeedsRehash(testCond.hash); got != testCond.want {
+				t.Errorf("NeedsRehash() = %t, want %t", got, testCond.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedAlgorithm(t *testing.T) {
+	if _, err := New(Options{Algorithm: "md5"}).HashPassword("hello"); err != ErrUnsupportedAlgorithm {
+		t.Errorf("HashPassword() error = %v, want %v", err, ErrUnsupportedAlgorithm)
+	}
+}
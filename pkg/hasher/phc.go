@@ -0,0 +1,61 @@
+package hasher
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// phcHash is a decoded PHC string "$<id>[$v=<version>]$<param>=<value>,...$<salt>$<hash>".
+type phcHash struct {
+	version string
+	params  map[string]int
+	salt    []byte
+	key     []byte
+}
+
+var phcEncoding = base64.RawStdEncoding
+
+func encodePHC(id Algorithm, version, params string, salt, key []byte) string {
+	fields := []string{"", string(id)}
+	if version != "" {
+		fields = append(fields, version)
+	}
+	fields = append(fields, params, phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key))
+	return strings.Join(fields, "$")
+}
+
+func decodePHC(hash string, id Algorithm) (*phcHash, error) {
+	fields := strings.Split(hash, "$")
+	if len(fields) < 5 || fields[0] != "" || fields[1] != string(id) {
+		return nil, ErrMalformedHash
+	}
+	phc := &phcHash{params: make(map[string]int)}
+	fields = fields[2:]
+	if strings.HasPrefix(fields[0], "v=") {
+		phc.version = fields[0]
+		fields = fields[1:]
+	}
+	if len(fields) != 3 {
+		return nil, ErrMalformedHash
+	}
+	for _, param := range strings.Split(fields[0], ",") {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrMalformedHash
+		}
+		value, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, ErrMalformedHash
+		}
+		phc.params[kv[0]] = value
+	}
+	var err error
+	if phc.salt, err = phcEncoding.DecodeString(fields[1]); err != nil {
+		return nil, ErrMalformedHash
+	}
+	if phc.key, err = phcEncoding.DecodeString(fields[2]); err != nil || len(phc.key) == 0 {
+		return nil, ErrMalformedHash
+	}
+	return phc, nil
+}
@@ -0,0 +1,98 @@
+// Package jwt implements signing and verification of compact JSON Web Tokens
+// with HMAC SHA-256 (HS256) signatures, which is the only algorithm the chat issues.
+package jwt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const algorithm = "HS256"
+
+var ErrMalformedToken = errors.New("malformed token")
+var ErrUnsupportedAlgorithm = errors.New("unsupported token signing algorithm")
+var ErrInvalidSignature = errors.New("invalid token signature")
+var ErrTokenExpired = errors.New("token has been expired")
+
+// Claims is a set of registered claims used by the chat and the token type.
+type Claims struct {
+	Id        string `json:"jti"`
+	Subject   string `json:"sub"`
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"typ"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ"`
+}
+
+var encoding = base64.RawURLEncoding
+
+// Sign returns compact serialization of claims signed with the key.
+func Sign(claims *Claims, key []byte) (string, error) {
+	h, err := json.Marshal(&header{Algorithm: algorithm, Type: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	unsigned := encoding.EncodeToString(h) + "." + encoding.EncodeToString(c)
+	return unsigned + "." + encoding.EncodeToString(sign(unsigned, key)), nil
+}
+
+// Parse verifies signature and expiration of the token at the provided time and returns its claims.
+func Parse(token string, key []byte, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformedToken
+	}
+	h := &header{}
+	if err := decodeSegment(parts[0], h); err != nil {
+		return nil, err
+	}
+	if h.Algorithm != algorithm {
+		return nil, ErrUnsupportedAlgorithm
+	}
+	signature, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformedToken
+	}
+	if !hmac.Equal(signature, sign(parts[0]+"."+parts[1], key)) {
+		return nil, ErrInvalidSignature
+	}
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, err
+	}
+	if claims.ExpiresAt <= now.Unix() {
+		return nil, ErrTokenExpired
+	}
+	return claims, nil
+}
+
+func sign(unsigned string, key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(unsigned))
+	return mac.Sum(nil)
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := encoding.DecodeString(segment)
+	if err != nil {
+		return ErrMalformedToken
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return ErrMalformedToken
+	}
+	return nil
+}
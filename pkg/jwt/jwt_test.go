@@ -0,0 +1,49 @@
+package jwt
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignAndParse(t *testing.T) {
+	key := []byte("secret")
+	now := time.Unix(1640000000, 0)
+	claims := &Claims{Id: "1", Subject: "user", Name: "foo", Type: "access", IssuedAt: now.Unix(), ExpiresAt: now.Unix() + 60}
+	token, err := Sign(claims, key)
+	if err != nil {
+		t.Fatalf("Sign() error = %v, want nil", err)
+	}
+	parts := strings.Split(token, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	noneHeader := encoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`))
+
+	testConditions := []struct {
+		tName      string
+		token      string
+		key        string
+		now        time.Time
+		wantClaims *Claims
+		wantErr    error
+	}{
+		{tName: "valid token", token: token, key: "secret", now: now, wantClaims: claims},
+		{tName: "expired token", token: token, key: "secret", now: now.Add(time.Minute), wantErr: ErrTokenExpired},
+		{tName: "another key", token: token, key: "other", now: now, wantErr: ErrInvalidSignature},
+		{tName: "tampered claims", token: tampered, key: "secret", now: now, wantErr: ErrInvalidSignature},
+		{tName: "unsigned token", token: noneHeader + "." + parts[1] + ".", key: "secret", now: now, wantErr: ErrUnsupportedAlgorithm},
+		{tName: "not a token", token: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", key: "secret", now: now, wantErr: ErrMalformedToken},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			gotClaims, gotErr := Parse(testCond.token, []byte(testCond.key), testCond.now)
+
+			if gotErr != testCond.wantErr {
+				t.Fatalf("Parse() error = %v, want %v", gotErr, testCond.wantErr)
+			}
+			if testCond.wantClaims != nil && *gotClaims != *testCond.wantClaims {
+				t.Errorf("Parse() = %+v, want %+v", gotClaims, testCond.wantClaims)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+// Package logger implements leveled structured logging in JSON or logfmt format.
+// Every entry is a single line with time, level and message followed by key value pairs,
+// e.g. logger.Info("Session opened", "sessionId", id). Loggers derived with With share
+// the output of their parent and add their pairs to every entry.
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+var ErrUnknownLevel = errors.New("unknown log level")
+
+// ParseLevel accepts debug, info, warn and error in any case.
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return LevelInfo, ErrUnknownLevel
+}
+
+// Format is an encoding of log entries.
+type Format string
+
+const (
+	FormatJSON   Format = "json"
+	FormatLogfmt Format = "logfmt"
+)
+
+// output serializes writes of all loggers derived from the same root.
+type output struct {
+	w  io.Writer
+	mu *sync.Mutex
+}
+
+// Logger is safe for concurrent use.
+type Logger struct {
+	out     *output
+	level   Level
+	format  Format
+	keyvals []interface{}
+	now     func() time.Time
+}
+
+// New creates logger which writes entries of the level and above to w.
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{
+		out:    &output{w: w, mu: &sync.Mutex{}},
+		level:  level,
+		format: format,
+		now:    time.Now,
+	}
+}
+
+// With returns logger which adds the key value pairs to every entry.
+// Pair replaces the one of the parent with the same key.
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	child := *l
+	child.keyvals = make([]interface{}, len(l.keyvals), len(l.keyvals)+len(keyvals))
+	copy(child.keyvals, l.keyvals)
+	for i := 0; i < len(keyvals); i += 2 {
+		key := fmt.Sprint(keyvals[i])
+		var v interface{} = "(MISSING)"
+		if i+1 < len(keyvals) {
+			v = keyvals[i+1]
+		}
+		child.keyvals = setPair(child.keyvals, key, v)
+	}
+	return &child
+}
+
+func setPair(keyvals []interface{}, key string, v interface{}) []interface{} {
+	for i := 0; i < len(keyvals); i += 2 {
+		if keyvals[i] == key {
+			keyvals[i+1] = v
+			return keyvals
+		}
+	}
+	return append(keyvals, key, v)
+}
+
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) {
+	l.log(LevelDebug, msg, keyvals)
+}
+
+func (l *Logger) Info(msg string, keyvals ...interface{}) {
+	l.log(LevelInfo, msg, keyvals)
+}
+
+func (l *Logger) Warn(msg string, keyvals ...interface{}) {
+	l.log(LevelWarn, msg, keyvals)
+}
+
+func (l *Logger) Error(msg string, keyvals ...interface{}) {
+	l.log(LevelError, msg, keyvals)
+}
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	pairs := make([]interface{}, 0, 6+len(l.keyvals)+len(keyvals))
+	pairs = append(pairs, "time", l.now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg)
+	pairs = append(append(pairs, l.keyvals...), keyvals...)
+	if len(pairs)%2 != 0 {
+		pairs = append(pairs, "(MISSING)")
+	}
+
+	buf := &bytes.Buffer{}
+	if l.format == FormatLogfmt {
+		encodeLogfmt(buf, pairs)
+	} else {
+		encodeJSON(buf, pairs)
+	}
+	buf.WriteByte('\n')
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	l.out.w.Write(buf.Bytes())
+}
+
+// value converts errors, durations and other stringers into strings, the rest is encoded as is.
+func value(v interface{}) interface{} {
+	switch t := v.(type) {
+	case nil:
+		return nil
+	case error:
+		return t.Error()
+	case time.Duration:
+		return t.String()
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	case fmt.Stringer:
+		return t.String()
+	default:
+		return v
+	}
+}
+
+func encodeJSON(buf *bytes.Buffer, pairs []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(pairs[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+		data, err := json.Marshal(value(pairs[i+1]))
+		if err != nil {
+			data, _ = json.Marshal(fmt.Sprint(pairs[i+1]))
+		}
+		buf.Write(data)
+	}
+	buf.WriteByte('}')
+}
+
+func encodeLogfmt(buf *bytes.Buffer, pairs []interface{}) {
+	for i := 0; i < len(pairs); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(logfmtKey(fmt.Sprint(pairs[i])))
+		buf.WriteByte('=')
+		v := value(pairs[i+1])
+		if v == nil {
+			continue
+		}
+		buf.WriteString(logfmtValue(fmt.Sprint(v)))
+	}
+}
+
+// logfmtKey drops characters which would break the pair.
+func logfmtKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return -1
+		}
+		return r
+	}, key)
+}
+
+func logfmtValue(v string) string {
+	if v == "" {
+		return `""`
+	}
+	if strings.IndexFunc(v, func(r rune) bool {
+		return r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r)
+	}) >= 0 {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+type contextKey struct{}
+
+var (
+	defaultLogger = New(os.Stdout, LevelInfo, FormatJSON)
+	defaultMu     = &sync.RWMutex{}
+)
+
+// Default returns logger used when context does not carry one.
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces logger used when context does not carry one, it is meant to be called on startup.
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// NewContext returns a copy of ctx carrying the logger.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns logger stored in ctx by NewContext or the default one.
+func FromContext(ctx context.Context) *Logger {
+	if l, ok := ctx.Value(contextKey{}).(*Logger); ok && l != nil {
+		return l
+	}
+	return Default()
+}
+
+// With returns a copy of ctx carrying its logger extended with the key value pairs.
+func With(ctx context.Context, keyvals ...interface{}) context.Context {
+	return NewContext(ctx, FromContext(ctx).With(keyvals...))
+}
@@ -0,0 +1,86 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestLogger(level Level, format Format) (*Logger, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	l := New(out, level, format)
+	l.now = func() time.Time { return time.Date(2021, 12, 17, 10, 0, 0, 0, time.UTC) }
+	return l, out
+}
+
+func TestFormats(t *testing.T) {
+	testConditions := []struct {
+		tName  string
+		format Format
+		want   string
+	}{
+		{
+			tName:  "json",
+			format: FormatJSON,
+			want:   `{"time":"2021-12-17T10:00:00Z","level":"error","msg":"Unable to write","requestId":"r1","err":"broken pipe","took":"1.5s","ids":["a","b"],"odd":"(MISSING)"}` + "\n",
+		},
+		{
+			tName:  "logfmt",
+			format: FormatLogfmt,
+			want:   `time=2021-12-17T10:00:00Z level=error msg="Unable to write" requestId=r1 err="broken pipe" took=1.5s ids="[a b]" odd=(MISSING)` + "\n",
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			l, out := newTestLogger(LevelInfo, testCond.format)
+
+			l.With("requestId", "r1").Error("Unable to write", "err", errors.New("broken pipe"), "took", 1500*time.Millisecond, "ids", []string{"a", "b"}, "odd")
+
+			if out.String() != testCond.want {
+				t.Errorf("Error() wrote %s, want %s", out.String(), testCond.want)
+			}
+		})
+	}
+}
+
+func TestLevel(t *testing.T) {
+	l, out := newTestLogger(LevelWarn, FormatLogfmt)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Warn("warn")
+
+	want := "time=2021-12-17T10:00:00Z level=warn msg=warn\n"
+	if out.String() != want {
+		t.Errorf("logger wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	if level, err := ParseLevel("WARN"); err != nil || level != LevelWarn {
+		t.Errorf("ParseLevel() = %v, %v, want %v, nil", level, err, LevelWarn)
+	}
+	if _, err := ParseLevel("verbose"); err != ErrUnknownLevel {
+		t.Errorf("ParseLevel() error = %v, want %v", err, ErrUnknownLevel)
+	}
+}
+
+func TestContext(t *testing.T) {
+	l, out := newTestLogger(LevelInfo, FormatLogfmt)
+	ctx := NewContext(context.Background(), l)
+
+	ctx = With(ctx, "userId", "u0")
+	ctx = With(ctx, "userId", "u1")
+	FromContext(ctx).Info("Session opened", "sessionId", "s1")
+
+	want := "time=2021-12-17T10:00:00Z level=info msg=\"Session opened\" userId=u1 sessionId=s1\n"
+	if out.String() != want {
+		t.Errorf("logger wrote %q, want %q", out.String(), want)
+	}
+	if FromContext(context.Background()) != Default() {
+		t.Errorf("FromContext() without logger did not return the default logger")
+	}
+}
@@ -0,0 +1,374 @@
+// Package metrics implements counters, gauges and histograms exposed in the Prometheus
+// text exposition format, so the server can be scraped without any client library.
+// Metrics are registered once, usually in package level variables, and are safe for concurrent use.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType is a media type of the text exposition format written by Registry.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are upper bounds in seconds suitable for latencies of HTTP requests and database operations.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// DefaultRegistry keeps metrics created by the package level constructors.
+var DefaultRegistry = NewRegistry()
+
+type metric interface {
+	name() string
+	write(*bufio.Writer)
+}
+
+// Registry is a set of uniquely named metrics.
+type Registry struct {
+	metrics []metric
+	names   map[string]bool
+	mu      *sync.Mutex
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: map[string]bool{},
+		mu:    &sync.Mutex{},
+	}
+}
+
+// register panics on duplicate names, it is a programming error like a duplicate route.
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.names[m.name()] {
+		panic(fmt.Sprintf("metrics: %s is already registered", m.name()))
+	}
+	r.names[m.name()] = true
+	r.metrics = append(r.metrics, m)
+}
+
+// WriteTo writes all metrics sorted by name in the text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	metrics := make([]metric, len(r.metrics))
+	copy(metrics, r.metrics)
+	r.mu.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].name() < metrics[j].name()
+	})
+
+	cw := &countingWriter{w: w}
+	bw := bufio.NewWriter(cw)
+	for _, m := range metrics {
+		m.write(bw)
+	}
+	err := bw.Flush()
+	return cw.n, err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+// family keeps series of a metric keyed by label values.
+type family struct {
+	metricName string
+	help       string
+	kind       string
+	labels     []string
+	series     map[string]interface{}
+	mu         *sync.Mutex
+}
+
+func newFamily(name, help, kind string, labels []string) *family {
+	return &family{
+		metricName: name,
+		help:       help,
+		kind:       kind,
+		labels:     labels,
+		series:     map[string]interface{}{},
+		mu:         &sync.Mutex{},
+	}
+}
+
+func (f *family) name() string {
+	return f.metricName
+}
+
+// get returns series of the label values creating it when needed, must be called with the lock held.
+func (f *family) get(values []string, create func() interface{}) interface{} {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", f.metricName, len(f.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = create()
+		f.series[key] = s
+	}
+	return s
+}
+
+// each visits series ordered by label values, must be called with the lock held.
+func (f *family) each(visit func(values []string, s interface{})) {
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var values []string
+		if len(f.labels) > 0 {
+			values = strings.Split(key, "\xff")
+		}
+		visit(values, f.series[key])
+	}
+}
+
+func (f *family) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", f.metricName, escape(f.help, false))
+	fmt.Fprintf(w, "# TYPE %s %s\n", f.metricName, f.kind)
+}
+
+// Counter is a value which only goes up, e.g. number of served requests.
+type Counter struct {
+	*family
+}
+
+// NewCounter registers counter in the registry.
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{family: newFamily(name, help, "counter", labels)}
+	r.register(c)
+	return c
+}
+
+// NewCounter registers counter in the default registry.
+func NewCounter(name, help string, labels ...string) *Counter {
+	return DefaultRegistry.NewCounter(name, help, labels...)
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add panics when the value is negative, use Gauge for values which go down.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: counter %s can not decrease", c.metricName))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.get(labelValues, newValue).(*float64) += v
+}
+
+// Value returns current value of the series, it is meant for tests.
+func (c *Counter) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[strings.Join(labelValues, "\xff")]
+	if !ok {
+		return 0
+	}
+	return *s.(*float64)
+}
+
+func (c *Counter) write(w *bufio.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeHeader(w)
+	writeValues(w, c.family)
+}
+
+// Gauge is a value which goes up and down, e.g. number of open connections.
+type Gauge struct {
+	*family
+}
+
+// NewGauge registers gauge in the registry.
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{family: newFamily(name, help, "gauge", labels)}
+	r.register(g)
+	return g
+}
+
+// NewGauge registers gauge in the default registry.
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return DefaultRegistry.NewGauge(name, help, labels...)
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(labelValues, newValue).(*float64) = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.get(labelValues, newValue).(*float64) += v
+}
+
+func (g *Gauge) Inc(labelValues ...string) {
+	g.Add(1, labelValues...)
+}
+
+func (g *Gauge) Dec(labelValues ...string) {
+	g.Add(-1, labelValues...)
+}
+
+// Value returns current value of the series, it is meant for tests.
+func (g *Gauge) Value(labelValues ...string) float64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	s, ok := g.series[strings.Join(labelValues, "\xff")]
+	if !ok {
+		return 0
+	}
+	return *s.(*float64)
+}
+
+func (g *Gauge) write(w *bufio.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.writeHeader(w)
+	writeValues(w, g.family)
+}
+
+func newValue() interface{} {
+	return new(float64)
+}
+
+// writeValues writes series of counter or gauge, metric without labels is written even when it was never updated.
+func writeValues(w *bufio.Writer, f *family) {
+	if len(f.labels) == 0 && len(f.series) == 0 {
+		fmt.Fprintf(w, "%s 0\n", f.metricName)
+		return
+	}
+	f.each(func(values []string, s interface{}) {
+		fmt.Fprintf(w, "%s%s %s\n", f.metricName, labelPairs(f.labels, values), formatFloat(*s.(*float64)))
+	})
+}
+
+type histogramSeries struct {
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// Histogram counts observations in buckets, e.g. latencies of requests.
+type Histogram struct {
+	*family
+	buckets []float64
+}
+
+// NewHistogram registers histogram with the bucket upper bounds in the registry.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+	h := &Histogram{family: newFamily(name, help, "histogram", labels), buckets: sorted}
+	r.register(h)
+	return h
+}
+
+// NewHistogram registers histogram in the default registry.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return DefaultRegistry.NewHistogram(name, help, buckets, labels...)
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.get(labelValues, func() interface{} {
+		return &histogramSeries{counts: make([]uint64, len(h.buckets))}
+	}).(*histogramSeries)
+	for i, bound := range h.buckets {
+		if v <= bound {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += v
+}
+
+// ObserveSince observes seconds elapsed since the start.
+func (h *Histogram) ObserveSince(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+// Count returns number of observations of the series, it is meant for tests.
+func (h *Histogram) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[strings.Join(labelValues, "\xff")]
+	if !ok {
+		return 0
+	}
+	return s.(*histogramSeries).count
+}
+
+func (h *Histogram) write(w *bufio.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.writeHeader(w)
+	bucketLabels := append(append([]string{}, h.labels...), "le")
+	h.each(func(values []string, series interface{}) {
+		s := series.(*histogramSeries)
+		for i, bound := range h.buckets {
+			le := append(append([]string{}, values...), formatFloat(bound))
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelPairs(bucketLabels, le), s.counts[i])
+		}
+		le := append(append([]string{}, values...), "+Inf")
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.metricName, labelPairs(bucketLabels, le), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.metricName, labelPairs(h.labels, values), formatFloat(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.metricName, labelPairs(h.labels, values), s.count)
+	})
+}
+
+func labelPairs(labels, values []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(labels))
+	for i, label := range labels {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, label, escape(values[i], true))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escape escapes backslashes and line feeds, and double quotes inside label values.
+func escape(s string, quotes bool) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	if quotes {
+		s = strings.ReplaceAll(s, `"`, `\"`)
+	}
+	return s
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
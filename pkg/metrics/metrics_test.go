@@ -0,0 +1,114 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounter("http_requests_total", "Served requests.", "route", "status")
+	conns := r.NewGauge("connections", "Open connections.")
+	latency := r.NewHistogram("latency_seconds", "Latency\nof requests.", []float64{1, 0.1})
+	r.NewCounter("unused_total", "Never updated.", "kind")
+
+	requests.Inc("/user", "200")
+	requests.Add(2, "/user", "200")
+	requests.Inc(`/a"b\c`, "500")
+	conns.Inc()
+	conns.Inc()
+	conns.Dec()
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(3)
+
+	want := `# HELP connections Open connections.
+# TYPE connections gauge
+connections 1
+# HELP http_requests_total Served requests.
+# TYPE http_requests_total counter
+http_requests_total{route="/a\"b\\c",status="500"} 1
+http_requests_total{route="/user",status="200"} 3
+# HELP latency_seconds Latency\nof requests.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 3.55
+latency_seconds_count 3
+# HELP unused_total Never updated.
+# TYPE unused_total counter
+`
+	out := &bytes.Buffer{}
+	n, err := r.WriteTo(out)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	if out.String() != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", out.String(), want)
+	}
+	if n != int64(out.Len()) {
+		t.Errorf("WriteTo() = %d, want %d", n, out.Len())
+	}
+}
+
+func TestUnlabelledMetricWrittenBeforeUpdate(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("connections", "Open connections.")
+
+	out := &bytes.Buffer{}
+	r.WriteTo(out)
+
+	want := "# HELP connections Open connections.\n# TYPE connections gauge\nconnections 0\n"
+	if out.String() != want {
+		t.Errorf("WriteTo() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestValue(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("tokens_total", "Tokens.", "type")
+	h := r.NewHistogram("op_seconds", "Operations.", DefaultBuckets, "op")
+
+	c.Inc("access")
+	h.Observe(0.2, "find")
+
+	if got := c.Value("access"); got != 1 {
+		t.Errorf("Value() = %v, want %v", got, 1)
+	}
+	if got := c.Value("refresh"); got != 0 {
+		t.Errorf("Value() of missing series = %v, want %v", got, 0)
+	}
+	if got := h.Count("find"); got != 1 {
+		t.Errorf("Count() = %v, want %v", got, 1)
+	}
+}
+
+func TestPanics(t *testing.T) {
+	testConditions := []struct {
+		tName string
+		call  func(r *Registry)
+	}{
+		{tName: "duplicate name", call: func(r *Registry) {
+			r.NewCounter("a_total", "A.")
+			r.NewGauge("a_total", "A.")
+		}},
+		{tName: "wrong number of label values", call: func(r *Registry) {
+			r.NewCounter("a_total", "A.", "kind").Inc()
+		}},
+		{tName: "decreasing counter", call: func(r *Registry) {
+			r.NewCounter("a_total", "A.").Add(-1)
+		}},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", testCond.tName)
+				}
+			}()
+			testCond.call(NewRegistry())
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package ratelimit implements token bucket rate limiting of events grouped by an arbitrary key,
+// e.g. client address or user id. Every key has its own bucket which is refilled at a constant
+// rate up to the burst size, an event is allowed only when the bucket has a token to take.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// Limiter is safe for concurrent use. Buckets which were refilled completely are
+// forgotten, so memory usage depends only on the number of recently active keys.
+type Limiter struct {
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+	sweptAt time.Time
+	mu      *sync.Mutex
+	now     func() time.Time
+}
+
+// New creates limiter which allows burst events at once and refills perSecond tokens every second.
+// Limiter with non positive rate or burst allows every event.
+func New(perSecond float64, burst int) *Limiter {
+	return &Limiter{
+		rate:    perSecond,
+		burst:   float64(burst),
+		buckets: map[string]*bucket{},
+		mu:      &sync.Mutex{},
+		now:     time.Now,
+	}
+}
+
+// PerMinute converts number of events per minute into the rate accepted by New.
+func PerMinute(n int) float64 {
+	return float64(n) / 60
+}
+
+// Allow takes a token from the bucket of the key. When the bucket is empty it reports
+// how long the caller has to wait until the next event is allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l.rate <= 0 || l.burst <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, updatedAt: now}
+		l.buckets[key] = b
+	}
+	b.tokens = l.refill(b, now)
+	b.updatedAt = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration(math.Ceil((1 - b.tokens) / l.rate * float64(time.Second)))
+	return false, wait
+}
+
+func (l *Limiter) refill(b *bucket, now time.Time) float64 {
+	return math.Min(l.burst, b.tokens+now.Sub(b.updatedAt).Seconds()*l.rate)
+}
+
+// sweep removes full buckets once per time needed to refill an empty bucket.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.sweptAt).Seconds() < l.burst/l.rate {
+		return
+	}
+	l.sweptAt = now
+	for key, b := range l.buckets {
+		if l.refill(b, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package ratelimit
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock lets tests move limiter time manually.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newTestLimiter(perSecond float64, burst int) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	l := New(perSecond, burst)
+	l.now = func() time.Time { return clock.now }
+	return l, clock
+}
+
+func TestAllowBurst(t *testing.T) {
+	l, _ := newTestLimiter(1, 3)
+
+	for i := 0; i < 3; i++ {
+		if ok, wait := l.Allow("a"); !ok {
+			t.Fatalf("Allow() #%d = %t, %v, want %t", i, ok, wait, true)
+		}
+	}
+	if ok, wait := l.Allow("a"); ok || wait != time.Second {
+		t.Errorf("Allow() = %t, %v, want %t, %v", ok, wait, false, time.Second)
+	}
+	if ok, _ := l.Allow("b"); !ok {
+		t.Errorf("Allow() for another key = %t, want %t", ok, true)
+	}
+}
+
+func TestAllowRefill(t *testing.T) {
+	l, clock := newTestLimiter(PerMinute(30), 1)
+
+	if ok, _ := l.Allow("a"); !ok {
+		t.Fatalf("Allow() = %t, want %t", ok, true)
+	}
+	clock.advance(time.Second)
+	if ok, wait := l.Allow("a"); ok || wait != time.Second {
+		t.Errorf("Allow() = %t, %v, want %t, %v", ok, wait, false, time.Second)
+	}
+	clock.advance(time.Second)
+	if ok, wait := l.Allow("a"); !ok {
+		t.Errorf("Allow() after refill = %t, %v, want %t", ok, wait, true)
+	}
+}
+
+func TestAllowUnlimited(t *testing.T) {
+	l, _ := newTestLimiter(0, 0)
+
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Allow() #%d = %t, want %t", i, ok, true)
+		}
+	}
+}
+
+func TestSweepForgetsFullBuckets(t *testing.T) {
+	l, clock := newTestLimiter(1, 2)
+	l.Allow("a")
+	l.Allow("b")
+	l.Allow("b")
+
+	clock.advance(2 * time.Second)
+	l.Allow("c")
+
+	if _, ok := l.buckets["a"]; ok {
+		t.Errorf("bucket of idle key was not removed")
+	}
+	if got := len(l.buckets); got != 1 {
+		t.Errorf("len(buckets) = %d, want %d", got, 1)
+	}
+}
+
+func TestAllowConcurrent(t *testing.T) {
+	l := New(PerMinute(1), 50)
+	allowed := make(chan bool, 100)
+	wg := &sync.WaitGroup{}
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _ := l.Allow("a")
+			allowed <- ok
+		}()
+	}
+	wg.Wait()
+	close(allowed)
+
+	got := 0
+	for ok := range allowed {
+		if ok {
+			got++
+		}
+	}
+	if got != 50 {
+		t.Errorf("allowed %d concurrent events, want %d", got, 50)
+	}
+}
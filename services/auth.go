@@ -0,0 +1,20 @@
+package services
+
+import (
+	"context"
+
+	"github.com/andriystech/lgc/models"
+)
+
+type userContextKey struct{}
+
+// ContextWithUser returns a copy of ctx carrying authenticated user.
+func ContextWithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// UserFromContext returns authenticated user stored in ctx by the auth middleware.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(userContextKey{}).(*models.User)
+	return user, ok && user != nil
+}
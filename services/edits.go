@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+)
+
+var ErrNotMessageSender = errors.New("message can be changed only by its sender")
+var ErrMessageDeleted = errors.New("message is deleted")
+
+// EditMessage replaces payload of the sender's message and notifies its recipients.
+func (svc *webSocketService) EditMessage(ctx context.Context, sender *models.User, messageId, payload string) (*models.Message, error) {
+	if err := validatePayload(payload); err != nil {
+		return nil, err
+	}
+	msg, err := svc.findOwnMessage(ctx, sender, messageId)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := msg.Revision()
+	msg.Edit(payload, time.Now().Unix())
+	if err = svc.publishUpdate(ctx, msg, revision); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// DeleteMessage turns the sender's message into a tombstone and notifies its recipients.
+func (svc *webSocketService) DeleteMessage(ctx context.Context, sender *models.User, messageId string) error {
+	msg, err := svc.findOwnMessage(ctx, sender, messageId)
+	if err != nil {
+		return err
+	}
+
+	revision := msg.Revision()
+	msg.Delete(time.Now().Unix())
+	return svc.publishUpdate(ctx, msg, revision)
+}
+
+func (svc *webSocketService) findOwnMessage(ctx context.Context, sender *models.User, messageId string) (*models.Message, error) {
+	msg, err := svc.messages.FindMessage(ctx, messageId)
+	if err != nil {
+		return nil, err
+	}
+	if msg.SenderId != sender.Id {
+		return nil, ErrNotMessageSender
+	}
+	if msg.IsDeleted() {
+		return nil, ErrMessageDeleted
+	}
+	return msg, nil
+}
+
+// publishUpdate stores the change of the message loaded with the revision and notifies its recipients.
+func (svc *webSocketService) publishUpdate(ctx context.Context, msg *models.Message, revision int) error {
+	if err := svc.messages.UpdateMessageContent(ctx, msg, revision); err != nil {
+		return err
+	}
+	copies, err := svc.messages.FindMessageReceipts(ctx, msg.SenderId, msg.LogicalId())
+	if err != nil {
+		return err
+	}
+	recipientIds := make([]string, 0, len(copies))
+	for _, c := range copies {
+		recipientIds = append(recipientIds, c.RecipientId)
+	}
+	return svc.broker.Publish(ctx, models.NewUpdateEvent(msg, recipientIds))
+}
+
+// handleUpdateFrame edits or deletes the message and acknowledges the change.
+// Errors caused by the frame content are reported back to the client, the rest are returned.
+func (svc *webSocketService) handleUpdateFrame(ctx context.Context, conn ws.ConnHelper, frame *models.Frame, sender *models.User) error {
+	var err error
+	if frame.Type == models.FrameTypeEdit {
+		_, err = svc.EditMessage(ctx, sender, frame.Id, frame.Payload)
+	} else {
+		err = svc.DeleteMessage(ctx, sender, frame.Id)
+	}
+	if errors.Is(err, ErrNotMessageSender) || errors.Is(err, ErrMessageDeleted) || errors.Is(err, repositories.ErrMessageNotFound) ||
+		errors.Is(err, repositories.ErrMessageChanged) {
+		return writeFrame(conn, models.NewErrorFrame(frame.Id, err.Error()))
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFrame(conn, models.NewAckFrame(frame.Id, frame.Id))
+}
+
+// deliverUpdate writes edit or delete frame to local connections of the message recipients
+// and to other sessions of the sender.
+func (svc *webSocketService) deliverUpdate(ctx context.Context, event *models.Event) error {
+	cs, err := svc.connections.GetAllConnections(ctx)
+	if err != nil {
+		return err
+	}
+
+	msg := event.Message
+	targets := map[string]bool{msg.SenderId: true}
+	for _, id := range event.RecipientIds {
+		targets[id] = true
+	}
+	for rId, conns := range cs {
+		if !targets[rId] {
+			continue
+		}
+		if svc.writeUpdate(ctx, conns, msg) && rId != msg.SenderId {
+			svc.markUpdateDelivered(ctx, rId, msg)
+		}
+	}
+
+	return nil
+}
+
+// writeUpdate reports whether at least one session received the update.
+func (svc *webSocketService) writeUpdate(ctx context.Context, conns []ws.ConnHelper, msg *models.Message) bool {
+	delivered := false
+	for _, conn := range conns {
+		if err := writeFrame(conn, models.NewUpdateFrame(msg)); err != nil {
+			logger.FromContext(ctx).Error("Unable to deliver update of message", "messageId", msg.LogicalId(), "err", err)
+			continue
+		}
+		delivered = true
+	}
+	return delivered
+}
+
+func (svc *webSocketService) markUpdateDelivered(ctx context.Context, recipientId string, msg *models.Message) {
+	if err := svc.messages.MarkUpdateDelivered(ctx, recipientId, msg.LogicalId()); err != nil {
+		logger.FromContext(ctx).Error("Unable to mark update of message as delivered", "messageId", msg.LogicalId(), "recipientId", recipientId, "err", err)
+	}
+}
+
+// loadPendingUpdates notifies connecting recipient about messages changed while it was offline.
+func (svc *webSocketService) loadPendingUpdates(ctx context.Context, usr *models.User, conn ws.ConnHelper) error {
+	messages, err := svc.messages.FindPendingUpdates(ctx, usr.Id)
+	if err != nil {
+		return err
+	}
+
+	for _, msg := range messages {
+		if err = writeFrame(conn, models.NewUpdateFrame(msg)); err != nil {
+			return err
+		}
+		svc.markUpdateDelivered(ctx, usr.Id, msg)
+	}
+
+	return nil
+}
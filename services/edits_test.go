@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEditMessage(t *testing.T) {
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	messageId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa50"
+	stored := func() *models.Message {
+		return &models.Message{Id: "c1", OriginId: messageId, SenderId: sender.Id, Payload: "helo", Time: 5}
+	}
+	testConditions := []struct {
+		tName        string
+		payload      string
+		wantErr      error
+		prepareMocks func(*mocks.MessagesRepository, *mocks.Broker)
+	}{
+		{
+			tName:        "should fail with empty payload error",
+			payload:      "",
+			wantErr:      ErrEmptyFramePayload,
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {},
+		},
+		{
+			tName:   "should fail with message not found error",
+			payload: "hello",
+			wantErr: repositories.ErrMessageNotFound,
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {
+				mr.On("FindMessage", mock.Anything, messageId).Return(nil, repositories.ErrMessageNotFound)
+			},
+		},
+		{
+			tName:   "should fail with not message sender error",
+			payload: "hello",
+			wantErr: ErrNotMessageSender,
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {
+				msg := stored()
+				msg.SenderId = "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+				mr.On("FindMessage", mock.Anything, messageId).Return(msg, nil)
+			},
+		},
+		{
+			tName:   "should fail with message deleted error",
+			payload: "hello",
+			wantErr: ErrMessageDeleted,
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {
+				msg := stored()
+				msg.Delete(7)
+				mr.On("FindMessage", mock.Anything, messageId).Return(msg, nil)
+			},
+		},
+		{
+			tName:   "should fail with message changed error when message is changed meanwhile",
+			payload: "hello",
+			wantErr: repositories.ErrMessageChanged,
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {
+				mr.On("FindMessage", mock.Anything, messageId).Return(stored(), nil)
+				mr.On("UpdateMessageContent", mock.Anything, mock.Anything, 0).Return(repositories.ErrMessageChanged)
+			},
+		},
+		{
+			tName:   "should store revision and publish update to all recipients",
+			payload: "hello",
+			prepareMocks: func(mr *mocks.MessagesRepository, br *mocks.Broker) {
+				mr.On("FindMessage", mock.Anything, messageId).Return(stored(), nil)
+				mr.On("UpdateMessageContent", mock.Anything, mock.MatchedBy(func(msg *models.Message) bool {
+					return msg.Payload == "hello" && msg.EditedAt != 0 && len(msg.History) == 1 && msg.History[0].Payload == "helo" && msg.History[0].Time == 5
+				}), 0).Return(nil)
+				mr.On("FindMessageReceipts", mock.Anything, sender.Id, messageId).Return([]*models.Message{{RecipientId: "r1"}, {RecipientId: "r2"}}, nil)
+				br.On("Publish", mock.Anything, mock.MatchedBy(func(event *models.Event) bool {
+					return event.Type == models.EventTypeUpdate && assert.ObjectsAreEqual([]string{"r1", "r2"}, event.RecipientIds)
+				})).Return(nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			mr := new(mocks.MessagesRepository)
+			br := new(mocks.Broker)
+			br.On("Subscribe", mock.Anything).Return()
+			testCond.prepareMocks(mr, br)
+			svc := NewWebSocketService(nil, mr, nil, nil, nil, br, nil, &config.ServerConfig{})
+
+			_, gotErr := svc.EditMessage(context.Background(), sender, messageId, testCond.payload)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "EditMessage returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			mr.AssertExpectations(t)
+			br.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteMessage(t *testing.T) {
+	ctx := context.Background()
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	offlineId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa47"
+	strangerId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48"
+	messageId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa50"
+	cr := new(mocks.ConnectionsRepository)
+	mr := new(mocks.MessagesRepository)
+	recipient, stranger := new(mocks.ConnHelper), new(mocks.ConnHelper)
+	mr.On("FindMessage", ctx, messageId).Return(&models.Message{Id: "c1", OriginId: messageId, SenderId: sender.Id, Payload: "oops", History: []*models.MessageRevision{{Payload: "oop"}}}, nil)
+	mr.On("UpdateMessageContent", ctx, mock.MatchedBy(func(msg *models.Message) bool {
+		return msg.IsDeleted() && msg.Payload == "" && msg.History == nil
+	}), 1).Return(nil)
+	mr.On("FindMessageReceipts", ctx, sender.Id, messageId).Return([]*models.Message{{RecipientId: recipientId}, {RecipientId: offlineId}}, nil)
+	cr.On("GetAllConnections", ctx).Return(map[string][]ws.ConnHelper{recipientId: {recipient}, strangerId: {stranger}}, nil)
+	recipient.On("Protocol").Return(ws.ProtocolJSON)
+	recipient.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+		return strings.Contains(string(data), `"type":"delete"`) && strings.Contains(string(data), `"id":"`+messageId+`"`)
+	})).Return(nil).Once()
+	mr.On("MarkUpdateDelivered", ctx, recipientId, messageId).Return(nil).Once()
+	svc := NewWebSocketService(cr, mr, nil, nil, nil, broker.NewInMemoryBroker(), nil, &config.ServerConfig{})
+
+	gotErr := svc.DeleteMessage(ctx, sender, messageId)
+
+	assert.Nil(t, gotErr, "DeleteMessage returned unexpected result: got error %v want %v", gotErr, nil)
+
+	cr.AssertExpectations(t)
+	mr.AssertExpectations(t)
+	recipient.AssertExpectations(t)
+	stranger.AssertExpectations(t)
+}
+
+func TestHandleUpdateFrame(t *testing.T) {
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	mr := new(mocks.MessagesRepository)
+	wc := new(mocks.ConnHelper)
+	mr.On("FindMessage", mock.Anything, "m1").Return(nil, repositories.ErrMessageNotFound)
+	wc.On("Protocol").Return(ws.ProtocolJSON)
+	wc.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+		return strings.Contains(string(data), `"type":"error"`) && strings.Contains(string(data), `"replyTo":"m1"`)
+	})).Return(nil).Once()
+	svc := NewWebSocketService(nil, mr, nil, nil, nil, broker.NewInMemoryBroker(), nil, &config.ServerConfig{}).(*webSocketService)
+
+	gotErr := svc.handleUpdateFrame(context.Background(), wc, &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeDelete, Id: "m1"}, sender)
+
+	assert.Nil(t, gotErr, "handleUpdateFrame returned unexpected result: got error %v want %v", gotErr, nil)
+
+	mr.AssertExpectations(t)
+	wc.AssertExpectations(t)
+}
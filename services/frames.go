@@ -0,0 +1,107 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/models"
+	"github.com/gorilla/websocket"
+)
+
+var ErrMalformedFrame = errors.New("frame is not a valid JSON envelope")
+var ErrUnsupportedFrameVersion = fmt.Errorf("frame version is not supported, expected %d", models.FrameVersion)
+var ErrUnsupportedFrameType = errors.New("frame type is not supported")
+var ErrEmptyFramePayload = errors.New("frame payload is empty")
+var ErrFramePayloadTooLong = fmt.Errorf("frame payload is longer than %d bytes", models.FramePayloadMaxLength)
+var ErrAmbiguousFrameTarget = errors.New("frame can not target both room and recipient")
+var ErrMissingFrameId = errors.New("frame id is required")
+
+// readFrame decodes inbound web socket message according to the connection protocol.
+// Returned frame is not nil when envelope was parsed but failed validation.
+func readFrame(conn ws.ConnHelper, data []byte) (*models.Frame, error) {
+	if conn.Protocol() == ws.ProtocolLegacy {
+		frame := &models.Frame{
+			Version: models.FrameVersion,
+			Type:    models.FrameTypeMessage,
+			Payload: string(data),
+		}
+		return frame, validateFrame(frame)
+	}
+
+	frame := &models.Frame{}
+	if err := json.Unmarshal(data, frame); err != nil {
+		return nil, ErrMalformedFrame
+	}
+	return frame, validateFrame(frame)
+}
+
+// writeFrame encodes outbound frame according to the connection protocol.
+// Legacy clients receive bare payloads of message frames only.
+func writeFrame(conn ws.ConnHelper, frame *models.Frame) error {
+	if conn.Protocol() == ws.ProtocolLegacy {
+		if frame.Type != models.FrameTypeMessage {
+			return nil
+		}
+		return conn.WriteMessage(websocket.TextMessage, []byte(frame.Payload))
+	}
+
+	data, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func validateFrame(frame *models.Frame) error {
+	if frame.Version != models.FrameVersion {
+		return ErrUnsupportedFrameVersion
+	}
+	switch frame.Type {
+	case models.FrameTypeMessage:
+		return validateMessageFrame(frame)
+	case models.FrameTypeRead:
+		if frame.Id == "" {
+			return ErrMissingFrameId
+		}
+		return nil
+	case models.FrameTypeEdit:
+		if frame.Id == "" {
+			return ErrMissingFrameId
+		}
+		return validatePayload(frame.Payload)
+	case models.FrameTypeDelete:
+		if frame.Id == "" {
+			return ErrMissingFrameId
+		}
+		return nil
+	case models.FrameTypeTyping:
+		if frame.RoomId != "" && frame.RecipientId != "" {
+			return ErrAmbiguousFrameTarget
+		}
+		return nil
+	default:
+		return ErrUnsupportedFrameType
+	}
+}
+
+func validateMessageFrame(frame *models.Frame) error {
+	if err := validatePayload(frame.Payload); err != nil {
+		return err
+	}
+	if frame.RoomId != "" && frame.RecipientId != "" {
+		return ErrAmbiguousFrameTarget
+	}
+	return nil
+}
+
+func validatePayload(payload string) error {
+	if len(payload) == 0 {
+		return ErrEmptyFramePayload
+	}
+	if len(payload) > models.FramePayloadMaxLength {
+		return ErrFramePayloadTooLong
+	}
+	return nil
+}
@@ -0,0 +1,341 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newTestFrame(roomId, recipientId, payload string) *models.Frame {
+	return &models.Frame{
+		Version:     models.FrameVersion,
+		Type:        models.FrameTypeMessage,
+		RoomId:      roomId,
+		RecipientId: recipientId,
+		Payload:     payload,
+	}
+}
+
+func TestReadFrame(t *testing.T) {
+	testConditions := []struct {
+		tName     string
+		protocol  ws.Protocol
+		data      string
+		wantFrame *models.Frame
+		wantErr   error
+	}{
+		{
+			tName:     "should wrap legacy text into message frame",
+			protocol:  ws.ProtocolLegacy,
+			data:      "hello",
+			wantFrame: newTestFrame("", "", "hello"),
+		},
+		{
+			tName:    "should parse json envelope",
+			protocol: ws.ProtocolJSON,
+			data:     `{"v":1,"type":"message","id":"c1","roomId":"r1","payload":"hello"}`,
+			wantFrame: &models.Frame{
+				Version: models.FrameVersion,
+				Type:    models.FrameTypeMessage,
+				Id:      "c1",
+				RoomId:  "r1",
+				Payload: "hello",
+			},
+		},
+		{
+			tName:    "should fail with malformed frame error",
+			protocol: ws.ProtocolJSON,
+			data:     "hello",
+			wantErr:  ErrMalformedFrame,
+		},
+		{
+			tName:     "should fail with unsupported version error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":2,"type":"message","payload":"hello"}`,
+			wantFrame: &models.Frame{Version: 2, Type: models.FrameTypeMessage, Payload: "hello"},
+			wantErr:   ErrUnsupportedFrameVersion,
+		},
+		{
+			tName:     "should fail with unsupported type error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"ack","payload":"hello"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeAck, Payload: "hello"},
+			wantErr:   ErrUnsupportedFrameType,
+		},
+		{
+			tName:     "should fail with empty payload error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"message"}`,
+			wantFrame: newTestFrame("", "", ""),
+			wantErr:   ErrEmptyFramePayload,
+		},
+		{
+			tName:     "should fail with too long payload error",
+			protocol:  ws.ProtocolLegacy,
+			data:      strings.Repeat("a", models.FramePayloadMaxLength+1),
+			wantFrame: newTestFrame("", "", strings.Repeat("a", models.FramePayloadMaxLength+1)),
+			wantErr:   ErrFramePayloadTooLong,
+		},
+		{
+			tName:     "should parse read frame",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"read","id":"m1"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeRead, Id: "m1"},
+		},
+		{
+			tName:     "should fail with missing id error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"read"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeRead},
+			wantErr:   ErrMissingFrameId,
+		},
+		{
+			tName:     "should parse edit frame",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"edit","id":"m1","payload":"hello"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeEdit, Id: "m1", Payload: "hello"},
+		},
+		{
+			tName:     "should fail edit frame with empty payload error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"edit","id":"m1"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeEdit, Id: "m1"},
+			wantErr:   ErrEmptyFramePayload,
+		},
+		{
+			tName:     "should fail delete frame with missing id error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"delete"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeDelete},
+			wantErr:   ErrMissingFrameId,
+		},
+		{
+			tName:     "should parse typing frame",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"typing","roomId":"r1"}`,
+			wantFrame: &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeTyping, RoomId: "r1"},
+		},
+		{
+			tName:     "should fail with ambiguous target error",
+			protocol:  ws.ProtocolJSON,
+			data:      `{"v":1,"type":"message","roomId":"r1","recipientId":"u1","payload":"hello"}`,
+			wantFrame: newTestFrame("r1", "u1", "hello"),
+			wantErr:   ErrAmbiguousFrameTarget,
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			wc := new(mocks.ConnHelper)
+			wc.On("Protocol").Return(testCond.protocol)
+
+			gotFrame, gotErr := readFrame(wc, []byte(testCond.data))
+
+			assert.Equal(t, testCond.wantErr, gotErr, "readFrame returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantFrame, gotFrame, "readFrame returned unexpected result: got frame %v want %v", gotFrame, testCond.wantFrame)
+
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWriteFrame(t *testing.T) {
+	testConditions := []struct {
+		tName        string
+		frame        *models.Frame
+		prepareMocks func(*mocks.ConnHelper)
+	}{
+		{
+			tName: "should write json envelope",
+			frame: models.NewAckFrame("s1", "c1"),
+			prepareMocks: func(wc *mocks.ConnHelper) {
+				wc.On("Protocol").Return(ws.ProtocolJSON)
+				wc.On("WriteMessage", websocket.TextMessage, []byte(`{"v":1,"type":"ack","id":"s1","replyTo":"c1"}`)).Return(nil)
+			},
+		},
+		{
+			tName: "should write bare payload for legacy client",
+			frame: newTestFrame("", "", "hello"),
+			prepareMocks: func(wc *mocks.ConnHelper) {
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
+				wc.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil)
+			},
+		},
+		{
+			tName: "should skip service frames for legacy client",
+			frame: models.NewErrorFrame("c1", "oops"),
+			prepareMocks: func(wc *mocks.ConnHelper) {
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			wc := new(mocks.ConnHelper)
+			testCond.prepareMocks(wc)
+
+			gotErr := writeFrame(wc, testCond.frame)
+
+			assert.Nil(t, gotErr, "writeFrame returned unexpected result: got error %v want %v", gotErr, nil)
+
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleMessageFrame(t *testing.T) {
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	testConditions := []struct {
+		tName        string
+		frame        *models.Frame
+		wantType     string
+		prepareMocks func(*mocks.ConnectionsRepository, *mocks.MessagesRepository, *mocks.UsersRepository)
+	}{
+		{
+			tName:    "should acknowledge delivered message",
+			frame:    &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeMessage, Id: "c1", RecipientId: recipientId, Payload: "hello"},
+			wantType: models.FrameTypeAck,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, recipientId).Return(&models.User{Id: recipientId}, nil)
+				cr.On("GetUserConnections", mock.Anything, recipientId).Return(nil, nil)
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("1", nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipientId}).Return([]*models.Message{{Id: "c1", RecipientId: recipientId}}, nil)
+			},
+		},
+		{
+			tName:    "should reply with error frame when recipient does not exist",
+			frame:    &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeMessage, Id: "c1", RecipientId: recipientId, Payload: "hello"},
+			wantType: models.FrameTypeError,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, recipientId).Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			cr := new(mocks.ConnectionsRepository)
+			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
+			ur := new(mocks.UsersRepository)
+			wu := new(mocks.UpgraderHelper)
+			wc := new(mocks.ConnHelper)
+			testCond.prepareMocks(cr, mr, ur)
+			wc.On("Protocol").Return(ws.ProtocolJSON)
+			wc.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+				return strings.Contains(string(data), `"type":"`+testCond.wantType+`"`) && strings.Contains(string(data), `"replyTo":"c1"`)
+			})).Return(nil).Once()
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), new(mocks.PresenceRepository), &config.ServerConfig{}).(*webSocketService)
+
+			gotErr := svc.handleMessageFrame(context.Background(), wc, testCond.frame, sender)
+
+			assert.Nil(t, gotErr, "handleMessageFrame returned unexpected result: got error %v want %v", gotErr, nil)
+
+			cr.AssertExpectations(t)
+			mr.AssertExpectations(t)
+			ur.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleReadFrame(t *testing.T) {
+	reader := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	testConditions := []struct {
+		tName        string
+		wantType     string
+		prepareMocks func(*mocks.ConnectionsRepository, *mocks.MessagesRepository)
+	}{
+		{
+			tName:    "should acknowledge read message",
+			wantType: models.FrameTypeAck,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository) {
+				mr.On("MarkMessageRead", mock.Anything, reader.Id, "m1", mock.Anything).Return(&models.Message{Id: "c1", OriginId: "m1", SenderId: "sender"}, nil)
+				cr.On("GetUserConnections", mock.Anything, "sender").Return(nil, nil)
+			},
+		},
+		{
+			tName:    "should reply with error frame when message does not exist",
+			wantType: models.FrameTypeError,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository) {
+				mr.On("MarkMessageRead", mock.Anything, reader.Id, "m1", mock.Anything).Return(nil, repositories.ErrMessageNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			cr := new(mocks.ConnectionsRepository)
+			mr := new(mocks.MessagesRepository)
+			wc := new(mocks.ConnHelper)
+			testCond.prepareMocks(cr, mr)
+			wc.On("Protocol").Return(ws.ProtocolJSON)
+			wc.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+				return strings.Contains(string(data), `"type":"`+testCond.wantType+`"`) && strings.Contains(string(data), `"replyTo":"m1"`)
+			})).Return(nil).Once()
+			svc := NewWebSocketService(cr, mr, nil, nil, nil, broker.NewInMemoryBroker(), nil, &config.ServerConfig{}).(*webSocketService)
+
+			gotErr := svc.handleReadFrame(context.Background(), wc, &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeRead, Id: "m1"}, reader)
+
+			assert.Nil(t, gotErr, "handleReadFrame returned unexpected result: got error %v want %v", gotErr, nil)
+
+			cr.AssertExpectations(t)
+			mr.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAllowFrame(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	other := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46", UserName: "bar"}
+	cnf := &config.ServerConfig{WsMessageRateLimitPerSecond: 1, WsMessageRateLimitBurst: 1}
+	svc := NewWebSocketService(nil, nil, nil, nil, nil, broker.NewInMemoryBroker(), nil, cnf).(*webSocketService)
+	testConditions := []struct {
+		tName     string
+		frameType string
+		usr       *models.User
+		wantOk    bool
+	}{
+		{tName: "should allow first message", frameType: models.FrameTypeMessage, usr: usr, wantOk: true},
+		{tName: "should reject edit over the limit", frameType: models.FrameTypeEdit, usr: usr, wantOk: false},
+		{tName: "should allow read frame over the limit", frameType: models.FrameTypeRead, usr: usr, wantOk: true},
+		{tName: "should allow typing frame over the limit", frameType: models.FrameTypeTyping, usr: usr, wantOk: true},
+		{tName: "should allow message of another user", frameType: models.FrameTypeMessage, usr: other, wantOk: true},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			gotOk, gotWait := svc.allowFrame(&models.Frame{Type: testCond.frameType}, testCond.usr)
+
+			assert.Equal(t, testCond.wantOk, gotOk, "allowFrame returned unexpected result: got %v want %v", gotOk, testCond.wantOk)
+			if !gotOk {
+				assert.Greater(t, int64(gotWait), int64(0), "allowFrame returned unexpected wait: got %v", gotWait)
+			}
+		})
+	}
+}
+
+func TestRateLimitFrame(t *testing.T) {
+	wc := new(mocks.ConnHelper)
+	wc.On("Protocol").Return(ws.ProtocolJSON)
+	wc.On("WriteMessage", websocket.TextMessage, []byte(`{"v":1,"type":"error","replyTo":"c1","payload":"rate limit exceeded","retryAfter":2}`)).Return(nil)
+
+	gotErr := writeFrame(wc, models.NewRateLimitFrame("c1", ErrRateLimited.Error(), 1500*time.Millisecond))
+
+	assert.Nil(t, gotErr, "writeFrame returned unexpected result: got error %v want %v", gotErr, nil)
+	wc.AssertExpectations(t)
+}
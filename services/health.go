@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/mongo"
+	"github.com/andriystech/lgc/models"
+)
+
+// Names of dependencies checked by the readiness probe.
+const (
+	DependencyMongo = "mongo"
+)
+
+// HealthService answers liveness and readiness probes. The server is live as long as it
+// is able to answer, and ready when all its dependencies are reachable and it is not shutting down.
+type HealthService interface {
+	Live(context.Context) *models.Health
+	Ready(context.Context) *models.Health
+	Shutdown()
+}
+
+type healthService struct {
+	checks       map[string]func(context.Context) error
+	timeout      time.Duration
+	shuttingDown int32
+}
+
+func NewHealthService(db mongo.ClientHelper, cnf *config.ServerConfig) HealthService {
+	return &healthService{
+		checks: map[string]func(context.Context) error{
+			DependencyMongo: db.Ping,
+		},
+		timeout: cnf.HealthCheckTimeout,
+	}
+}
+
+func (svc *healthService) Live(ctx context.Context) *models.Health {
+	return &models.Health{Status: models.HealthStatusOk}
+}
+
+// Ready checks every dependency within the health check timeout. Dependencies are checked
+// during shutdown as well, so probes still show which of them are reachable.
+func (svc *healthService) Ready(ctx context.Context) *models.Health {
+	health := &models.Health{
+		Status:       models.HealthStatusOk,
+		Dependencies: make(map[string]*models.DependencyHealth, len(svc.checks)),
+	}
+	for name, check := range svc.checks {
+		dependency := svc.check(ctx, check)
+		if dependency.Status != models.HealthStatusOk {
+			health.Status = models.HealthStatusFail
+		}
+		health.Dependencies[name] = dependency
+	}
+	if atomic.LoadInt32(&svc.shuttingDown) == 1 {
+		health.Status = models.HealthStatusShuttingDown
+	}
+	return health
+}
+
+func (svc *healthService) check(ctx context.Context, check func(context.Context) error) *models.DependencyHealth {
+	ctx, cancel := context.WithTimeout(ctx, svc.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check(ctx)
+	dependency := &models.DependencyHealth{Status: models.HealthStatusOk, Latency: time.Since(start)}
+	if err != nil {
+		dependency.Status = models.HealthStatusFail
+		dependency.Error = err.Error()
+	}
+	return dependency
+}
+
+// Shutdown makes the server not ready, so load balancers stop routing new requests to it.
+func (svc *healthService) Shutdown() {
+	atomic.StoreInt32(&svc.shuttingDown, 1)
+}
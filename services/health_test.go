@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testHealthConfig = &config.ServerConfig{HealthCheckTimeout: time.Second}
+
+func TestReady(t *testing.T) {
+	testConditions := []struct {
+		tName        string
+		shutdown     bool
+		wantStatus   string
+		wantMongo    string
+		wantError    string
+		prepareMocks func(*mocks.ClientHelper)
+	}{
+		{
+			tName:      "should be ready when database is reachable",
+			wantStatus: models.HealthStatusOk,
+			wantMongo:  models.HealthStatusOk,
+			prepareMocks: func(db *mocks.ClientHelper) {
+				db.On("Ping", mock.Anything).Return(nil)
+			},
+		},
+		{
+			tName:      "should not be ready when database is unreachable",
+			wantStatus: models.HealthStatusFail,
+			wantMongo:  models.HealthStatusFail,
+			wantError:  "server selection timeout",
+			prepareMocks: func(db *mocks.ClientHelper) {
+				db.On("Ping", mock.Anything).Return(errors.New("server selection timeout"))
+			},
+		},
+		{
+			tName:      "should not be ready during shutdown",
+			shutdown:   true,
+			wantStatus: models.HealthStatusShuttingDown,
+			wantMongo:  models.HealthStatusOk,
+			prepareMocks: func(db *mocks.ClientHelper) {
+				db.On("Ping", mock.Anything).Return(nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			db := new(mocks.ClientHelper)
+			testCond.prepareMocks(db)
+			svc := NewHealthService(db, testHealthConfig)
+			if testCond.shutdown {
+				svc.Shutdown()
+			}
+
+			got := svc.Ready(context.Background())
+
+			assert.Equal(t, testCond.wantStatus, got.Status, "Ready returned unexpected status: got %v want %v", got.Status, testCond.wantStatus)
+			mongo := got.Dependencies[DependencyMongo]
+			if assert.NotNil(t, mongo, "Ready returned no mongo check") {
+				assert.Equal(t, testCond.wantMongo, mongo.Status, "Ready returned unexpected mongo status: got %v want %v", mongo.Status, testCond.wantMongo)
+				assert.Equal(t, testCond.wantError, mongo.Error, "Ready returned unexpected mongo error: got %v want %v", mongo.Error, testCond.wantError)
+			}
+			db.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReadyTimeout(t *testing.T) {
+	db := new(mocks.ClientHelper)
+	db.On("Ping", mock.Anything).Return(func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	svc := NewHealthService(db, &config.ServerConfig{HealthCheckTimeout: 10 * time.Millisecond})
+
+	got := svc.Ready(context.Background())
+
+	assert.Equal(t, models.HealthStatusFail, got.Status, "Ready returned unexpected status: got %v want %v", got.Status, models.HealthStatusFail)
+	assert.Equal(t, context.DeadlineExceeded.Error(), got.Dependencies[DependencyMongo].Error, "Ready returned unexpected mongo error: got %v want %v", got.Dependencies[DependencyMongo].Error, context.DeadlineExceeded)
+}
+
+func TestLive(t *testing.T) {
+	svc := NewHealthService(new(mocks.ClientHelper), testHealthConfig)
+	svc.Shutdown()
+
+	got := svc.Live(context.Background())
+
+	assert.Equal(t, models.HealthStatusOk, got.Status, "Live returned unexpected status: got %v want %v", got.Status, models.HealthStatusOk)
+}
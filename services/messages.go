@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/models"
+)
+
+type MessageService interface {
+	GetDirectMessages(context.Context, string, string) ([]*models.Message, error)
+	GetMessages(context.Context, *models.MessagesQuery) (*models.MessagesPage, error)
+	GetMessageReceipts(context.Context, string, string) ([]*models.Message, error)
+}
+
+type messageService struct {
+	storage repositories.MessagesRepository
+}
+
+func NewMessageService(storage repositories.MessagesRepository) MessageService {
+	return &messageService{
+		storage: storage,
+	}
+}
+
+func (svc *messageService) GetDirectMessages(ctx context.Context, userId, peerId string) ([]*models.Message, error) {
+	return svc.storage.FindDirectMessages(ctx, userId, peerId)
+}
+
+// GetMessages returns a page of recipient's messages and a cursor of the next page if there is one.
+func (svc *messageService) GetMessages(ctx context.Context, q *models.MessagesQuery) (*models.MessagesPage, error) {
+	pageQuery := *q
+	pageQuery.Limit = q.Limit + 1
+	messages, err := svc.storage.FindMessages(ctx, &pageQuery)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.MessagesPage{Messages: messages}
+	if len(messages) > q.Limit {
+		page.Messages = messages[:q.Limit]
+		page.NextCursor = models.NewMessageCursor(page.Messages[q.Limit-1])
+	}
+	return page, nil
+}
+
+// GetMessageReceipts returns delivery state of every recipient's copy of the message sent by the sender.
+func (svc *messageService) GetMessageReceipts(ctx context.Context, senderId, messageId string) ([]*models.Message, error) {
+	return svc.storage.FindMessageReceipts(ctx, senderId, messageId)
+}
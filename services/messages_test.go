@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDirectMessages(t *testing.T) {
+	ctx := context.Background()
+	mr := new(mocks.MessagesRepository)
+	msgs := []*models.Message{{Id: "1", Payload: "hello"}}
+	mr.On("FindDirectMessages", ctx, "user", "peer").Return(msgs, nil)
+	svc := NewMessageService(mr)
+
+	gotMsgs, gotErr := svc.GetDirectMessages(ctx, "user", "peer")
+
+	assert.Nil(t, gotErr, "GetDirectMessages returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, msgs, gotMsgs, "GetDirectMessages returned unexpected result: got messages %v want %v", gotMsgs, msgs)
+
+	mr.AssertExpectations(t)
+}
+
+func TestGetMessages(t *testing.T) {
+	msgs := []*models.Message{{Id: "3", Time: 30}, {Id: "2", Time: 20}, {Id: "1", Time: 10}}
+	testConditions := []struct {
+		tName    string
+		limit    int
+		found    []*models.Message
+		wantPage *models.MessagesPage
+	}{
+		{
+			tName:    "should return last page without cursor",
+			limit:    3,
+			found:    msgs,
+			wantPage: &models.MessagesPage{Messages: msgs},
+		},
+		{
+			tName:    "should return page with cursor of the last message",
+			limit:    2,
+			found:    msgs,
+			wantPage: &models.MessagesPage{Messages: msgs[:2], NextCursor: &models.MessageCursor{Time: 20, Id: "2"}},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			mr := new(mocks.MessagesRepository)
+			mr.On("FindMessages", ctx, &models.MessagesQuery{RecipientId: "user", Limit: testCond.limit + 1}).Return(testCond.found, nil)
+			svc := NewMessageService(mr)
+
+			gotPage, gotErr := svc.GetMessages(ctx, &models.MessagesQuery{RecipientId: "user", Limit: testCond.limit})
+
+			assert.Nil(t, gotErr, "GetMessages returned unexpected result: got error %v want %v", gotErr, nil)
+			assert.Equal(t, testCond.wantPage, gotPage, "GetMessages returned unexpected result: got page %v want %v", gotPage, testCond.wantPage)
+
+			mr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMessageReceipts(t *testing.T) {
+	ctx := context.Background()
+	mr := new(mocks.MessagesRepository)
+	msgs := []*models.Message{{Id: "1", OriginId: "m1", RecipientId: "peer", ReadAt: 10}}
+	mr.On("FindMessageReceipts", ctx, "user", "m1").Return(msgs, nil)
+	svc := NewMessageService(mr)
+
+	gotMsgs, gotErr := svc.GetMessageReceipts(ctx, "user", "m1")
+
+	assert.Nil(t, gotErr, "GetMessageReceipts returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, msgs, gotMsgs, "GetMessageReceipts returned unexpected result: got messages %v want %v", gotMsgs, msgs)
+
+	mr.AssertExpectations(t)
+}
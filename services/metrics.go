@@ -0,0 +1,59 @@
+package services
+
+import "github.com/andriystech/lgc/pkg/metrics"
+
+// Kinds of stored messages.
+const (
+	messageKindBroadcast = "broadcast"
+	messageKindRoom      = "room"
+	messageKindDirect    = "direct"
+)
+
+// Reasons of failed messages.
+const (
+	messageFailureRejected    = "rejected"
+	messageFailureRateLimited = "rate_limited"
+	messageFailureError       = "error"
+	messageFailureDelivery    = "delivery"
+)
+
+// Types of tokens, one time tokens are exchanged for web socket connections.
+const (
+	tokenTypeOneTime = "one_time"
+)
+
+var (
+	activeConnections = metrics.NewGauge(
+		"lgc_ws_active_connections",
+		"Web socket connections held by the instance.",
+	)
+	messagesSent = metrics.NewCounter(
+		"lgc_messages_sent_total",
+		"Messages written to sessions of recipients connected to the instance.",
+	)
+	messagesStored = metrics.NewCounter(
+		"lgc_messages_stored_total",
+		"Messages received from senders and stored, by kind: broadcast, room or direct.",
+		"kind",
+	)
+	messagesFailed = metrics.NewCounter(
+		"lgc_messages_failed_total",
+		"Messages which were not stored or delivered, by reason: rejected, rate_limited, error or delivery.",
+		"reason",
+	)
+	tokensIssued = metrics.NewCounter(
+		"lgc_tokens_issued_total",
+		"Issued tokens by type: one_time, access or refresh.",
+		"type",
+	)
+	tokensConsumed = metrics.NewCounter(
+		"lgc_tokens_consumed_total",
+		"One time tokens exchanged for web socket connections and refresh tokens rotated, by type.",
+		"type",
+	)
+	tokensExpired = metrics.NewCounter(
+		"lgc_tokens_expired_total",
+		"Expired tokens presented by clients, by type.",
+		"type",
+	)
+)
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+)
+
+// typingThrottlePruneSize is a number of tracked senders after which expired entries are removed.
+const typingThrottlePruneSize = 1024
+
+// typingThrottle lets through at most one typing frame per sender and target within the interval.
+type typingThrottle struct {
+	interval time.Duration
+	last     map[string]time.Time
+	mu       *sync.Mutex
+}
+
+func newTypingThrottle(interval time.Duration) *typingThrottle {
+	return &typingThrottle{
+		interval: interval,
+		last:     map[string]time.Time{},
+		mu:       &sync.Mutex{},
+	}
+}
+
+func (t *typingThrottle) allow(key string, now time.Time) bool {
+	if t.interval <= 0 {
+		return true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if last, ok := t.last[key]; ok && now.Sub(last) < t.interval {
+		return false
+	}
+	if len(t.last) >= typingThrottlePruneSize {
+		for k, last := range t.last {
+			if now.Sub(last) >= t.interval {
+				delete(t.last, k)
+			}
+		}
+	}
+	t.last[key] = now
+	return true
+}
+
+// SendTypingIndicator notifies target of the frame that the sender is typing.
+// Frames sent more often than the throttle interval are silently dropped.
+func (svc *webSocketService) SendTypingIndicator(ctx context.Context, frame *models.Frame, sender *models.User) error {
+	var recipientIds []string
+	if frame.RoomId != "" {
+		room, err := svc.rooms.FindRoomById(ctx, frame.RoomId)
+		if err != nil {
+			return err
+		}
+		if !room.HasMember(sender.Id) {
+			return ErrNotRoomMember
+		}
+		recipientIds = room.Members
+	}
+
+	key := sender.Id + "/" + frame.RoomId + "/" + frame.RecipientId
+	now := time.Now()
+	if !svc.typing.allow(key, now) {
+		return nil
+	}
+	return svc.broker.Publish(ctx, models.NewTypingEvent(models.NewTypingFrame(frame, sender, now.Unix()), sender, recipientIds))
+}
+
+// handleTypingFrame reports invalid targets back to the client, typing frames are not acknowledged.
+func (svc *webSocketService) handleTypingFrame(ctx context.Context, conn ws.ConnHelper, frame *models.Frame, sender *models.User) error {
+	err := svc.SendTypingIndicator(ctx, frame, sender)
+	if errors.Is(err, ErrNotRoomMember) || errors.Is(err, repositories.ErrRoomNotFound) {
+		return writeFrame(conn, models.NewErrorFrame(frame.Id, err.Error()))
+	}
+	return err
+}
+
+// notifyPresence publishes presence change when the first session of the user
+// is opened or the last one is closed on any instance of the server.
+func (svc *webSocketService) notifyPresence(ctx context.Context, usr *models.User, status string) {
+	sessions, err := svc.presence.GetUserSessions(ctx, usr.Id)
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to check presence of user", "userId", usr.Id, "err", err)
+		return
+	}
+	if (status == models.PresenceJoined && len(sessions) != 1) || (status == models.PresenceLeft && len(sessions) != 0) {
+		return
+	}
+
+	frame := models.NewPresenceFrame(usr, status, time.Now().Unix())
+	if err = svc.broker.Publish(ctx, models.NewPresenceEvent(frame, usr)); err != nil {
+		logger.FromContext(ctx).Error("Unable to publish presence of user", "userId", usr.Id, "err", err)
+	}
+}
+
+// deliverEphemeral writes frame of presence or typing event to local connections of its recipients.
+func (svc *webSocketService) deliverEphemeral(ctx context.Context, event *models.Event) error {
+	cs, err := svc.connections.GetAllConnections(ctx)
+	if err != nil {
+		return err
+	}
+
+	for rId, conns := range cs {
+		if event.SenderId == rId || !event.IsRecipient(rId) {
+			continue
+		}
+		for _, conn := range conns {
+			if err = writeFrame(conn, event.Frame); err != nil {
+				logger.FromContext(ctx).Error("Unable to deliver frame", "type", event.Frame.Type, "recipientId", rId, "err", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/facilities/broker"
+	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTypingThrottle(t *testing.T) {
+	throttle := newTypingThrottle(3 * time.Second)
+	now := time.Now()
+
+	testConditions := []struct {
+		tName string
+		key   string
+		at    time.Time
+		want  bool
+	}{
+		{tName: "should allow first frame", key: "u1/r1/", at: now, want: true},
+		{tName: "should drop frame within interval", key: "u1/r1/", at: now.Add(time.Second), want: false},
+		{tName: "should allow frame to another target", key: "u1/r2/", at: now.Add(time.Second), want: true},
+		{tName: "should allow frame after interval", key: "u1/r1/", at: now.Add(3 * time.Second), want: true},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			got := throttle.allow(testCond.key, testCond.at)
+
+			assert.Equal(t, testCond.want, got, "allow returned unexpected result: got %v want %v", got, testCond.want)
+		})
+	}
+}
+
+func TestSendTypingIndicator(t *testing.T) {
+	ctx := context.Background()
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	memberId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	strangerId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa47"
+	room := &models.Room{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", Members: []string{sender.Id, memberId}}
+	cr := new(mocks.ConnectionsRepository)
+	mr := new(mocks.MessagesRepository)
+	rr := new(mocks.RoomsRepository)
+	member, stranger := new(mocks.ConnHelper), new(mocks.ConnHelper)
+	rr.On("FindRoomById", ctx, room.Id).Return(room, nil)
+	cr.On("GetAllConnections", ctx).Return(map[string][]ws.ConnHelper{memberId: {member}, strangerId: {stranger}}, nil).Once()
+	member.On("Protocol").Return(ws.ProtocolJSON)
+	member.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+		return strings.Contains(string(data), `"type":"typing"`) && strings.Contains(string(data), `"senderId":"`+sender.Id+`"`)
+	})).Return(nil).Once()
+	svc := NewWebSocketService(cr, mr, rr, nil, nil, broker.NewInMemoryBroker(), nil, &config.ServerConfig{TypingThrottle: time.Minute})
+	frame := &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeTyping, RoomId: room.Id}
+
+	for i := 0; i < 3; i++ {
+		gotErr := svc.SendTypingIndicator(ctx, frame, sender)
+
+		assert.Nil(t, gotErr, "SendTypingIndicator returned unexpected result: got error %v want %v", gotErr, nil)
+	}
+
+	cr.AssertExpectations(t)
+	mr.AssertExpectations(t)
+	member.AssertExpectations(t)
+	stranger.AssertExpectations(t)
+}
+
+func TestSendTypingIndicatorToForeignRoom(t *testing.T) {
+	ctx := context.Background()
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	room := &models.Room{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", Members: []string{"14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"}}
+	rr := new(mocks.RoomsRepository)
+	br := new(mocks.Broker)
+	br.On("Subscribe", mock.Anything).Return()
+	rr.On("FindRoomById", ctx, room.Id).Return(room, nil)
+	svc := NewWebSocketService(nil, nil, rr, nil, nil, br, nil, &config.ServerConfig{})
+
+	gotErr := svc.SendTypingIndicator(ctx, &models.Frame{Version: models.FrameVersion, Type: models.FrameTypeTyping, RoomId: room.Id}, sender)
+
+	assert.Equal(t, ErrNotRoomMember, gotErr, "SendTypingIndicator returned unexpected result: got error %v want %v", gotErr, ErrNotRoomMember)
+	br.AssertExpectations(t)
+	rr.AssertExpectations(t)
+}
+
+func TestNotifyPresence(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	session := &models.Session{Id: "s1", UserId: usr.Id}
+	testConditions := []struct {
+		tName       string
+		status      string
+		sessions    []*models.Session
+		wantPublish bool
+	}{
+		{tName: "should publish when first session is opened", status: models.PresenceJoined, sessions: []*models.Session{session}, wantPublish: true},
+		{tName: "should skip when another session is opened", status: models.PresenceJoined, sessions: []*models.Session{session, {Id: "s2", UserId: usr.Id}}},
+		{tName: "should publish when last session is closed", status: models.PresenceLeft, sessions: []*models.Session{}, wantPublish: true},
+		{tName: "should skip when other sessions remain", status: models.PresenceLeft, sessions: []*models.Session{session}},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			pr := new(mocks.PresenceRepository)
+			br := new(mocks.Broker)
+			br.On("Subscribe", mock.Anything).Return()
+			pr.On("GetUserSessions", ctx, usr.Id).Return(testCond.sessions, nil)
+			if testCond.wantPublish {
+				br.On("Publish", ctx, mock.MatchedBy(func(event *models.Event) bool {
+					return event.Type == models.EventTypePresence && event.SenderId == usr.Id && event.Frame.Payload == testCond.status
+				})).Return(nil)
+			}
+			svc := NewWebSocketService(nil, nil, nil, nil, nil, br, pr, &config.ServerConfig{}).(*webSocketService)
+
+			svc.notifyPresence(ctx, usr, testCond.status)
+
+			br.AssertExpectations(t)
+			pr.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/models"
+	"github.com/google/uuid"
+)
+
+type RoomService interface {
+	CreateRoom(context.Context, string, string) (*models.Room, error)
+	ListRooms(context.Context) ([]*models.Room, error)
+	JoinRoom(context.Context, string, string) error
+	LeaveRoom(context.Context, string, string) error
+}
+
+type roomService struct {
+	storage repositories.RoomsRepository
+}
+
+func NewRoomService(storage repositories.RoomsRepository) RoomService {
+	return &roomService{
+		storage: storage,
+	}
+}
+
+func (svc *roomService) CreateRoom(ctx context.Context, name, ownerId string) (*models.Room, error) {
+	room := models.NewRoom(uuid.NewString(), name, ownerId)
+	if _, err := svc.storage.SaveRoom(ctx, room); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+func (svc *roomService) ListRooms(ctx context.Context) ([]*models.Room, error) {
+	return svc.storage.FindRooms(ctx)
+}
+
+func (svc *roomService) JoinRoom(ctx context.Context, roomId, userId string) error {
+	return svc.storage.AddMember(ctx, roomId, userId)
+}
+
+func (svc *roomService) LeaveRoom(ctx context.Context, roomId, userId string) error {
+	return svc.storage.RemoveMember(ctx, roomId, userId)
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/andriystech/lgc/mocks"
+	"github.com/andriystech/lgc/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCreateRoom(t *testing.T) {
+	ctx := context.Background()
+	fakeErr := errors.New("Unable to save room")
+	testConditions := []struct {
+		tName        string
+		wantErr      error
+		prepareMocks func(*mocks.RoomsRepository)
+	}{
+		{
+			tName: "should create room with owner as a member",
+			prepareMocks: func(rr *mocks.RoomsRepository) {
+				rr.On("SaveRoom", ctx, mock.Anything).Return("1", nil)
+			},
+		},
+		{
+			tName:   "should fail with unable to save room error",
+			wantErr: fakeErr,
+			prepareMocks: func(rr *mocks.RoomsRepository) {
+				rr.On("SaveRoom", ctx, mock.Anything).Return("", fakeErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			rr := new(mocks.RoomsRepository)
+			testCond.prepareMocks(rr)
+			svc := NewRoomService(rr)
+
+			gotRoom, gotErr := svc.CreateRoom(ctx, "general", "owner")
+
+			assert.Equal(t, testCond.wantErr, gotErr, "CreateRoom returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			if testCond.wantErr == nil {
+				assert.Equal(t, []string{"owner"}, gotRoom.Members, "CreateRoom returned unexpected result: got members %v want %v", gotRoom.Members, []string{"owner"})
+			}
+			rr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListRooms(t *testing.T) {
+	ctx := context.Background()
+	rr := new(mocks.RoomsRepository)
+	rooms := []*models.Room{{Id: "1", Name: "general"}}
+	rr.On("FindRooms", ctx).Return(rooms, nil)
+	svc := NewRoomService(rr)
+
+	gotRooms, gotErr := svc.ListRooms(ctx)
+
+	assert.Nil(t, gotErr, "ListRooms returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, rooms, gotRooms, "ListRooms returned unexpected result: got rooms %v want %v", gotRooms, rooms)
+
+	rr.AssertExpectations(t)
+}
+
+func TestJoinAndLeaveRoom(t *testing.T) {
+	ctx := context.Background()
+	rr := new(mocks.RoomsRepository)
+	rr.On("AddMember", ctx, "room", "user").Return(nil)
+	rr.On("RemoveMember", ctx, "room", "user").Return(nil)
+	svc := NewRoomService(rr)
+
+	joinErr := svc.JoinRoom(ctx, "room", "user")
+	leaveErr := svc.LeaveRoom(ctx, "room", "user")
+
+	assert.Nil(t, joinErr, "JoinRoom returned unexpected result: got error %v want %v", joinErr, nil)
+	assert.Nil(t, leaveErr, "LeaveRoom returned unexpected result: got error %v want %v", leaveErr, nil)
+
+	rr.AssertExpectations(t)
+}
@@ -2,40 +2,238 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/jwt"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/google/uuid"
 )
 
+// Types of signed tokens, access token can not be used for refresh and vice versa.
+const (
+	AccessTokenType  = "access"
+	RefreshTokenType = "refresh"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+var ErrTokenRevoked = errors.New("token has been revoked")
+
 type TokenService interface {
 	GenerateToken(context.Context, *models.User) (*models.Token, error)
 	GetUserByToken(context.Context, string) (*models.User, error)
+	IssueTokens(context.Context, *models.User) (*models.TokenPair, error)
+	RefreshTokens(context.Context, string) (*models.TokenPair, error)
+	RevokeRefreshToken(context.Context, string) error
+	GetUserByAccessToken(context.Context, string) (*models.User, error)
 }
 
 type TokenServiceContainer struct {
-	storage repositories.TokensRepository
+	storage    repositories.TokensRepository
+	revoked    repositories.RevokedTokensRepository
+	users      repositories.UsersRepository
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
 }
 
-func NewTokenService(storage repositories.TokensRepository) TokenService {
+func NewTokenService(
+	storage repositories.TokensRepository,
+	revoked repositories.RevokedTokensRepository,
+	users repositories.UsersRepository,
+	cnf *config.ServerConfig,
+) TokenService {
 	return &TokenServiceContainer{
-		storage: storage,
+		storage:    storage,
+		revoked:    revoked,
+		users:      users,
+		secret:     tokensSecret(cnf),
+		accessTTL:  cnf.AccessTokenTTL,
+		refreshTTL: cnf.RefreshTokenTTL,
 	}
 }
 
+// tokensSecret returns HMAC key from the config. Without configured key a random one is
+// generated, so issued tokens are not accepted by other instances and after restart.
+func tokensSecret(cnf *config.ServerConfig) []byte {
+	if cnf.JwtSecret != "" {
+		return []byte(cnf.JwtSecret)
+	}
+	logger.Default().Warn("JWT_SECRET is not set, tokens are signed with a random key valid until restart")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		panic(err)
+	}
+	return secret
+}
+
 func (svc *TokenServiceContainer) GenerateToken(ctx context.Context, user *models.User) (*models.Token, error) {
 	token := models.NewToken(uuid.NewString())
 	err := svc.storage.SaveToken(ctx, token.Payload, user)
 	if err != nil {
 		return nil, err
 	}
+	tokensIssued.Inc(tokenTypeOneTime)
+	logger.FromContext(ctx).Info("Issued web socket token", "userId", user.Id)
 	return token, nil
 }
 
+// GetUserByToken accepts either one time token issued on login or an access token,
+// the latter lets clients reconnect without logging in again.
 func (svc *TokenServiceContainer) GetUserByToken(ctx context.Context, token string) (*models.User, error) {
+	if isSignedToken(token) {
+		return svc.GetUserByAccessToken(ctx, token)
+	}
 	user, err := svc.storage.GetUserByToken(ctx, token)
+	if errors.Is(err, repositories.ErrTokenExpired) {
+		tokensExpired.Inc(tokenTypeOneTime)
+	}
 	if err != nil {
 		return nil, err
 	}
+	tokensConsumed.Inc(tokenTypeOneTime)
+	if _, err = svc.tokenOwner(ctx, user.Id); err != nil {
+		return nil, err
+	}
 	return user, nil
 }
+
+// IssueTokens signs a new token pair. Tokens are issued strictly after the last password change
+// of the user, so a pair issued in the same second as the change is not rejected as a stale one.
+func (svc *TokenServiceContainer) IssueTokens(ctx context.Context, user *models.User) (*models.TokenPair, error) {
+	now := time.Now()
+	if now.Unix() <= user.PasswordChangedAt {
+		now = time.Unix(user.PasswordChangedAt+1, 0)
+	}
+	accessToken, err := svc.sign(user, AccessTokenType, now, svc.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := svc.sign(user, RefreshTokenType, now, svc.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	tokensIssued.Inc(AccessTokenType)
+	tokensIssued.Inc(RefreshTokenType)
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(svc.accessTTL / time.Second),
+	}, nil
+}
+
+// RefreshTokens rotates refresh token: the provided one is revoked and a new pair is issued.
+// Tokens of deleted users and tokens issued before the password change are not refreshed.
+func (svc *TokenServiceContainer) RefreshTokens(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	claims, err := svc.parseRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := svc.checkClaimsOwner(ctx, claims)
+	if err != nil {
+		return nil, err
+	}
+	// revocation is the gate of the rotation, only one of concurrent refreshes gets a new pair
+	err = svc.revoked.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if errors.Is(err, repositories.ErrTokenAlreadyRevoked) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	tokensConsumed.Inc(RefreshTokenType)
+	return svc.IssueTokens(ctx, &models.User{Id: claims.Subject, UserName: claims.Name, PasswordChangedAt: owner.PasswordChangedAt})
+}
+
+func (svc *TokenServiceContainer) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	claims, err := svc.parseRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return err
+	}
+	err = svc.revoked.RevokeToken(ctx, claims.Id, time.Unix(claims.ExpiresAt, 0))
+	if errors.Is(err, repositories.ErrTokenAlreadyRevoked) {
+		return ErrTokenRevoked
+	}
+	return err
+}
+
+// GetUserByAccessToken verifies access token and returns public data of its owner.
+// Tokens of deleted users and tokens issued before the password change are rejected.
+func (svc *TokenServiceContainer) GetUserByAccessToken(ctx context.Context, accessToken string) (*models.User, error) {
+	claims, err := svc.parse(accessToken, AccessTokenType)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = svc.checkClaimsOwner(ctx, claims); err != nil {
+		return nil, err
+	}
+	return &models.User{Id: claims.Subject, UserName: claims.Name}, nil
+}
+
+// tokenOwner returns owner of the token, token is invalid once its owner is deleted.
+func (svc *TokenServiceContainer) tokenOwner(ctx context.Context, userId string) (*models.User, error) {
+	user, err := svc.users.FindUserById(ctx, userId)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, ErrInvalidToken
+	}
+	return user, err
+}
+
+// checkClaimsOwner returns owner of the signed token, tokens of deleted users and tokens issued
+// before the password change or in the same second are rejected.
+func (svc *TokenServiceContainer) checkClaimsOwner(ctx context.Context, claims *jwt.Claims) (*models.User, error) {
+	user, err := svc.tokenOwner(ctx, claims.Subject)
+	if err != nil {
+		return nil, err
+	}
+	if claims.IssuedAt <= user.PasswordChangedAt {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+func (svc *TokenServiceContainer) parseRefreshToken(ctx context.Context, refreshToken string) (*jwt.Claims, error) {
+	claims, err := svc.parse(refreshToken, RefreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := svc.revoked.IsTokenRevoked(ctx, claims.Id)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+func (svc *TokenServiceContainer) parse(token, tokenType string) (*jwt.Claims, error) {
+	claims, err := jwt.Parse(token, svc.secret, time.Now())
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		tokensExpired.Inc(tokenType)
+	}
+	if err != nil || claims.Type != tokenType {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (svc *TokenServiceContainer) sign(user *models.User, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	return jwt.Sign(&jwt.Claims{
+		Id:        uuid.NewString(),
+		Subject:   user.Id,
+		Name:      user.UserName,
+		Type:      tokenType,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}, svc.secret)
+}
+
+func isSignedToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
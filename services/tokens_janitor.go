@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/pkg/logger"
+)
+
+// TokensJanitor evicts one time tokens which were issued but never used and
+// revoked refresh tokens which expired anyway.
+type TokensJanitor interface {
+	Run(context.Context)
+	Sweep(context.Context) (int, error)
+}
+
+type tokensJanitor struct {
+	storage  repositories.TokensRepository
+	revoked  repositories.RevokedTokensRepository
+	interval time.Duration
+}
+
+func NewTokensJanitor(storage repositories.TokensRepository, revoked repositories.RevokedTokensRepository, cnf *config.ServerConfig) TokensJanitor {
+	return &tokensJanitor{
+		storage:  storage,
+		revoked:  revoked,
+		interval: cnf.TokensSweepInterval,
+	}
+}
+
+// Run sweeps expired tokens periodically until the context is cancelled.
+// Non positive interval disables the janitor.
+func (j *tokensJanitor) Run(ctx context.Context) {
+	if j.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.FromContext(ctx).Info("Tokens janitor stopped")
+			return
+		case <-ticker.C:
+			purged, err := j.Sweep(ctx)
+			if err != nil {
+				logger.FromContext(ctx).Error("Unable to purge expired tokens", "err", err)
+				continue
+			}
+			if purged > 0 {
+				logger.FromContext(ctx).Info("Purged expired tokens", "count", purged)
+			}
+		}
+	}
+}
+
+// Sweep evicts expired tokens once and returns how many of them were purged.
+func (j *tokensJanitor) Sweep(ctx context.Context) (int, error) {
+	purged, err := j.storage.DeleteExpiredTokens(ctx)
+	if err != nil {
+		return 0, err
+	}
+	revoked, err := j.revoked.DeleteExpiredTokens(ctx)
+	if err != nil {
+		return purged, err
+	}
+	return purged + revoked, nil
+}
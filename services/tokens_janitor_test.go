@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTokensJanitorSweep(t *testing.T) {
+	ctx := context.Background()
+	errUnableToDelete := errors.New("Unable to delete tokens")
+	testConditions := []struct {
+		tName        string
+		wantPurged   int
+		wantErr      error
+		prepareMocks func(*mocks.TokensRepository, *mocks.RevokedTokensRepository)
+	}{
+		{
+			tName:      "should report number of purged tokens",
+			wantPurged: 5,
+			prepareMocks: func(tr *mocks.TokensRepository, rr *mocks.RevokedTokensRepository) {
+				tr.On("DeleteExpiredTokens", ctx).Return(3, nil)
+				rr.On("DeleteExpiredTokens", ctx).Return(2, nil)
+			},
+		},
+		{
+			tName:   "should fail with unable to delete error",
+			wantErr: errUnableToDelete,
+			prepareMocks: func(tr *mocks.TokensRepository, rr *mocks.RevokedTokensRepository) {
+				tr.On("DeleteExpiredTokens", ctx).Return(0, errUnableToDelete)
+			},
+		},
+		{
+			tName:      "should fail with unable to delete revoked tokens error",
+			wantPurged: 3,
+			wantErr:    errUnableToDelete,
+			prepareMocks: func(tr *mocks.TokensRepository, rr *mocks.RevokedTokensRepository) {
+				tr.On("DeleteExpiredTokens", ctx).Return(3, nil)
+				rr.On("DeleteExpiredTokens", ctx).Return(0, errUnableToDelete)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			tr := new(mocks.TokensRepository)
+			rr := new(mocks.RevokedTokensRepository)
+			testCond.prepareMocks(tr, rr)
+			janitor := NewTokensJanitor(tr, rr, &config.ServerConfig{TokensSweepInterval: time.Second})
+
+			gotPurged, gotErr := janitor.Sweep(ctx)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "Sweep returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantPurged, gotPurged, "Sweep returned unexpected result: got %v want %v", gotPurged, testCond.wantPurged)
+
+			tr.AssertExpectations(t)
+			rr.AssertExpectations(t)
+		})
+	}
+}
+
+func TestTokensJanitorRunStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	swept := make(chan struct{}, 1)
+	tr := new(mocks.TokensRepository)
+	tr.On("DeleteExpiredTokens", ctx).Run(func(args mock.Arguments) {
+		select {
+		case swept <- struct{}{}:
+		default:
+		}
+	}).Return(1, nil)
+	rr := new(mocks.RevokedTokensRepository)
+	rr.On("DeleteExpiredTokens", ctx).Return(0, nil)
+	janitor := &tokensJanitor{storage: tr, revoked: rr, interval: time.Millisecond}
+
+	done := make(chan struct{})
+	go func() {
+		janitor.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-swept:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not sweep expired tokens")
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("janitor did not stop after context cancellation")
+	}
+}
+
+func TestTokensJanitorDisabled(t *testing.T) {
+	tr := new(mocks.TokensRepository)
+	janitor := NewTokensJanitor(tr, new(mocks.RevokedTokensRepository), &config.ServerConfig{TokensSweepInterval: 0})
+
+	janitor.Run(context.Background())
+
+	tr.AssertExpectations(t)
+}
@@ -4,14 +4,26 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
 	"github.com/andriystech/lgc/mocks"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/jwt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
 
+var testTokensConfig = &config.ServerConfig{
+	JwtSecret:       "secret",
+	AccessTokenTTL:  time.Minute,
+	RefreshTokenTTL: time.Hour,
+}
+
 type generateTokenTestData struct {
 	usr          *models.User
 	wantErr      error
@@ -22,7 +34,7 @@ type getUserByTokenTestData struct {
 	uuid         string
 	wantUsr      *models.User
 	wantErr      error
-	prepareMocks func(*mocks.TokensRepository)
+	prepareMocks func(*mocks.TokensRepository, *mocks.UsersRepository)
 }
 
 func TestGenerateToken(t *testing.T) {
@@ -51,7 +63,7 @@ func TestGenerateToken(t *testing.T) {
 			ctx := context.Background()
 			tr := new(mocks.TokensRepository)
 			testCond.prepareMocks(tr)
-			svc := NewTokenService(tr)
+			svc := NewTokenService(tr, new(mocks.RevokedTokensRepository), new(mocks.UsersRepository), testTokensConfig)
 
 			_, gotErr := svc.GenerateToken(ctx, testCond.usr)
 
@@ -63,7 +75,7 @@ func TestGenerateToken(t *testing.T) {
 
 func TestGetUserByToken(t *testing.T) {
 	fakeUuid := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45"
-	fakeUsr := &models.User{UserName: "foo"}
+	fakeUsr := &models.User{Id: "1", UserName: "foo"}
 	fakeErr := errors.New("Unable to find user by token")
 
 	testConditions := []getUserByTokenTestData{
@@ -71,15 +83,25 @@ func TestGetUserByToken(t *testing.T) {
 			uuid:    fakeUuid,
 			wantUsr: fakeUsr,
 			wantErr: nil,
-			prepareMocks: func(tr *mocks.TokensRepository) {
+			prepareMocks: func(tr *mocks.TokensRepository, ur *mocks.UsersRepository) {
 				tr.On("GetUserByToken", context.Background(), fakeUuid).Return(fakeUsr, nil)
+				ur.On("FindUserById", context.Background(), fakeUsr.Id).Return(fakeUsr, nil)
+			},
+		},
+		{
+			uuid:    fakeUuid,
+			wantUsr: nil,
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(tr *mocks.TokensRepository, ur *mocks.UsersRepository) {
+				tr.On("GetUserByToken", context.Background(), fakeUuid).Return(fakeUsr, nil)
+				ur.On("FindUserById", context.Background(), fakeUsr.Id).Return(nil, repositories.ErrUserNotFound)
 			},
 		},
 		{
 			uuid:    fakeUuid,
 			wantUsr: nil,
 			wantErr: fakeErr,
-			prepareMocks: func(tr *mocks.TokensRepository) {
+			prepareMocks: func(tr *mocks.TokensRepository, ur *mocks.UsersRepository) {
 				tr.On("GetUserByToken", context.Background(), fakeUuid).Return(nil, fakeErr)
 			},
 		},
@@ -90,14 +112,317 @@ func TestGetUserByToken(t *testing.T) {
 		t.Run(tName, func(t *testing.T) {
 			ctx := context.Background()
 			tr := new(mocks.TokensRepository)
-			testCond.prepareMocks(tr)
-			svc := NewTokenService(tr)
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(tr, ur)
+			svc := NewTokenService(tr, new(mocks.RevokedTokensRepository), ur, testTokensConfig)
 
 			gotUsr, gotErr := svc.GetUserByToken(ctx, testCond.uuid)
 
 			assert.Equal(t, testCond.wantErr, gotErr, "GetUserByToken returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
 			assert.Equal(t, testCond.wantUsr, gotUsr, "GetUserByToken returned unexpected result: got user %v want %v", gotUsr, testCond.wantUsr)
 			tr.AssertExpectations(t)
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIssueTokens(t *testing.T) {
+	ctx := context.Background()
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	ur := new(mocks.UsersRepository)
+	ur.On("FindUserById", ctx, usr.Id).Return(usr, nil)
+	svc := NewTokenService(new(mocks.TokensRepository), new(mocks.RevokedTokensRepository), ur, testTokensConfig)
+
+	gotPair, gotErr := svc.IssueTokens(ctx, usr)
+
+	assert.Nil(t, gotErr, "IssueTokens returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, 60, gotPair.ExpiresIn, "IssueTokens returned unexpected result: got expires in %v want %v", gotPair.ExpiresIn, 60)
+	gotUsr, gotErr := svc.GetUserByAccessToken(ctx, gotPair.AccessToken)
+	assert.Nil(t, gotErr, "GetUserByAccessToken returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.Equal(t, usr, gotUsr, "GetUserByAccessToken returned unexpected result: got user %v want %v", gotUsr, usr)
+	_, gotErr = svc.GetUserByAccessToken(ctx, gotPair.RefreshToken)
+	assert.Equal(t, ErrInvalidToken, gotErr, "GetUserByAccessToken returned unexpected result: got error %v want %v", gotErr, ErrInvalidToken)
+}
+
+func TestGetUserByTokenAcceptsAccessToken(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	passwordChanged := &models.User{Id: usr.Id, UserName: usr.UserName, PasswordChangedAt: time.Now().Add(time.Minute).Unix()}
+	fakeErr := errors.New("Unable to find user")
+	testConditions := []struct {
+		tName        string
+		wantUsr      *models.User
+		wantErr      error
+		prepareMocks func(*mocks.UsersRepository)
+	}{
+		{
+			tName:   "should return owner of access token",
+			wantUsr: usr,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(usr, nil)
+			},
+		},
+		{
+			tName:   "should fail with invalid token error for deleted user",
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+		{
+			tName:   "should fail with invalid token error for token issued before password change",
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(passwordChanged, nil)
+			},
+		},
+		{
+			tName:   "should fail with some error",
+			wantErr: fakeErr,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(nil, fakeErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			tr := new(mocks.TokensRepository)
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(ur)
+			svc := NewTokenService(tr, new(mocks.RevokedTokensRepository), ur, testTokensConfig)
+			pair, _ := svc.IssueTokens(ctx, usr)
+
+			gotUsr, gotErr := svc.GetUserByToken(ctx, pair.AccessToken)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "GetUserByToken returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantUsr, gotUsr, "GetUserByToken returned unexpected result: got user %v want %v", gotUsr, testCond.wantUsr)
+			tr.AssertExpectations(t)
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIssueTokensAfterPasswordChange(t *testing.T) {
+	ctx := context.Background()
+	changed := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo", PasswordChangedAt: time.Now().Unix()}
+	ur := new(mocks.UsersRepository)
+	ur.On("FindUserById", ctx, changed.Id).Return(changed, nil)
+	svc := NewTokenService(new(mocks.TokensRepository), new(mocks.RevokedTokensRepository), ur, testTokensConfig)
+	stale, _ := jwt.Sign(&jwt.Claims{Id: "1", Subject: changed.Id, Type: AccessTokenType, IssuedAt: changed.PasswordChangedAt, ExpiresAt: time.Now().Add(time.Minute).Unix()}, []byte(testTokensConfig.JwtSecret))
+
+	pair, _ := svc.IssueTokens(ctx, changed)
+	_, gotErr := svc.GetUserByAccessToken(ctx, pair.AccessToken)
+	assert.Nil(t, gotErr, "GetUserByAccessToken returned unexpected result: got error %v want %v", gotErr, nil)
+	_, gotErr = svc.GetUserByAccessToken(ctx, stale)
+	assert.Equal(t, ErrInvalidToken, gotErr, "GetUserByAccessToken returned unexpected result: got error %v want %v", gotErr, ErrInvalidToken)
+}
+
+func TestRefreshTokens(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	fakeErr := errors.New("Unable to revoke token")
+	expired, _ := jwt.Sign(&jwt.Claims{Id: "1", Subject: usr.Id, Type: RefreshTokenType, ExpiresAt: time.Now().Add(-time.Minute).Unix()}, []byte(testTokensConfig.JwtSecret))
+	testConditions := []struct {
+		tName        string
+		token        func(TokenService) string
+		wantErr      error
+		prepareMocks func(*mocks.RevokedTokensRepository, *mocks.UsersRepository)
+	}{
+		{
+			tName: "should rotate refresh token",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil)
+				rr.On("RevokeToken", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(usr, nil)
+			},
+		},
+		{
+			tName: "should fail with invalid token error for deleted user",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil)
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+		{
+			tName: "should fail with invalid token error for token issued before password change",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil)
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(&models.User{Id: usr.Id, PasswordChangedAt: time.Now().Add(time.Minute).Unix()}, nil)
+			},
+		},
+		{
+			tName: "should fail with token revoked error",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			wantErr: ErrTokenRevoked,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(true, nil)
+			},
+		},
+		{
+			tName: "should fail with invalid token error when token is revoked meanwhile",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			wantErr: ErrInvalidToken,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil)
+				rr.On("RevokeToken", mock.Anything, mock.Anything, mock.Anything).Return(repositories.ErrTokenAlreadyRevoked)
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(usr, nil)
+			},
+		},
+		{
+			tName: "should fail with invalid token error for access token",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.AccessToken
+			},
+			wantErr:      ErrInvalidToken,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {},
+		},
+		{
+			tName:        "should fail with invalid token error for expired token",
+			token:        func(svc TokenService) string { return expired },
+			wantErr:      ErrInvalidToken,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {},
+		},
+		{
+			tName: "should fail with some error",
+			token: func(svc TokenService) string {
+				pair, _ := svc.IssueTokens(context.Background(), usr)
+				return pair.RefreshToken
+			},
+			wantErr: fakeErr,
+			prepareMocks: func(rr *mocks.RevokedTokensRepository, ur *mocks.UsersRepository) {
+				rr.On("IsTokenRevoked", mock.Anything, mock.Anything).Return(false, nil)
+				rr.On("RevokeToken", mock.Anything, mock.Anything, mock.Anything).Return(fakeErr)
+				ur.On("FindUserById", mock.Anything, usr.Id).Return(usr, nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			rr := new(mocks.RevokedTokensRepository)
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(rr, ur)
+			svc := NewTokenService(new(mocks.TokensRepository), rr, ur, testTokensConfig)
+
+			gotPair, gotErr := svc.RefreshTokens(context.Background(), testCond.token(svc))
+
+			assert.Equal(t, testCond.wantErr, gotErr, "RefreshTokens returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			if testCond.wantErr == nil {
+				gotUsr, _ := svc.GetUserByAccessToken(context.Background(), gotPair.AccessToken)
+				assert.Equal(t, usr, gotUsr, "RefreshTokens returned unexpected result: got user %v want %v", gotUsr, usr)
+			}
+
+			rr.AssertExpectations(t)
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRefreshTokensConcurrently(t *testing.T) {
+	ctx := context.Background()
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	ur := new(mocks.UsersRepository)
+	ur.On("FindUserById", mock.Anything, usr.Id).Return(usr, nil)
+	svc := NewTokenService(new(mocks.TokensRepository), repositories.NewInMemoryRevokedTokensRepository(), ur, testTokensConfig)
+	pair, _ := svc.IssueTokens(ctx, usr)
+
+	attempts := 20
+	var refreshed int32
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := svc.RefreshTokens(ctx, pair.RefreshToken); err == nil {
+				atomic.AddInt32(&refreshed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), refreshed, "RefreshTokens rotated the same token unexpected number of times: got %v want %v", refreshed, 1)
+}
+
+func TestRevokeRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	rr := new(mocks.RevokedTokensRepository)
+	svc := NewTokenService(new(mocks.TokensRepository), rr, new(mocks.UsersRepository), testTokensConfig)
+	pair, _ := svc.IssueTokens(ctx, usr)
+	rr.On("IsTokenRevoked", ctx, mock.Anything).Return(false, nil)
+	rr.On("RevokeToken", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	rr.On("RevokeToken", ctx, mock.Anything, mock.Anything).Return(repositories.ErrTokenAlreadyRevoked).Once()
+
+	gotErr := svc.RevokeRefreshToken(ctx, pair.RefreshToken)
+	assert.Nil(t, gotErr, "RevokeRefreshToken returned unexpected result: got error %v want %v", gotErr, nil)
+
+	gotErr = svc.RevokeRefreshToken(ctx, pair.RefreshToken)
+	assert.Equal(t, ErrTokenRevoked, gotErr, "RevokeRefreshToken returned unexpected result: got error %v want %v", gotErr, ErrTokenRevoked)
+	rr.AssertExpectations(t)
+}
+
+func TestTokenMetrics(t *testing.T) {
+	ctx := context.Background()
+	usr := &models.User{Id: "1", UserName: "foo"}
+	tr := new(mocks.TokensRepository)
+	tr.On("SaveToken", ctx, mock.Anything, usr).Return(nil)
+	tr.On("GetUserByToken", ctx, "valid").Return(usr, nil)
+	tr.On("GetUserByToken", ctx, "stale").Return(nil, repositories.ErrTokenExpired)
+	ur := new(mocks.UsersRepository)
+	ur.On("FindUserById", ctx, usr.Id).Return(usr, nil)
+	svc := NewTokenService(tr, new(mocks.RevokedTokensRepository), ur, testTokensConfig)
+	expired, _ := jwt.Sign(&jwt.Claims{Subject: "1", Type: AccessTokenType, ExpiresAt: time.Now().Add(-time.Minute).Unix()}, []byte(testTokensConfig.JwtSecret))
+	issued := map[string]float64{}
+	consumed := map[string]float64{}
+	expiredCount := map[string]float64{}
+	for _, tokenType := range []string{tokenTypeOneTime, AccessTokenType, RefreshTokenType} {
+		issued[tokenType] = tokensIssued.Value(tokenType)
+		consumed[tokenType] = tokensConsumed.Value(tokenType)
+		expiredCount[tokenType] = tokensExpired.Value(tokenType)
+	}
+
+	svc.GenerateToken(ctx, usr)
+	svc.GetUserByToken(ctx, "valid")
+	svc.GetUserByToken(ctx, "stale")
+	svc.IssueTokens(ctx, usr)
+	svc.GetUserByAccessToken(ctx, expired)
+
+	testConditions := []struct {
+		tName   string
+		counter float64
+		before  float64
+	}{
+		{tName: "one time token issued", counter: tokensIssued.Value(tokenTypeOneTime), before: issued[tokenTypeOneTime]},
+		{tName: "access token issued", counter: tokensIssued.Value(AccessTokenType), before: issued[AccessTokenType]},
+		{tName: "refresh token issued", counter: tokensIssued.Value(RefreshTokenType), before: issued[RefreshTokenType]},
+		{tName: "one time token consumed", counter: tokensConsumed.Value(tokenTypeOneTime), before: consumed[tokenTypeOneTime]},
+		{tName: "one time token expired", counter: tokensExpired.Value(tokenTypeOneTime), before: expiredCount[tokenTypeOneTime]},
+		{tName: "access token expired", counter: tokensExpired.Value(AccessTokenType), before: expiredCount[AccessTokenType]},
+	}
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			assert.Equal(t, 1.0, testCond.counter-testCond.before, "%s counted unexpected tokens: got %v want %v", testCond.tName, testCond.counter-testCond.before, 1)
 		})
 	}
 }
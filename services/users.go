@@ -2,38 +2,64 @@ package services
 
 import (
 	"context"
+	"errors"
+	"time"
 
+	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
 	"github.com/andriystech/lgc/models"
 	"github.com/andriystech/lgc/pkg/hasher"
 	"github.com/google/uuid"
 )
 
+var ErrInvalidPassword = errors.New("current password is invalid")
+
 type UserService interface {
 	NewUser(string, string) (*models.User, error)
+	FindUserById(context.Context, string) (*models.User, error)
 	FindUserByName(context.Context, string) (*models.User, error)
 	SaveUser(context.Context, *models.User) (string, error)
+	CheckPassword(*models.User, string) bool
+	UpgradePasswordHash(context.Context, *models.User, string) error
+	UpdateProfile(context.Context, string, *models.ProfileUpdate) (*models.User, error)
+	ChangePassword(context.Context, string, string, string) (*models.User, error)
+	DeleteUser(context.Context, string, string) error
 }
 
 type userService struct {
 	storage repositories.UsersRepository
+	hasher  hasher.Hasher
 }
 
-func NewUserService(storage repositories.UsersRepository) UserService {
+func NewUserService(storage repositories.UsersRepository, cnf *config.ServerConfig) UserService {
+	opts := hasher.DefaultOptions
+	opts.Algorithm = hasher.Algorithm(cnf.PasswordHashAlgorithm)
+	opts.BcryptCost = cnf.PasswordBcryptCost
+	opts.Argon2Time = uint32(cnf.PasswordArgon2Time)
+	opts.Argon2Memory = uint32(cnf.PasswordArgon2Memory)
+	opts.Argon2Threads = uint8(cnf.PasswordArgon2Threads)
+	opts.ScryptLogN = uint8(cnf.PasswordScryptLogN)
+	opts.ScryptR = cnf.PasswordScryptR
+	opts.ScryptP = cnf.PasswordScryptP
 	return &userService{
 		storage: storage,
+		hasher:  hasher.New(opts),
 	}
 }
 
 func (svc *userService) NewUser(name, password string) (*models.User, error) {
 	userId := uuid.NewString()
-	passwordHash, err := hasher.HashPassword(password)
+	passwordHash, err := svc.hasher.HashPassword(password)
 	if err != nil {
 		return nil, err
 	}
 	return models.NewUser(userId, name, passwordHash), nil
 }
 
+func (svc *userService) FindUserById(ctx context.Context, id string) (*models.User, error) {
+	return svc.storage.FindUserById(ctx, id)
+}
+
 func (svc *userService) FindUserByName(ctx context.Context, name string) (*models.User, error) {
 	return svc.storage.FindUserByName(ctx, name)
 }
@@ -41,3 +67,76 @@ func (svc *userService) FindUserByName(ctx context.Context, name string) (*model
 func (svc *userService) SaveUser(ctx context.Context, user *models.User) (string, error) {
 	return svc.storage.SaveUser(ctx, user)
 }
+
+// CheckPassword checks provided password against stored hash of any supported algorithm
+// including legacy sha512 ones.
+func (svc *userService) CheckPassword(user *models.User, password string) bool {
+	return svc.hasher.CheckPasswordHash(password, user.Password)
+}
+
+// UpgradePasswordHash rehashes already verified password when stored hash was produced
+// by another algorithm or with weaker parameters than currently configured.
+func (svc *userService) UpgradePasswordHash(ctx context.Context, user *models.User, password string) error {
+	if !svc.hasher.NeedsRehash(user.Password) {
+		return nil
+	}
+	passwordHash, err := svc.hasher.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err = svc.storage.UpdateUserPassword(ctx, user.Id, passwordHash); err != nil {
+		return err
+	}
+	user.Password = passwordHash
+	return nil
+}
+
+// UpdateProfile applies the update and returns the updated user.
+func (svc *userService) UpdateProfile(ctx context.Context, id string, update *models.ProfileUpdate) (*models.User, error) {
+	if !update.IsEmpty() {
+		if err := svc.storage.UpdateUserProfile(ctx, id, update, time.Now().Unix()); err != nil {
+			return nil, err
+		}
+	}
+	return svc.storage.FindUserById(ctx, id)
+}
+
+// ChangePassword replaces password of the user when the current one matches and returns the updated user.
+// Tokens issued before the change are no longer accepted by the token service.
+func (svc *userService) ChangePassword(ctx context.Context, id, currentPassword, newPassword string) (*models.User, error) {
+	user, err := svc.verifyPassword(ctx, id, currentPassword)
+	if err != nil {
+		return nil, err
+	}
+	passwordHash, err := svc.hasher.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+	changedAt := time.Now().Unix()
+	if err := svc.storage.ChangeUserPassword(ctx, id, passwordHash, changedAt); err != nil {
+		return nil, err
+	}
+	changed := *user
+	changed.Password = passwordHash
+	changed.PasswordChangedAt = changedAt
+	return &changed, nil
+}
+
+// DeleteUser removes account of the user when the password matches.
+func (svc *userService) DeleteUser(ctx context.Context, id, password string) error {
+	if _, err := svc.verifyPassword(ctx, id, password); err != nil {
+		return err
+	}
+	return svc.storage.DeleteUser(ctx, id)
+}
+
+func (svc *userService) verifyPassword(ctx context.Context, id, password string) (*models.User, error) {
+	user, err := svc.storage.FindUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !svc.CheckPassword(user, password) {
+		return nil, ErrInvalidPassword
+	}
+	return user, nil
+}
@@ -2,20 +2,46 @@ package services
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
 	"github.com/andriystech/lgc/mocks"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/hasher"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+// testUsersConfig hashes passwords with hasher.DefaultOptions
+var testUsersConfig = config.Default()
+
+const legacyPasswordHash = "9b71d224bd62f3785d96d46ad3ea3d73319bfbc2890caadae2dff72519673ca72323c3d99ba5c11d7c7acc6e14b8c5da0c4663475c2e5c3adef46f73bcdec043"
+
 func TestNewUser(t *testing.T) {
 	ur := new(mocks.UsersRepository)
-	svc := NewUserService(ur)
+	svc := NewUserService(ur, testUsersConfig)
 
-	_, gotErr := svc.NewUser("foo", "bar")
+	gotUsr, gotErr := svc.NewUser("foo", "bar")
 
 	assert.Nil(t, gotErr, "NewUser returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.True(t, svc.CheckPassword(gotUsr, "bar"), "NewUser returned unexpected result: got password hash %v not matching password", gotUsr.Password)
+	assert.False(t, hasher.NeedsRehash(gotUsr.Password), "NewUser returned unexpected result: got outdated password hash %v", gotUsr.Password)
+}
+
+func TestNewUserUsesConfiguredCost(t *testing.T) {
+	cnf := config.Default()
+	cnf.PasswordHashAlgorithm = string(hasher.Bcrypt)
+	cnf.PasswordBcryptCost = 5
+	svc := NewUserService(new(mocks.UsersRepository), cnf)
+
+	gotUsr, gotErr := svc.NewUser("foo", "bar")
+
+	assert.Nil(t, gotErr, "NewUser returned unexpected result: got error %v want %v", gotErr, nil)
+	assert.True(t, strings.HasPrefix(gotUsr.Password, "$2a$05$"), "NewUser returned unexpected result: got password hash %v want bcrypt hash of cost 5", gotUsr.Password)
 }
 
 func TestFindUserByName(t *testing.T) {
@@ -23,7 +49,7 @@ func TestFindUserByName(t *testing.T) {
 	ur := new(mocks.UsersRepository)
 	usr := &models.User{UserName: "foo"}
 	ur.On("FindUserByName", ctx, "foo").Return(usr, nil)
-	svc := NewUserService(ur)
+	svc := NewUserService(ur, testUsersConfig)
 
 	gotUsr, gotErr := svc.FindUserByName(ctx, "foo")
 
@@ -39,7 +65,7 @@ func TestSaveUser(t *testing.T) {
 	ur := new(mocks.UsersRepository)
 	usr := &models.User{UserName: "foo"}
 	ur.On("SaveUser", ctx, usr).Return(wantId, nil)
-	svc := NewUserService(ur)
+	svc := NewUserService(ur, testUsersConfig)
 
 	gotUsrId, gotErr := svc.SaveUser(ctx, usr)
 
@@ -48,3 +74,216 @@ func TestSaveUser(t *testing.T) {
 
 	ur.AssertExpectations(t)
 }
+
+func TestCheckPassword(t *testing.T) {
+	svc := NewUserService(new(mocks.UsersRepository), testUsersConfig)
+	usr := &models.User{Id: "1", Password: legacyPasswordHash}
+
+	assert.True(t, svc.CheckPassword(usr, "hello"), "CheckPassword returned unexpected result: got %v want %v", false, true)
+	assert.False(t, svc.CheckPassword(usr, "hellO"), "CheckPassword returned unexpected result: got %v want %v", true, false)
+}
+
+func TestUpgradePasswordHash(t *testing.T) {
+	unknownErr := errors.New("Unable to update")
+	currentHash, _ := hasher.HashPassword("hello")
+	testConditions := []struct {
+		tName        string
+		passwordHash string
+		wantErr      error
+		wantUpgraded bool
+		prepareMocks func(*mocks.UsersRepository)
+	}{
+		{
+			tName:        "should replace legacy hash",
+			passwordHash: legacyPasswordHash,
+			wantUpgraded: true,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("UpdateUserPassword", mock.Anything, "1", mock.MatchedBy(func(hash string) bool {
+					return !hasher.NeedsRehash(hash) && hasher.CheckPasswordHash("hello", hash)
+				})).Return(nil)
+			},
+		},
+		{
+			tName:        "should keep current hash",
+			passwordHash: currentHash,
+			prepareMocks: func(ur *mocks.UsersRepository) {},
+		},
+		{
+			tName:        "should fail with some error",
+			passwordHash: legacyPasswordHash,
+			wantErr:      unknownErr,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("UpdateUserPassword", mock.Anything, "1", mock.Anything).Return(unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(ur)
+			svc := NewUserService(ur, testUsersConfig)
+			usr := &models.User{Id: "1", Password: testCond.passwordHash}
+
+			gotErr := svc.UpgradePasswordHash(context.Background(), usr, "hello")
+
+			assert.Equal(t, testCond.wantErr, gotErr, "UpgradePasswordHash returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantUpgraded, usr.Password != testCond.passwordHash, "UpgradePasswordHash returned unexpected result: got password hash %v", usr.Password)
+
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	displayName := "Foo"
+	usr := &models.User{Id: "1", UserName: "foo", DisplayName: displayName}
+	testConditions := []struct {
+		tName        string
+		update       *models.ProfileUpdate
+		wantUsr      *models.User
+		wantErr      error
+		prepareMocks func(*mocks.UsersRepository)
+	}{
+		{
+			tName:   "should update provided fields",
+			update:  &models.ProfileUpdate{DisplayName: &displayName},
+			wantUsr: usr,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("UpdateUserProfile", mock.Anything, "1", &models.ProfileUpdate{DisplayName: &displayName}, mock.Anything).Return(nil)
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+			},
+		},
+		{
+			tName:   "should skip empty update",
+			update:  &models.ProfileUpdate{},
+			wantUsr: usr,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+			},
+		},
+		{
+			tName:   "should fail with user not found error",
+			update:  &models.ProfileUpdate{DisplayName: &displayName},
+			wantErr: repositories.ErrUserNotFound,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("UpdateUserProfile", mock.Anything, "1", mock.Anything, mock.Anything).Return(repositories.ErrUserNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(ur)
+			svc := NewUserService(ur, testUsersConfig)
+
+			gotUsr, gotErr := svc.UpdateProfile(context.Background(), "1", testCond.update)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "UpdateProfile returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			assert.Equal(t, testCond.wantUsr, gotUsr, "UpdateProfile returned unexpected result: got user %v want %v", gotUsr, testCond.wantUsr)
+
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	currentHash, _ := hasher.HashPassword("hello")
+	usr := &models.User{Id: "1", UserName: "foo", Password: currentHash}
+	testConditions := []struct {
+		tName           string
+		currentPassword string
+		wantErr         error
+		prepareMocks    func(*mocks.UsersRepository)
+	}{
+		{
+			tName:           "should replace password",
+			currentPassword: "hello",
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+				ur.On("ChangeUserPassword", mock.Anything, "1", mock.MatchedBy(func(hash string) bool {
+					return hasher.CheckPasswordHash("secret", hash)
+				}), mock.MatchedBy(func(changedAt int64) bool {
+					return time.Now().Unix()-changedAt < 5
+				})).Return(nil)
+			},
+		},
+		{
+			tName:           "should fail with invalid password error",
+			currentPassword: "hellO",
+			wantErr:         ErrInvalidPassword,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+			},
+		},
+		{
+			tName:           "should fail with user not found error",
+			currentPassword: "hello",
+			wantErr:         repositories.ErrUserNotFound,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(ur)
+			svc := NewUserService(ur, testUsersConfig)
+
+			gotUsr, gotErr := svc.ChangePassword(context.Background(), "1", testCond.currentPassword, "secret")
+
+			assert.Equal(t, testCond.wantErr, gotErr, "ChangePassword returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+			if testCond.wantErr == nil {
+				assert.NotZero(t, gotUsr.PasswordChangedAt, "ChangePassword returned unexpected result: got user %v without password change time", gotUsr)
+				assert.True(t, hasher.CheckPasswordHash("secret", gotUsr.Password), "ChangePassword returned unexpected result: got user %v with stale password", gotUsr)
+			}
+
+			ur.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUser(t *testing.T) {
+	currentHash, _ := hasher.HashPassword("hello")
+	usr := &models.User{Id: "1", UserName: "foo", Password: currentHash}
+	testConditions := []struct {
+		tName        string
+		password     string
+		wantErr      error
+		prepareMocks func(*mocks.UsersRepository)
+	}{
+		{
+			tName:    "should delete user",
+			password: "hello",
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+				ur.On("DeleteUser", mock.Anything, "1").Return(nil)
+			},
+		},
+		{
+			tName:    "should fail with invalid password error",
+			password: "hellO",
+			wantErr:  ErrInvalidPassword,
+			prepareMocks: func(ur *mocks.UsersRepository) {
+				ur.On("FindUserById", mock.Anything, "1").Return(usr, nil)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ur := new(mocks.UsersRepository)
+			testCond.prepareMocks(ur)
+			svc := NewUserService(ur, testUsersConfig)
+
+			gotErr := svc.DeleteUser(context.Background(), "1", testCond.password)
+
+			assert.Equal(t, testCond.wantErr, gotErr, "DeleteUser returned unexpected result: got error %v want %v", gotErr, testCond.wantErr)
+
+			ur.AssertExpectations(t)
+		})
+	}
+}
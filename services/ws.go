@@ -2,82 +2,191 @@ package services
 
 import (
 	"context"
-	"log"
+	"errors"
 	"net/http"
+	"sync"
+	"time"
 
+	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
 	"github.com/andriystech/lgc/facilities/ws"
 	"github.com/andriystech/lgc/models"
+	"github.com/andriystech/lgc/pkg/logger"
+	"github.com/andriystech/lgc/pkg/ratelimit"
 	"github.com/google/uuid"
-	"github.com/gorilla/websocket"
 )
 
+var ErrNotRoomMember = errors.New("user is not a member of the room")
+var ErrSessionNotFound = errors.New("session not found")
+var ErrRateLimited = errors.New("rate limit exceeded")
+
 type WebSocketService interface {
 	NewConnection(http.ResponseWriter, *http.Request, *models.User) error
 	GetActiveConnectionsCount(context.Context) (int, error)
 	GetActiveUsers(context.Context) ([]string, error)
-	SendMessageToAllConnections(context.Context, string, *models.User) error
+	GetSendQueueStats(context.Context) ws.QueueStats
+	SendMessageToAllConnections(context.Context, *models.Frame, *models.User) error
+	SendDirectMessage(context.Context, *models.Frame, *models.User) error
 	LoadUserMessages(context.Context, *models.User, ws.ConnHelper) error
-	SaveUnreadMessages(context.Context, *models.User, string) error
+	SaveUnreadMessages(context.Context, *models.User, *models.Frame) error
+	MarkMessageRead(context.Context, *models.User, string) error
+	SendTypingIndicator(context.Context, *models.Frame, *models.User) error
+	EditMessage(context.Context, *models.User, string, string) (*models.Message, error)
+	DeleteMessage(context.Context, *models.User, string) error
+	GetUserSessions(context.Context, *models.User) ([]*models.Session, error)
+	CloseSession(context.Context, *models.User, string) error
+	RemoveUser(context.Context, *models.User) error
+	Run(context.Context)
+	Shutdown(context.Context) error
 }
 
 type webSocketService struct {
 	connections repositories.ConnectionsRepository
 	messages    repositories.MessagesRepository
+	rooms       repositories.RoomsRepository
 	upgrader    ws.UpgraderHelper
 	users       repositories.UsersRepository
+	broker      broker.Broker
+	presence    repositories.PresenceRepository
+	heartbeat   time.Duration
+	typing      *typingThrottle
+	limiter     *ratelimit.Limiter
 }
 
+// NewWebSocketService creates service which delivers messages published by any
+// instance of the server to connections held by the current one.
 func NewWebSocketService(
 	cr repositories.ConnectionsRepository,
 	mr repositories.MessagesRepository,
+	rr repositories.RoomsRepository,
 	ur repositories.UsersRepository,
 	wu ws.UpgraderHelper,
+	br broker.Broker,
+	pr repositories.PresenceRepository,
+	cnf *config.ServerConfig,
 ) WebSocketService {
-	return &webSocketService{
+	svc := &webSocketService{
 		connections: cr,
 		messages:    mr,
+		rooms:       rr,
 		upgrader:    wu,
 		users:       ur,
+		broker:      br,
+		presence:    pr,
+		heartbeat:   cnf.PresenceTTL / 3,
+		typing:      newTypingThrottle(cnf.TypingThrottle),
+		limiter:     ratelimit.New(float64(cnf.WsMessageRateLimitPerSecond), cnf.WsMessageRateLimitBurst),
+	}
+	br.Subscribe(svc.handleEvent)
+	return svc
+}
+
+// Run receives events from other instances and keeps presence of local
+// connections alive until the context is cancelled.
+func (svc *webSocketService) Run(ctx context.Context) {
+	go func() {
+		if err := svc.broker.Run(ctx); err != nil {
+			logger.FromContext(ctx).Error("Message broker stopped", "err", err)
+		}
+	}()
+	if svc.heartbeat <= 0 {
+		return
+	}
+	ticker := time.NewTicker(svc.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.presence.Refresh(ctx); err != nil {
+				logger.FromContext(ctx).Error("Unable to refresh presence", "err", err)
+			}
+		}
 	}
 }
 
 func (svc *webSocketService) NewConnection(w http.ResponseWriter, r *http.Request, user *models.User) error {
+	// session id is known before the upgrade, so the connection logs with it as well
+	id := uuid.NewString()
+	ctx := contextWithSession(logger.With(r.Context(), "sessionId", id, "userId", user.Id), id)
+	r = r.WithContext(ctx)
+	lg := logger.FromContext(ctx)
 	c, err := svc.upgrader.Upgrade(w, r)
 	if err != nil {
-		log.Printf("Unable to establish web socket connection. Reason: %s", err.Error())
+		lg.Error("Unable to establish web socket connection", "err", err)
 		return err
 	}
 
-	id := uuid.NewString()
-	if svc.connections.AddConnection(r.Context(), id, c, user); err != nil {
+	session := models.NewSession(id, user, r.UserAgent(), r.RemoteAddr)
+	if err = svc.connections.AddConnection(ctx, session, c); err != nil {
+		c.Close()
 		return err
 	}
+	activeConnections.Inc()
+	lg.Info("Session opened", "protocol", c.Protocol(), "userAgent", session.UserAgent)
+	if err = svc.presence.AddConnection(ctx, session); err != nil {
+		lg.Error("Unable to register presence of connection", "err", err)
+	} else {
+		svc.notifyPresence(ctx, user, models.PresenceJoined)
+	}
 
 	defer func() {
 		c.Close()
-		if err = svc.connections.DeleteConnection(r.Context(), id); err != nil {
-			log.Printf("Unable to delete connection. Reason: %s", err.Error())
+		activeConnections.Dec()
+		if err = svc.connections.DeleteConnection(ctx, id); err != nil {
+			lg.Error("Unable to delete connection", "err", err)
 		}
+		if err = svc.presence.DeleteConnection(ctx, id); err == nil {
+			svc.notifyPresence(ctx, user, models.PresenceLeft)
+		} else if !errors.Is(err, repositories.ErrConnNotFound) {
+			// presence of a removed user is already gone
+			lg.Error("Unable to delete presence of connection", "err", err)
+		}
+		lg.Info("Session closed")
 	}()
 
-	if err = svc.LoadUserMessages(r.Context(), user, c); err != nil {
-		log.Printf("Unable to read messages. Reason: %s", err.Error())
+	if err = svc.LoadUserMessages(ctx, user, c); err != nil {
+		lg.Error("Unable to read messages", "err", err)
 		return err
 	}
 
 	for {
-		_, message, err := c.ReadMessage()
+		_, data, err := c.ReadMessage()
 		if err != nil {
-			log.Println("web socket read error:", err)
+			lg.Debug("Unable to read from web socket", "err", err)
 			break
 		}
-		if err = svc.SendMessageToAllConnections(r.Context(), string(message), user); err != nil {
-			log.Println("web socket write error:", err)
-			break
+		frame, err := readFrame(c, data)
+		if err != nil {
+			lg.Warn("Invalid frame received", "err", err)
+			svc.replyWithError(ctx, c, frame, err)
+			continue
+		}
+		if allowed, wait := svc.allowFrame(frame, user); !allowed {
+			if frame.Type != models.FrameTypeEdit && frame.Type != models.FrameTypeDelete {
+				messagesFailed.Inc(messageFailureRateLimited)
+			}
+			if err = writeFrame(c, models.NewRateLimitFrame(frame.Id, ErrRateLimited.Error(), wait)); err != nil {
+				lg.Error("Unable to write to web socket", "err", err)
+				break
+			}
+			continue
 		}
-		if err = svc.SaveUnreadMessages(r.Context(), user, string(message)); err != nil {
-			log.Println("save unread messages error:", err)
+		switch frame.Type {
+		case models.FrameTypeRead:
+			err = svc.handleReadFrame(ctx, c, frame, user)
+		case models.FrameTypeTyping:
+			err = svc.handleTypingFrame(ctx, c, frame, user)
+		case models.FrameTypeEdit, models.FrameTypeDelete:
+			err = svc.handleUpdateFrame(ctx, c, frame, user)
+		default:
+			err = svc.handleMessageFrame(ctx, c, frame, user)
+		}
+		if err != nil {
+			lg.Error("Unable to write to web socket", "err", err)
 			break
 		}
 	}
@@ -85,94 +194,412 @@ func (svc *webSocketService) NewConnection(w http.ResponseWriter, r *http.Reques
 	return nil
 }
 
+// allowFrame limits frames which create or change messages of the user across all sessions
+// held by the current instance. Read and typing frames are not limited.
+func (svc *webSocketService) allowFrame(frame *models.Frame, user *models.User) (bool, time.Duration) {
+	switch frame.Type {
+	case models.FrameTypeRead, models.FrameTypeTyping:
+		return true, 0
+	default:
+		return svc.limiter.Allow(user.Id)
+	}
+}
+
+// handleMessageFrame delivers inbound message and acknowledges it to the sender.
+// Errors caused by the frame content are reported back to the client, the rest are returned.
+func (svc *webSocketService) handleMessageFrame(ctx context.Context, conn ws.ConnHelper, frame *models.Frame, sender *models.User) error {
+	clientFrameId := frame.Id
+	frame.Id = uuid.NewString()
+	frame.Time = time.Now().Unix()
+
+	var err error
+	if frame.RecipientId != "" {
+		err = svc.SendDirectMessage(ctx, frame, sender)
+	} else if err = svc.SendMessageToAllConnections(ctx, frame, sender); err == nil {
+		err = svc.SaveUnreadMessages(ctx, sender, frame)
+	}
+	if errors.Is(err, ErrNotRoomMember) || errors.Is(err, repositories.ErrRoomNotFound) || errors.Is(err, repositories.ErrUserNotFound) {
+		logger.FromContext(ctx).Warn("Unable to deliver message", "messageId", frame.Id, "err", err)
+		messagesFailed.Inc(messageFailureRejected)
+		return writeFrame(conn, models.NewErrorFrame(clientFrameId, err.Error()))
+	}
+	if err != nil {
+		messagesFailed.Inc(messageFailureError)
+		return err
+	}
+
+	return writeFrame(conn, models.NewAckFrame(frame.Id, clientFrameId))
+}
+
+// handleReadFrame marks message as read by the client and acknowledges it.
+func (svc *webSocketService) handleReadFrame(ctx context.Context, conn ws.ConnHelper, frame *models.Frame, reader *models.User) error {
+	err := svc.MarkMessageRead(ctx, reader, frame.Id)
+	if errors.Is(err, repositories.ErrMessageNotFound) {
+		return writeFrame(conn, models.NewErrorFrame(frame.Id, err.Error()))
+	}
+	if err != nil {
+		return err
+	}
+
+	return writeFrame(conn, models.NewAckFrame(frame.Id, frame.Id))
+}
+
+func (svc *webSocketService) replyWithError(ctx context.Context, conn ws.ConnHelper, frame *models.Frame, reason error) {
+	replyTo := ""
+	if frame != nil {
+		replyTo = frame.Id
+	}
+	if err := writeFrame(conn, models.NewErrorFrame(replyTo, reason.Error())); err != nil {
+		logger.FromContext(ctx).Error("Unable to send error frame", "err", err)
+	}
+}
+
 func (svc *webSocketService) LoadUserMessages(ctx context.Context, usr *models.User, conn ws.ConnHelper) error {
-	messages, err := svc.messages.FindUserMessages(ctx, usr.Id)
+	messages, err := svc.messages.FindUndeliveredMessages(ctx, usr.Id)
 	if err != nil {
 		return err
 	}
 
 	for _, msg := range messages {
-		if err = conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
-			return err
+		// message retracted before the recipient saw it is not delivered at all
+		if !msg.IsDeleted() {
+			if err = writeFrame(conn, models.NewMessageFrame(msg)); err != nil {
+				messagesFailed.Inc(messageFailureDelivery)
+				return err
+			}
+			messagesSent.Inc()
 		}
+		svc.markDelivered(ctx, msg)
 	}
 
-	return nil
+	return svc.loadPendingUpdates(ctx, usr, conn)
 }
 
-func (svc *webSocketService) SaveUnreadMessages(ctx context.Context, sender *models.User, message string) error {
-	cs, err := svc.connections.GetAllConnections(ctx)
+// MarkMessageRead stores read state of reader's copy of the message and
+// sends read receipt to all connections of the message sender.
+func (svc *webSocketService) MarkMessageRead(ctx context.Context, reader *models.User, messageId string) error {
+	msg, err := svc.messages.MarkMessageRead(ctx, reader.Id, messageId, time.Now().Unix())
 	if err != nil {
 		return err
 	}
 
-	var activeUsrIds []string
-	for usrId := range cs {
-		activeUsrIds = append(activeUsrIds, usrId)
+	return svc.broker.Publish(ctx, models.NewReceiptEvent(msg))
+}
+
+// handleEvent delivers event published by any instance to local connections.
+func (svc *webSocketService) handleEvent(ctx context.Context, event *models.Event) error {
+	switch event.Type {
+	case models.EventTypeBroadcast:
+		return svc.deliverBroadcast(ctx, event)
+	case models.EventTypeDirect:
+		return svc.deliverDirect(ctx, event.Message)
+	case models.EventTypeReceipt:
+		return svc.deliverReceipt(ctx, event.Message)
+	case models.EventTypeKick:
+		return svc.closeLocalSession(ctx, event.SessionId)
+	case models.EventTypePresence, models.EventTypeTyping:
+		return svc.deliverEphemeral(ctx, event)
+	case models.EventTypeUpdate:
+		return svc.deliverUpdate(ctx, event)
+	default:
+		logger.FromContext(ctx).Warn("Skipping event of unknown type", "type", event.Type)
+		return nil
 	}
+}
 
-	notActiveUsers, err := svc.users.FindUsersNotInIdList(ctx, activeUsrIds)
+func (svc *webSocketService) deliverReceipt(ctx context.Context, msg *models.Message) error {
+	conns, err := svc.connections.GetUserConnections(ctx, msg.SenderId)
 	if err != nil {
 		return err
 	}
-
-	for _, usr := range notActiveUsers {
-		msg := models.NewMessage(
-			uuid.NewString(),
-			sender.Id,
-			sender.UserName,
-			usr.Id,
-			message,
-		)
-		if _, err = svc.messages.SaveMessage(ctx, msg); err != nil {
-			return err
+	for _, conn := range conns {
+		if err = writeFrame(conn, models.NewReceiptFrame(msg)); err != nil {
+			logger.FromContext(ctx).Error("Unable to deliver read receipt of message", "messageId", msg.LogicalId(), "err", err)
 		}
 	}
 
 	return nil
 }
 
-func (svc *webSocketService) sendMessage(
+func (svc *webSocketService) markDelivered(ctx context.Context, msg *models.Message) {
+	if err := svc.messages.MarkMessageDelivered(ctx, msg.Id, time.Now().Unix()); err != nil {
+		logger.FromContext(ctx).Error("Unable to mark message as delivered", "messageId", msg.Id, "err", err)
+	}
+}
+
+// SaveUnreadMessages stores undelivered copies of the message for recipients which are not
+// connected to any instance of the server.
+func (svc *webSocketService) SaveUnreadMessages(ctx context.Context, sender *models.User, frame *models.Frame) error {
+	activeUsrIds, err := svc.presence.OnlineUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	notActiveUsrIds, err := svc.findNotActiveRecipients(ctx, frame.RoomId, activeUsrIds)
+	if err != nil {
+		return err
+	}
+
+	_, err = svc.messages.SaveDeliveries(ctx, models.NewSharedMessage(frame, sender), notActiveUsrIds)
+	return err
+}
+
+func (svc *webSocketService) findNotActiveRecipients(ctx context.Context, roomId string, activeUsrIds []string) ([]string, error) {
+	if roomId != "" {
+		room, err := svc.rooms.FindRoomById(ctx, roomId)
+		if err != nil {
+			return nil, err
+		}
+		active := make(map[string]bool, len(activeUsrIds))
+		for _, id := range activeUsrIds {
+			active[id] = true
+		}
+		var ids []string
+		for _, id := range room.Members {
+			if !active[id] {
+				ids = append(ids, id)
+			}
+		}
+		return ids, nil
+	}
+
+	notActiveUsers, err := svc.users.FindUsersNotInIdList(ctx, activeUsrIds)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, usr := range notActiveUsers {
+		ids = append(ids, usr.Id)
+	}
+	return ids, nil
+}
+
+func (svc *webSocketService) SendMessageToAllConnections(
 	ctx context.Context,
-	conn ws.ConnHelper,
-	msg *models.Message,
+	frame *models.Frame,
+	sender *models.User,
 ) error {
+	var recipientIds []string
+	if frame.RoomId != "" {
+		room, err := svc.rooms.FindRoomById(ctx, frame.RoomId)
+		if err != nil {
+			return err
+		}
+		if !room.HasMember(sender.Id) {
+			return ErrNotRoomMember
+		}
+		recipientIds = room.Members
+	}
+
+	if frame.Id == "" {
+		frame.Id = uuid.NewString()
+	}
+	msg := models.NewSharedMessage(frame, sender)
 	if _, err := svc.messages.SaveMessage(ctx, msg); err != nil {
 		return err
 	}
+	if frame.RoomId != "" {
+		messagesStored.Inc(messageKindRoom)
+	} else {
+		messagesStored.Inc(messageKindBroadcast)
+	}
+	// every instance creates copies of its recipients with the same message time
+	frame.Time = msg.Time
 
-	if err := conn.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
+	return svc.broker.Publish(ctx, models.NewBroadcastEvent(frame, sender, sessionFromContext(ctx), recipientIds))
+}
+
+func (svc *webSocketService) deliverBroadcast(ctx context.Context, event *models.Event) error {
+	cs, err := svc.connections.GetAllConnections(ctx)
+	if err != nil {
 		return err
 	}
 
+	sender := event.Sender()
+	cs[sender.Id] = svc.withoutSession(ctx, cs[sender.Id], event.SessionId)
+	var recipientIds []string
+	for rId, conns := range cs {
+		if len(conns) > 0 && event.IsRecipient(rId) {
+			recipientIds = append(recipientIds, rId)
+		}
+	}
+
+	copies, err := svc.messages.SaveDeliveries(ctx, models.NewSharedMessage(event.Frame, sender), recipientIds)
+	if err != nil {
+		logger.FromContext(ctx).Error("Unable to save deliveries of message", "messageId", event.Frame.Id, "err", err)
+		messagesFailed.Add(float64(len(recipientIds)), messageFailureDelivery)
+		return nil
+	}
+	for _, msg := range copies {
+		svc.writeMessage(ctx, cs[msg.RecipientId], msg)
+	}
+
 	return nil
 }
 
-func (svc *webSocketService) SendMessageToAllConnections(
+// withoutSession skips connection of the session which sent the message,
+// sessions held by other instances are not found and nothing is skipped.
+func (svc *webSocketService) withoutSession(ctx context.Context, conns []ws.ConnHelper, sessionId string) []ws.ConnHelper {
+	if sessionId == "" {
+		return conns
+	}
+	origin, err := svc.connections.GetConnection(ctx, sessionId)
+	if err != nil {
+		return conns
+	}
+	rest := make([]ws.ConnHelper, 0, len(conns))
+	for _, conn := range conns {
+		if conn != origin {
+			rest = append(rest, conn)
+		}
+	}
+	return rest
+}
+
+func (svc *webSocketService) SendDirectMessage(
 	ctx context.Context,
-	payload string,
+	frame *models.Frame,
 	sender *models.User,
 ) error {
-	cs, err := svc.connections.GetAllConnections(ctx)
+	if _, err := svc.users.FindUserById(ctx, frame.RecipientId); err != nil {
+		return err
+	}
+
+	if frame.Id == "" {
+		frame.Id = uuid.NewString()
+	}
+	msg := models.NewSharedMessage(frame, sender)
+	if _, err := svc.messages.SaveMessage(ctx, msg); err != nil {
+		return err
+	}
+	messagesStored.Inc(messageKindDirect)
+	copies, err := svc.messages.SaveDeliveries(ctx, msg, []string{frame.RecipientId})
+	if err != nil {
+		return err
+	}
+
+	return svc.broker.Publish(ctx, models.NewDirectEvent(copies[0]))
+}
+
+func (svc *webSocketService) deliverDirect(ctx context.Context, msg *models.Message) error {
+	conns, err := svc.connections.GetUserConnections(ctx, msg.RecipientId)
 	if err != nil {
 		return err
 	}
 
-	for rId, conn := range cs {
-		if sender.Id == rId {
+	svc.writeMessage(ctx, conns, msg)
+	return nil
+}
+
+// writeMessage writes recipient's copy of the message to every session of the recipient.
+// Message is delivered as soon as at least one session received it.
+func (svc *webSocketService) writeMessage(ctx context.Context, conns []ws.ConnHelper, msg *models.Message) {
+	delivered := false
+	for _, conn := range conns {
+		if err := writeFrame(conn, models.NewMessageFrame(msg)); err != nil {
+			logger.FromContext(ctx).Error("Unable to deliver message", "messageId", msg.Id, "recipientId", msg.RecipientId, "err", err)
+			messagesFailed.Inc(messageFailureDelivery)
 			continue
 		}
+		messagesSent.Inc()
+		delivered = true
+	}
+	if delivered {
+		svc.markDelivered(ctx, msg)
+	}
+}
+
+// GetUserSessions returns sessions of the user opened on any instance of the server.
+func (svc *webSocketService) GetUserSessions(ctx context.Context, usr *models.User) ([]*models.Session, error) {
+	return svc.presence.GetUserSessions(ctx, usr.Id)
+}
+
+// CloseSession disconnects one of the user's sessions wherever it is held.
+func (svc *webSocketService) CloseSession(ctx context.Context, usr *models.User, sessionId string) error {
+	sessions, err := svc.presence.GetUserSessions(ctx, usr.Id)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if session.Id == sessionId {
+			return svc.broker.Publish(ctx, models.NewKickEvent(sessionId))
+		}
+	}
+	return ErrSessionNotFound
+}
+
+// RemoveUser cleans up after the deleted account: sessions of the user are closed wherever they are held,
+// its presence, room memberships and message copies addressed to it are removed. Rooms owned by the user
+// pass to the earliest remaining member, messages sent by the user stay in histories of their recipients.
+func (svc *webSocketService) RemoveUser(ctx context.Context, usr *models.User) error {
+	sessions, err := svc.presence.GetUserSessions(ctx, usr.Id)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		if err = svc.broker.Publish(ctx, models.NewKickEvent(session.Id)); err != nil {
+			logger.FromContext(ctx).Error("Unable to close session of user", "sessionId", session.Id, "err", err)
+		}
+	}
+	if err = svc.presence.DeleteUserConnections(ctx, usr.Id); err != nil {
+		return err
+	}
+	if len(sessions) > 0 {
+		svc.notifyPresence(ctx, usr, models.PresenceLeft)
+	}
+	if err = svc.rooms.RemoveUser(ctx, usr.Id); err != nil {
+		return err
+	}
+	return svc.messages.DeleteRecipientDeliveries(ctx, usr.Id)
+}
+
+type sessionContextKey struct{}
+
+// contextWithSession returns a copy of ctx carrying id of the web socket session which handles the frame.
+func contextWithSession(ctx context.Context, sessionId string) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, sessionId)
+}
 
-		msg := models.NewMessage(
-			uuid.NewString(),
-			sender.Id,
-			sender.UserName,
-			rId,
-			payload,
-		)
-		svc.sendMessage(ctx, conn, msg)
+func sessionFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionContextKey{}).(string)
+	return id
+}
+
+// closeLocalSession closes the connection if it is held by the current instance,
+// the read loop of the connection releases the rest of its resources.
+func (svc *webSocketService) closeLocalSession(ctx context.Context, sessionId string) error {
+	conn, err := svc.connections.GetConnection(ctx, sessionId)
+	if errors.Is(err, repositories.ErrConnNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return conn.CloseWithReason(ws.ClosePolicyViolation, "session closed")
+}
+
+// Shutdown writes pending messages and tells every local connection that the server is going away.
+// Connections which are not flushed until the context is done are closed anyway.
+func (svc *webSocketService) Shutdown(ctx context.Context) error {
+	cs, err := svc.connections.GetAllConnections(ctx)
+	if err != nil {
+		return err
 	}
 
+	wg := &sync.WaitGroup{}
+	for _, conns := range cs {
+		for _, conn := range conns {
+			wg.Add(1)
+			go func(conn ws.ConnHelper) {
+				defer wg.Done()
+				if err := conn.Flush(ctx); err != nil {
+					logger.FromContext(ctx).Error("Unable to flush web socket connection", "err", err)
+				}
+				conn.CloseWithReason(ws.CloseGoingAway, "server is shutting down")
+			}(conn)
+		}
+	}
+	wg.Wait()
 	return nil
 }
 
@@ -183,3 +610,7 @@ func (svc *webSocketService) GetActiveConnectionsCount(ctx context.Context) (int
 func (svc *webSocketService) GetActiveUsers(ctx context.Context) ([]string, error) {
 	return svc.connections.ConnectedClients(ctx)
 }
+
+func (svc *webSocketService) GetSendQueueStats(ctx context.Context) ws.QueueStats {
+	return svc.upgrader.QueueStats()
+}
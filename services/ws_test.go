@@ -3,8 +3,13 @@ package services
 import (
 	"context"
 	"errors"
+	"sort"
+	"strings"
 	"testing"
 
+	"github.com/andriystech/lgc/config"
+	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
 	"github.com/andriystech/lgc/facilities/ws"
 	"github.com/andriystech/lgc/mocks"
 	"github.com/andriystech/lgc/models"
@@ -17,11 +22,13 @@ func TestGetActiveConnectionsCount(t *testing.T) {
 	ctx := context.Background()
 	cr := new(mocks.ConnectionsRepository)
 	mr := new(mocks.MessagesRepository)
+	rr := new(mocks.RoomsRepository)
 	ur := new(mocks.UsersRepository)
 	wu := new(mocks.UpgraderHelper)
+	pr := new(mocks.PresenceRepository)
 	count := 1
 	cr.On("CountConnections", ctx).Return(count, nil)
-	svc := NewWebSocketService(cr, mr, ur, wu)
+	svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
 
 	gotCount, gotErr := svc.GetActiveConnectionsCount(ctx)
 
@@ -35,11 +42,13 @@ func TestGetActiveUsers(t *testing.T) {
 	ctx := context.Background()
 	cr := new(mocks.ConnectionsRepository)
 	mr := new(mocks.MessagesRepository)
+	rr := new(mocks.RoomsRepository)
 	ur := new(mocks.UsersRepository)
 	wu := new(mocks.UpgraderHelper)
+	pr := new(mocks.PresenceRepository)
 	clients := []string{"1-user", "2-user2"}
 	cr.On("ConnectedClients", ctx).Return(clients, nil)
-	svc := NewWebSocketService(cr, mr, ur, wu)
+	svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
 
 	gotClients, gotErr := svc.GetActiveUsers(ctx)
 
@@ -53,17 +62,21 @@ func TestSendMessageToAllConnections(t *testing.T) {
 	fakeMessageUuid := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa44"
 	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
 	recipient := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46", UserName: "bar"}
+	outsider := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa47", UserName: "baz"}
+	room := &models.Room{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", Members: []string{sender.Id, recipient.Id}}
 	errorUnableToGetConnections := errors.New("Unable to get connections list")
 	errorUnableToSaveMessage := errors.New("Unable to save message in database")
 	errorUnableToSendMessage := errors.New("Unable to send message into websocket")
 	testConditions := []struct {
 		tName        string
+		roomId       string
 		payload      string
 		sender       *models.User
 		expected     error
 		prepareMocks func(
 			*mocks.ConnectionsRepository,
 			*mocks.MessagesRepository,
+			*mocks.RoomsRepository,
 			*mocks.UsersRepository,
 			*mocks.ConnHelper,
 		)
@@ -73,7 +86,8 @@ func TestSendMessageToAllConnections(t *testing.T) {
 			payload:  "hello",
 			sender:   sender,
 			expected: errorUnableToGetConnections,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return(fakeMessageUuid, nil)
 				cr.On("GetAllConnections", mock.Anything).Return(nil, errorUnableToGetConnections)
 			},
 		},
@@ -81,13 +95,24 @@ func TestSendMessageToAllConnections(t *testing.T) {
 			tName:    "should fail with error when unable to save message",
 			payload:  "hello",
 			sender:   sender,
+			expected: errorUnableToSaveMessage,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("", errorUnableToSaveMessage)
+			},
+		},
+		{
+			tName:    "should skip delivery when unable to save deliveries",
+			payload:  "hello",
+			sender:   sender,
 			expected: nil,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				cr.On("GetAllConnections", mock.Anything).Return(map[string][]ws.ConnHelper{
+					sender.Id:    {wc},
+					recipient.Id: {wc},
 				}, nil)
-				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("", errorUnableToSaveMessage)
+				cr.On("GetConnection", mock.Anything, "s1").Return(wc, nil)
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return(fakeMessageUuid, nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return(nil, errorUnableToSaveMessage)
 			},
 		},
 		{
@@ -95,12 +120,15 @@ func TestSendMessageToAllConnections(t *testing.T) {
 			payload:  "hello",
 			sender:   sender,
 			expected: nil,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				cr.On("GetAllConnections", mock.Anything).Return(map[string][]ws.ConnHelper{
+					sender.Id:    {wc},
+					recipient.Id: {wc},
 				}, nil)
+				cr.On("GetConnection", mock.Anything, "s1").Return(wc, nil)
 				mr.On("SaveMessage", mock.Anything, mock.Anything).Return(fakeMessageUuid, nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return([]*models.Message{{Id: "c1", RecipientId: recipient.Id, Payload: "hello"}}, nil)
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
 				wc.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(errorUnableToSendMessage)
 			},
 		},
@@ -109,35 +137,86 @@ func TestSendMessageToAllConnections(t *testing.T) {
 			payload:  "hello",
 			sender:   sender,
 			expected: nil,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				cr.On("GetAllConnections", mock.Anything).Return(map[string][]ws.ConnHelper{
+					sender.Id:    {wc},
+					recipient.Id: {wc},
 				}, nil)
+				cr.On("GetConnection", mock.Anything, "s1").Return(wc, nil)
 				mr.On("SaveMessage", mock.Anything, mock.Anything).Return(fakeMessageUuid, nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return([]*models.Message{{Id: "c1", RecipientId: recipient.Id, Payload: "hello"}}, nil)
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
 				wc.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil)
+				mr.On("MarkMessageDelivered", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			},
+		},
+		{
+			tName:    "should fail with room not found error",
+			roomId:   room.Id,
+			payload:  "hello",
+			sender:   sender,
+			expected: repositories.ErrRoomNotFound,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				rr.On("FindRoomById", mock.Anything, room.Id).Return(nil, repositories.ErrRoomNotFound)
+			},
+		},
+		{
+			tName:    "should fail when sender is not a member of the room",
+			roomId:   room.Id,
+			payload:  "hello",
+			sender:   outsider,
+			expected: ErrNotRoomMember,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				rr.On("FindRoomById", mock.Anything, room.Id).Return(room, nil)
+			},
+		},
+		{
+			tName:    "should send message only to room members",
+			roomId:   room.Id,
+			payload:  "hello",
+			sender:   sender,
+			expected: nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				outsiderConn := new(mocks.ConnHelper)
+				rr.On("FindRoomById", mock.Anything, room.Id).Return(room, nil)
+				cr.On("GetAllConnections", mock.Anything).Return(map[string][]ws.ConnHelper{
+					sender.Id:    {wc},
+					recipient.Id: {wc},
+					outsider.Id:  {outsiderConn},
+				}, nil)
+				cr.On("GetConnection", mock.Anything, "s1").Return(wc, nil)
+				mr.On("SaveMessage", mock.Anything, mock.MatchedBy(func(msg *models.Message) bool {
+					return msg.RoomId == room.Id
+				})).Return(fakeMessageUuid, nil).Once()
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return([]*models.Message{{Id: "c1", RecipientId: recipient.Id, RoomId: room.Id, Payload: "hello"}}, nil).Once()
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
+				wc.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil).Once()
+				mr.On("MarkMessageDelivered", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
 			},
 		},
 	}
 
 	for _, testCond := range testConditions {
 		t.Run(testCond.tName, func(t *testing.T) {
-			ctx := context.Background()
+			ctx := contextWithSession(context.Background(), "s1")
 			cr := new(mocks.ConnectionsRepository)
 			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
 			ur := new(mocks.UsersRepository)
 			wu := new(mocks.UpgraderHelper)
+			pr := new(mocks.PresenceRepository)
 			wc := new(mocks.ConnHelper)
 
-			testCond.prepareMocks(cr, mr, ur, wc)
-			svc := NewWebSocketService(cr, mr, ur, wu)
+			testCond.prepareMocks(cr, mr, rr, ur, wc)
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
 
-			gotErr := svc.SendMessageToAllConnections(ctx, testCond.payload, testCond.sender)
+			gotErr := svc.SendMessageToAllConnections(ctx, newTestFrame(testCond.roomId, "", testCond.payload), testCond.sender)
 
 			assert.Equal(t, testCond.expected, gotErr, "SendMessageToAllConnections returned unexpected result: got error %v want %v", gotErr, testCond.expected)
 
 			cr.AssertExpectations(t)
 			mr.AssertExpectations(t)
+			rr.AssertExpectations(t)
 			ur.AssertExpectations(t)
 			wu.AssertExpectations(t)
 			wc.AssertExpectations(t)
@@ -175,7 +254,7 @@ func TestLoadUserMessages(t *testing.T) {
 			usr:      usr,
 			expected: errorUnableToFindUsrMsgs,
 			prepareMocks: func(mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
-				mr.On("FindUserMessages", mock.Anything, usr.Id).Return(nil, errorUnableToFindUsrMsgs)
+				mr.On("FindUndeliveredMessages", mock.Anything, usr.Id).Return(nil, errorUnableToFindUsrMsgs)
 			},
 		},
 		{
@@ -184,7 +263,8 @@ func TestLoadUserMessages(t *testing.T) {
 			usr:      usr,
 			expected: errorUnableToSendMessage,
 			prepareMocks: func(mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
-				mr.On("FindUserMessages", mock.Anything, usr.Id).Return(msgs, nil)
+				mr.On("FindUndeliveredMessages", mock.Anything, usr.Id).Return(msgs, nil)
+				wc.On("Protocol").Return(ws.ProtocolJSON)
 				wc.On("WriteMessage", websocket.TextMessage, mock.Anything).Return(errorUnableToSendMessage)
 			},
 		},
@@ -194,8 +274,30 @@ func TestLoadUserMessages(t *testing.T) {
 			usr:      usr,
 			expected: nil,
 			prepareMocks: func(mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
-				mr.On("FindUserMessages", mock.Anything, usr.Id).Return(msgs, nil)
+				mr.On("FindUndeliveredMessages", mock.Anything, usr.Id).Return(msgs, nil)
+				wc.On("Protocol").Return(ws.ProtocolJSON)
 				wc.On("WriteMessage", websocket.TextMessage, mock.Anything).Return(nil)
+				mr.On("MarkMessageDelivered", mock.Anything, msgs[0].Id, mock.Anything).Return(nil).Once()
+				mr.On("MarkMessageDelivered", mock.Anything, msgs[1].Id, mock.Anything).Return(nil).Once()
+				mr.On("FindPendingUpdates", mock.Anything, usr.Id).Return(nil, nil)
+			},
+		},
+		{
+			tName:    "should skip deleted messages and send pending updates",
+			msgs:     msgs,
+			usr:      usr,
+			expected: nil,
+			prepareMocks: func(mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
+				deleted := &models.Message{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", DeletedAt: 10}
+				edited := &models.Message{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa49", Payload: "fixed", EditedAt: 10}
+				mr.On("FindUndeliveredMessages", mock.Anything, usr.Id).Return([]*models.Message{deleted}, nil)
+				mr.On("MarkMessageDelivered", mock.Anything, deleted.Id, mock.Anything).Return(nil).Once()
+				mr.On("FindPendingUpdates", mock.Anything, usr.Id).Return([]*models.Message{edited}, nil)
+				wc.On("Protocol").Return(ws.ProtocolJSON)
+				wc.On("WriteMessage", websocket.TextMessage, mock.MatchedBy(func(data []byte) bool {
+					return strings.Contains(string(data), `"type":"edit"`) && strings.Contains(string(data), `"payload":"fixed"`)
+				})).Return(nil).Once()
+				mr.On("MarkUpdateDelivered", mock.Anything, usr.Id, edited.Id).Return(nil).Once()
 			},
 		},
 	}
@@ -205,12 +307,14 @@ func TestLoadUserMessages(t *testing.T) {
 			ctx := context.Background()
 			cr := new(mocks.ConnectionsRepository)
 			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
 			ur := new(mocks.UsersRepository)
 			wu := new(mocks.UpgraderHelper)
+			pr := new(mocks.PresenceRepository)
 			wc := new(mocks.ConnHelper)
 
 			testCond.prepareMocks(mr, wc)
-			svc := NewWebSocketService(cr, mr, ur, wu)
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
 
 			gotErr := svc.LoadUserMessages(ctx, testCond.usr, wc)
 
@@ -218,6 +322,7 @@ func TestLoadUserMessages(t *testing.T) {
 
 			cr.AssertExpectations(t)
 			mr.AssertExpectations(t)
+			rr.AssertExpectations(t)
 			ur.AssertExpectations(t)
 			wu.AssertExpectations(t)
 			wc.AssertExpectations(t)
@@ -228,28 +333,32 @@ func TestLoadUserMessages(t *testing.T) {
 func TestSaveUnreadMessages(t *testing.T) {
 	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
 	recipient := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46", UserName: "bar"}
-	errorUnableToGetConnections := errors.New("Unable to get connections list")
+	offlineMember := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa47", UserName: "baz"}
+	room := &models.Room{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", Members: []string{sender.Id, offlineMember.Id}}
+	errorUnableToGetOnlineUsers := errors.New("Unable to get online users")
 	errorUnableToFindUsrs := errors.New("Unable to find users")
 	errorUnableToSaveMessage := errors.New("Unable to save message into database")
 	testConditions := []struct {
 		tName        string
+		roomId       string
 		msg          string
 		sender       *models.User
 		expectedErr  error
 		prepareMocks func(
-			*mocks.ConnectionsRepository,
+			*mocks.PresenceRepository,
 			*mocks.MessagesRepository,
+			*mocks.RoomsRepository,
 			*mocks.UsersRepository,
 			*mocks.ConnHelper,
 		)
 	}{
 		{
-			tName:       "should fail with unable to get connections list error",
+			tName:       "should fail with unable to get online users error",
 			msg:         "hello",
 			sender:      sender,
-			expectedErr: errorUnableToGetConnections,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(nil, errorUnableToGetConnections)
+			expectedErr: errorUnableToGetOnlineUsers,
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return(nil, errorUnableToGetOnlineUsers)
 			},
 		},
 		{
@@ -257,11 +366,8 @@ func TestSaveUnreadMessages(t *testing.T) {
 			msg:         "hello",
 			sender:      sender,
 			expectedErr: errorUnableToFindUsrs,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
-				}, nil)
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return([]string{sender.Id, recipient.Id}, nil)
 				ur.On("FindUsersNotInIdList", mock.Anything, []string{sender.Id, recipient.Id}).Return(nil, errorUnableToFindUsrs)
 			},
 		},
@@ -270,13 +376,10 @@ func TestSaveUnreadMessages(t *testing.T) {
 			msg:         "hello",
 			sender:      sender,
 			expectedErr: errorUnableToSaveMessage,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
-				}, nil)
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return([]string{sender.Id, recipient.Id}, nil)
 				ur.On("FindUsersNotInIdList", mock.Anything, []string{sender.Id, recipient.Id}).Return([]*models.User{sender, recipient}, nil)
-				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("", errorUnableToSaveMessage)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{sender.Id, recipient.Id}).Return(nil, errorUnableToSaveMessage)
 			},
 		},
 		{
@@ -284,13 +387,35 @@ func TestSaveUnreadMessages(t *testing.T) {
 			msg:         "hello",
 			sender:      sender,
 			expectedErr: nil,
-			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
-				cr.On("GetAllConnections", mock.Anything).Return(map[string]ws.ConnHelper{
-					sender.Id:    wc,
-					recipient.Id: wc,
-				}, nil)
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return([]string{sender.Id, recipient.Id}, nil)
 				ur.On("FindUsersNotInIdList", mock.Anything, []string{sender.Id, recipient.Id}).Return([]*models.User{sender, recipient}, nil)
-				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{sender.Id, recipient.Id}).Return([]*models.Message{{Id: "c1"}, {Id: "c2"}}, nil)
+			},
+		},
+		{
+			tName:       "should fail with room not found error",
+			roomId:      room.Id,
+			msg:         "hello",
+			sender:      sender,
+			expectedErr: repositories.ErrRoomNotFound,
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return([]string{sender.Id}, nil)
+				rr.On("FindRoomById", mock.Anything, room.Id).Return(nil, repositories.ErrRoomNotFound)
+			},
+		},
+		{
+			tName:       "should save message only for offline room members",
+			roomId:      room.Id,
+			msg:         "hello",
+			sender:      sender,
+			expectedErr: nil,
+			prepareMocks: func(pr *mocks.PresenceRepository, mr *mocks.MessagesRepository, rr *mocks.RoomsRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				pr.On("OnlineUsers", mock.Anything).Return([]string{sender.Id, recipient.Id}, nil)
+				rr.On("FindRoomById", mock.Anything, room.Id).Return(room, nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.MatchedBy(func(msg *models.Message) bool {
+					return msg.RoomId == room.Id && msg.SenderId == sender.Id
+				}), []string{offlineMember.Id}).Return([]*models.Message{{Id: "c1", RecipientId: offlineMember.Id}}, nil).Once()
 			},
 		},
 	}
@@ -300,22 +425,393 @@ func TestSaveUnreadMessages(t *testing.T) {
 			ctx := context.Background()
 			cr := new(mocks.ConnectionsRepository)
 			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
 			ur := new(mocks.UsersRepository)
 			wu := new(mocks.UpgraderHelper)
+			pr := new(mocks.PresenceRepository)
 			wc := new(mocks.ConnHelper)
 
-			testCond.prepareMocks(cr, mr, ur, wc)
-			svc := NewWebSocketService(cr, mr, ur, wu)
+			testCond.prepareMocks(pr, mr, rr, ur, wc)
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
 
-			gotErr := svc.SaveUnreadMessages(ctx, testCond.sender, testCond.msg)
+			gotErr := svc.SaveUnreadMessages(ctx, testCond.sender, newTestFrame(testCond.roomId, "", testCond.msg))
 
 			assert.Equal(t, testCond.expectedErr, gotErr, "SaveUnreadMessages returned unexpected result: got error %v want %v", gotErr, testCond.expectedErr)
 
 			cr.AssertExpectations(t)
+			pr.AssertExpectations(t)
 			mr.AssertExpectations(t)
+			rr.AssertExpectations(t)
 			ur.AssertExpectations(t)
 			wu.AssertExpectations(t)
 			wc.AssertExpectations(t)
 		})
 	}
 }
+
+func TestSendDirectMessage(t *testing.T) {
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	recipient := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46", UserName: "bar"}
+	errorUnableToSaveMessage := errors.New("Unable to save message in database")
+	testConditions := []struct {
+		tName        string
+		expected     error
+		prepareMocks func(
+			*mocks.ConnectionsRepository,
+			*mocks.MessagesRepository,
+			*mocks.UsersRepository,
+			*mocks.ConnHelper,
+		)
+	}{
+		{
+			tName:    "should fail when recipient does not exist",
+			expected: repositories.ErrUserNotFound,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				ur.On("FindUserById", mock.Anything, recipient.Id).Return(nil, repositories.ErrUserNotFound)
+			},
+		},
+		{
+			tName:    "should store message when recipient is offline",
+			expected: nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				ur.On("FindUserById", mock.Anything, recipient.Id).Return(recipient, nil)
+				cr.On("GetUserConnections", mock.Anything, recipient.Id).Return(nil, nil)
+				mr.On("SaveMessage", mock.Anything, mock.MatchedBy(func(msg *models.Message) bool {
+					return msg.Direct && msg.SenderId == sender.Id
+				})).Return("1", nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return([]*models.Message{{Id: "c1", RecipientId: recipient.Id, Direct: true}}, nil)
+			},
+		},
+		{
+			tName:    "should fail when unable to store message",
+			expected: errorUnableToSaveMessage,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				ur.On("FindUserById", mock.Anything, recipient.Id).Return(recipient, nil)
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("", errorUnableToSaveMessage)
+			},
+		},
+		{
+			tName:    "should deliver message to every recipient connection",
+			expected: nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, ur *mocks.UsersRepository, wc *mocks.ConnHelper) {
+				ur.On("FindUserById", mock.Anything, recipient.Id).Return(recipient, nil)
+				cr.On("GetUserConnections", mock.Anything, recipient.Id).Return([]ws.ConnHelper{wc, wc}, nil)
+				mr.On("SaveMessage", mock.Anything, mock.Anything).Return("1", nil)
+				mr.On("SaveDeliveries", mock.Anything, mock.Anything, []string{recipient.Id}).Return([]*models.Message{{Id: "c1", RecipientId: recipient.Id, Payload: "hello", Direct: true}}, nil)
+				wc.On("Protocol").Return(ws.ProtocolLegacy)
+				wc.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil).Twice()
+				mr.On("MarkMessageDelivered", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			cr := new(mocks.ConnectionsRepository)
+			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
+			ur := new(mocks.UsersRepository)
+			wu := new(mocks.UpgraderHelper)
+			pr := new(mocks.PresenceRepository)
+			wc := new(mocks.ConnHelper)
+
+			testCond.prepareMocks(cr, mr, ur, wc)
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+			gotErr := svc.SendDirectMessage(ctx, newTestFrame("", recipient.Id, "hello"), sender)
+
+			assert.Equal(t, testCond.expected, gotErr, "SendDirectMessage returned unexpected result: got error %v want %v", gotErr, testCond.expected)
+
+			cr.AssertExpectations(t)
+			mr.AssertExpectations(t)
+			ur.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMarkMessageRead(t *testing.T) {
+	reader := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	senderId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	msg := &models.Message{Id: "copy", OriginId: "m1", SenderId: senderId, RecipientId: reader.Id, ReadAt: 10}
+	testConditions := []struct {
+		tName        string
+		expected     error
+		prepareMocks func(*mocks.ConnectionsRepository, *mocks.MessagesRepository, *mocks.ConnHelper)
+	}{
+		{
+			tName:    "should fail when message does not exist",
+			expected: repositories.ErrMessageNotFound,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
+				mr.On("MarkMessageRead", mock.Anything, reader.Id, "m1", mock.Anything).Return(nil, repositories.ErrMessageNotFound)
+			},
+		},
+		{
+			tName:    "should send read receipt to every sender connection",
+			expected: nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
+				mr.On("MarkMessageRead", mock.Anything, reader.Id, "m1", mock.Anything).Return(msg, nil)
+				cr.On("GetUserConnections", mock.Anything, senderId).Return([]ws.ConnHelper{wc, wc}, nil)
+				wc.On("Protocol").Return(ws.ProtocolJSON)
+				wc.On("WriteMessage", websocket.TextMessage, []byte(`{"v":1,"type":"receipt","id":"m1","recipientId":"`+reader.Id+`","time":10}`)).Return(nil).Twice()
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			cr := new(mocks.ConnectionsRepository)
+			mr := new(mocks.MessagesRepository)
+			rr := new(mocks.RoomsRepository)
+			ur := new(mocks.UsersRepository)
+			wu := new(mocks.UpgraderHelper)
+			pr := new(mocks.PresenceRepository)
+			wc := new(mocks.ConnHelper)
+
+			testCond.prepareMocks(cr, mr, wc)
+			svc := NewWebSocketService(cr, mr, rr, ur, wu, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+			gotErr := svc.MarkMessageRead(ctx, reader, "m1")
+
+			assert.Equal(t, testCond.expected, gotErr, "MarkMessageRead returned unexpected result: got error %v want %v", gotErr, testCond.expected)
+
+			cr.AssertExpectations(t)
+			mr.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSendMessageToAllConnectionsPublishesRoomMembers(t *testing.T) {
+	ctx := context.Background()
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	room := &models.Room{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa48", Members: []string{sender.Id, "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"}}
+	cr := new(mocks.ConnectionsRepository)
+	mr := new(mocks.MessagesRepository)
+	rr := new(mocks.RoomsRepository)
+	ur := new(mocks.UsersRepository)
+	wu := new(mocks.UpgraderHelper)
+	pr := new(mocks.PresenceRepository)
+	br := new(mocks.Broker)
+	br.On("Subscribe", mock.Anything).Return()
+	rr.On("FindRoomById", ctx, room.Id).Return(room, nil)
+	mr.On("SaveMessage", ctx, mock.Anything).Return("1", nil).Once()
+	br.On("Publish", ctx, mock.MatchedBy(func(event *models.Event) bool {
+		return event.Type == models.EventTypeBroadcast && event.SenderId == sender.Id && assert.ObjectsAreEqual(room.Members, event.RecipientIds) &&
+			event.Frame.Time != 0
+	})).Return(nil)
+	svc := NewWebSocketService(cr, mr, rr, ur, wu, br, pr, &config.ServerConfig{})
+
+	gotErr := svc.SendMessageToAllConnections(ctx, newTestFrame(room.Id, "", "hello"), sender)
+
+	assert.Nil(t, gotErr, "SendMessageToAllConnections returned unexpected result: got error %v want %v", gotErr, nil)
+
+	br.AssertExpectations(t)
+	rr.AssertExpectations(t)
+	cr.AssertExpectations(t)
+	mr.AssertExpectations(t)
+}
+
+func TestSendMessageToAllConnectionsReachesEverySession(t *testing.T) {
+	ctx := context.Background()
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	cr := new(mocks.ConnectionsRepository)
+	mr := new(mocks.MessagesRepository)
+	pr := new(mocks.PresenceRepository)
+	tab1 := new(mocks.ConnHelper)
+	tab2 := new(mocks.ConnHelper)
+	cr.On("GetAllConnections", ctx).Return(map[string][]ws.ConnHelper{recipientId: {tab1, tab2}}, nil)
+	mr.On("SaveMessage", ctx, mock.Anything).Return("1", nil).Once()
+	mr.On("SaveDeliveries", ctx, mock.Anything, []string{recipientId}).Return([]*models.Message{{Id: "c1", RecipientId: recipientId, Payload: "hello"}}, nil).Once()
+	mr.On("MarkMessageDelivered", ctx, mock.Anything, mock.Anything).Return(nil).Once()
+	for _, tab := range []*mocks.ConnHelper{tab1, tab2} {
+		tab.On("Protocol").Return(ws.ProtocolLegacy)
+		tab.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil).Once()
+	}
+	svc := NewWebSocketService(cr, mr, nil, nil, nil, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+	gotErr := svc.SendMessageToAllConnections(ctx, newTestFrame("", "", "hello"), sender)
+
+	assert.Nil(t, gotErr, "SendMessageToAllConnections returned unexpected result: got error %v want %v", gotErr, nil)
+
+	cr.AssertExpectations(t)
+	mr.AssertExpectations(t)
+	tab1.AssertExpectations(t)
+	tab2.AssertExpectations(t)
+}
+
+func TestSendMessageToAllConnectionsReachesOtherSessionsOfSender(t *testing.T) {
+	ctx := contextWithSession(context.Background(), "s1")
+	sender := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	recipientId := "14ef71b2-5d7c-11ec-a0f3-c46516a4fa46"
+	cr := new(mocks.ConnectionsRepository)
+	mr := new(mocks.MessagesRepository)
+	pr := new(mocks.PresenceRepository)
+	origin := new(mocks.ConnHelper)
+	senderTab := new(mocks.ConnHelper)
+	recipientTab := new(mocks.ConnHelper)
+	cr.On("GetAllConnections", ctx).Return(map[string][]ws.ConnHelper{sender.Id: {origin, senderTab}, recipientId: {recipientTab}}, nil)
+	cr.On("GetConnection", ctx, "s1").Return(origin, nil)
+	mr.On("SaveMessage", ctx, mock.Anything).Return("1", nil).Once()
+	mr.On("SaveDeliveries", ctx, mock.Anything, mock.MatchedBy(func(ids []string) bool {
+		sorted := append([]string{}, ids...)
+		sort.Strings(sorted)
+		return assert.ObjectsAreEqual([]string{sender.Id, recipientId}, sorted)
+	})).Return([]*models.Message{{Id: "c1", RecipientId: sender.Id, Payload: "hello"}, {Id: "c2", RecipientId: recipientId, Payload: "hello"}}, nil).Once()
+	mr.On("MarkMessageDelivered", ctx, mock.Anything, mock.Anything).Return(nil).Twice()
+	for _, tab := range []*mocks.ConnHelper{senderTab, recipientTab} {
+		tab.On("Protocol").Return(ws.ProtocolLegacy)
+		tab.On("WriteMessage", websocket.TextMessage, []byte("hello")).Return(nil).Once()
+	}
+	svc := NewWebSocketService(cr, mr, nil, nil, nil, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+	gotErr := svc.SendMessageToAllConnections(ctx, newTestFrame("", "", "hello"), sender)
+
+	assert.Nil(t, gotErr, "SendMessageToAllConnections returned unexpected result: got error %v want %v", gotErr, nil)
+
+	cr.AssertExpectations(t)
+	mr.AssertExpectations(t)
+	origin.AssertExpectations(t)
+	senderTab.AssertExpectations(t)
+	recipientTab.AssertExpectations(t)
+}
+
+func TestCloseSession(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	sessions := []*models.Session{{Id: "s1", UserId: usr.Id}, {Id: "s2", UserId: usr.Id}}
+	testConditions := []struct {
+		tName        string
+		sessionId    string
+		expected     error
+		prepareMocks func(*mocks.ConnectionsRepository, *mocks.PresenceRepository, *mocks.ConnHelper)
+	}{
+		{
+			tName:     "should fail when session does not belong to the user",
+			sessionId: "s3",
+			expected:  ErrSessionNotFound,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, pr *mocks.PresenceRepository, wc *mocks.ConnHelper) {
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return(sessions, nil)
+			},
+		},
+		{
+			tName:     "should close session held by the current instance",
+			sessionId: "s2",
+			expected:  nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, pr *mocks.PresenceRepository, wc *mocks.ConnHelper) {
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return(sessions, nil)
+				cr.On("GetConnection", mock.Anything, "s2").Return(wc, nil)
+				wc.On("CloseWithReason", ws.ClosePolicyViolation, mock.Anything).Return(nil)
+			},
+		},
+		{
+			tName:     "should skip session held by another instance",
+			sessionId: "s1",
+			expected:  nil,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, pr *mocks.PresenceRepository, wc *mocks.ConnHelper) {
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return(sessions, nil)
+				cr.On("GetConnection", mock.Anything, "s1").Return(nil, repositories.ErrConnNotFound)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			cr := new(mocks.ConnectionsRepository)
+			pr := new(mocks.PresenceRepository)
+			wc := new(mocks.ConnHelper)
+
+			testCond.prepareMocks(cr, pr, wc)
+			svc := NewWebSocketService(cr, nil, nil, nil, nil, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+			gotErr := svc.CloseSession(ctx, usr, testCond.sessionId)
+
+			assert.Equal(t, testCond.expected, gotErr, "CloseSession returned unexpected result: got error %v want %v", gotErr, testCond.expected)
+
+			cr.AssertExpectations(t)
+			pr.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRemoveUser(t *testing.T) {
+	usr := &models.User{Id: "14ef71b2-5d7c-11ec-a0f3-c46516a4fa45", UserName: "foo"}
+	unknownErr := errors.New("Unable to remove")
+	testConditions := []struct {
+		tName        string
+		expected     error
+		prepareMocks func(*mocks.ConnectionsRepository, *mocks.PresenceRepository, *mocks.RoomsRepository, *mocks.MessagesRepository, *mocks.ConnHelper)
+	}{
+		{
+			tName: "should close sessions and remove presence, rooms and deliveries of the user",
+			prepareMocks: func(cr *mocks.ConnectionsRepository, pr *mocks.PresenceRepository, rr *mocks.RoomsRepository, mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return([]*models.Session{{Id: "s1", UserId: usr.Id}}, nil).Once()
+				cr.On("GetConnection", mock.Anything, "s1").Return(wc, nil)
+				wc.On("CloseWithReason", ws.ClosePolicyViolation, mock.Anything).Return(nil)
+				pr.On("DeleteUserConnections", mock.Anything, usr.Id).Return(nil)
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return([]*models.Session{}, nil).Once()
+				cr.On("GetAllConnections", mock.Anything).Return(map[string][]ws.ConnHelper{}, nil)
+				rr.On("RemoveUser", mock.Anything, usr.Id).Return(nil)
+				mr.On("DeleteRecipientDeliveries", mock.Anything, usr.Id).Return(nil)
+			},
+		},
+		{
+			tName:    "should fail when rooms of the user are not updated",
+			expected: unknownErr,
+			prepareMocks: func(cr *mocks.ConnectionsRepository, pr *mocks.PresenceRepository, rr *mocks.RoomsRepository, mr *mocks.MessagesRepository, wc *mocks.ConnHelper) {
+				pr.On("GetUserSessions", mock.Anything, usr.Id).Return([]*models.Session{}, nil)
+				pr.On("DeleteUserConnections", mock.Anything, usr.Id).Return(nil)
+				rr.On("RemoveUser", mock.Anything, usr.Id).Return(unknownErr)
+			},
+		},
+	}
+
+	for _, testCond := range testConditions {
+		t.Run(testCond.tName, func(t *testing.T) {
+			ctx := context.Background()
+			cr := new(mocks.ConnectionsRepository)
+			pr := new(mocks.PresenceRepository)
+			rr := new(mocks.RoomsRepository)
+			mr := new(mocks.MessagesRepository)
+			wc := new(mocks.ConnHelper)
+
+			testCond.prepareMocks(cr, pr, rr, mr, wc)
+			svc := NewWebSocketService(cr, mr, rr, nil, nil, broker.NewInMemoryBroker(), pr, &config.ServerConfig{})
+
+			gotErr := svc.RemoveUser(ctx, usr)
+
+			assert.Equal(t, testCond.expected, gotErr, "RemoveUser returned unexpected result: got error %v want %v", gotErr, testCond.expected)
+
+			cr.AssertExpectations(t)
+			pr.AssertExpectations(t)
+			rr.AssertExpectations(t)
+			mr.AssertExpectations(t)
+			wc.AssertExpectations(t)
+		})
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	ctx := context.Background()
+	cr := new(mocks.ConnectionsRepository)
+	flushed, stale := new(mocks.ConnHelper), new(mocks.ConnHelper)
+	cr.On("GetAllConnections", ctx).Return(map[string][]ws.ConnHelper{
+		"14ef71b2-5d7c-11ec-a0f3-c46516a4fa45": {flushed, stale},
+	}, nil)
+	flushed.On("Flush", ctx).Return(nil)
+	flushed.On("CloseWithReason", ws.CloseGoingAway, mock.Anything).Return(nil)
+	stale.On("Flush", ctx).Return(context.DeadlineExceeded)
+	stale.On("CloseWithReason", ws.CloseGoingAway, mock.Anything).Return(nil)
+	svc := NewWebSocketService(cr, nil, nil, nil, nil, broker.NewInMemoryBroker(), nil, &config.ServerConfig{})
+
+	err := svc.Shutdown(ctx)
+
+	assert.Nil(t, err, "Shutdown returned unexpected result: got error %v want nil", err)
+	cr.AssertExpectations(t)
+	flushed.AssertExpectations(t)
+	stale.AssertExpectations(t)
+}
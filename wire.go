@@ -7,33 +7,48 @@ import (
 	"github.com/andriystech/lgc/api/server"
 	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
 	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/andriystech/lgc/services"
 	"github.com/google/wire"
 )
 
 var collectionsSet = wire.NewSet(
+	mongo.NewDeliveriesCollection,
+	mongo.NewEventsCollection,
 	mongo.NewMessagesCollection,
+	mongo.NewPresenceCollection,
+	mongo.NewRevokedTokensCollection,
+	mongo.NewRoomsCollection,
+	mongo.NewTokensCollection,
 	mongo.NewUsersCollection,
 )
 
 var repositoriesSet = wire.NewSet(
 	repositories.NewConnectionsRepository,
 	repositories.NewMessagesRepository,
+	repositories.NewPresenceRepository,
+	repositories.NewRevokedTokensRepository,
+	repositories.NewRoomsRepository,
 	repositories.NewTokensRepository,
 	repositories.NewUsersRepository,
 )
 
 var servicesSet = wire.NewSet(
+	services.NewHealthService,
+	services.NewMessageService,
+	services.NewRoomService,
+	services.NewTokensJanitor,
 	services.NewTokenService,
 	services.NewUserService,
 	services.NewWebSocketService,
 )
 
-func NewServer(db mongo.ClientHelper) server.HttpServer {
+func NewServer(db mongo.ClientHelper, serverConfig *config.ServerConfig, lg *logger.Logger) server.HttpServer {
 	wire.Build(
-		config.GetServerConfig,
+		broker.NewBroker,
 		ws.NewUpgrader,
 		collectionsSet,
 		repositoriesSet,
@@ -10,34 +10,49 @@ import (
 	"github.com/andriystech/lgc/api/server"
 	"github.com/andriystech/lgc/config"
 	"github.com/andriystech/lgc/db/repositories"
+	"github.com/andriystech/lgc/facilities/broker"
 	"github.com/andriystech/lgc/facilities/mongo"
 	"github.com/andriystech/lgc/facilities/ws"
+	"github.com/andriystech/lgc/pkg/logger"
 	"github.com/andriystech/lgc/services"
 	"github.com/google/wire"
 )
 
 // Injectors from wire.go:
 
-func NewServer(db mongo.ClientHelper) server.HttpServer {
-	serverConfig := config.GetServerConfig()
-	tokensRepository := repositories.NewTokensRepository(serverConfig)
-	tokenService := services.NewTokenService(tokensRepository)
+func NewServer(db mongo.ClientHelper, serverConfig *config.ServerConfig, lg *logger.Logger) server.HttpServer {
+	messagesCollection := mongo.NewMessagesCollection(db, serverConfig)
+	deliveriesCollection := mongo.NewDeliveriesCollection(db, serverConfig)
+	messagesRepository := repositories.NewMessagesRepository(messagesCollection, deliveriesCollection)
+	messageService := services.NewMessageService(messagesRepository)
+	roomsCollection := mongo.NewRoomsCollection(db, serverConfig)
+	roomsRepository := repositories.NewRoomsRepository(roomsCollection)
+	roomService := services.NewRoomService(roomsRepository)
+	tokensCollection := mongo.NewTokensCollection(db, serverConfig)
+	tokensRepository := repositories.NewTokensRepository(serverConfig, tokensCollection)
+	revokedTokensCollection := mongo.NewRevokedTokensCollection(db, serverConfig)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(serverConfig, revokedTokensCollection)
 	usersCollection := mongo.NewUsersCollection(db, serverConfig)
 	usersRepository := repositories.NewUsersRepository(usersCollection)
-	userService := services.NewUserService(usersRepository)
+	tokenService := services.NewTokenService(tokensRepository, revokedTokensRepository, usersRepository, serverConfig)
+	userService := services.NewUserService(usersRepository, serverConfig)
 	connectionsRepository := repositories.NewConnectionsRepository()
-	messagesCollection := mongo.NewMessagesCollection(db, serverConfig)
-	messagesRepository := repositories.NewMessagesRepository(messagesCollection)
 	upgraderHelper := ws.NewUpgrader(serverConfig)
-	webSocketService := services.NewWebSocketService(connectionsRepository, messagesRepository, usersRepository, upgraderHelper)
-	httpServer := server.NewHttpServer(tokenService, userService, webSocketService, serverConfig)
+	eventsCollection := mongo.NewEventsCollection(db, serverConfig)
+	brokerBroker := broker.NewBroker(serverConfig, eventsCollection)
+	presenceCollection := mongo.NewPresenceCollection(db, serverConfig)
+	presenceRepository := repositories.NewPresenceRepository(serverConfig, presenceCollection)
+	webSocketService := services.NewWebSocketService(connectionsRepository, messagesRepository, roomsRepository, usersRepository, upgraderHelper, brokerBroker, presenceRepository, serverConfig)
+	tokensJanitor := services.NewTokensJanitor(tokensRepository, revokedTokensRepository, serverConfig)
+	healthService := services.NewHealthService(db, serverConfig)
+	httpServer := server.NewHttpServer(messageService, roomService, tokenService, userService, webSocketService, tokensJanitor, healthService, lg, serverConfig)
 	return httpServer
 }
 
 // wire.go:
 
-var collectionsSet = wire.NewSet(mongo.NewMessagesCollection, mongo.NewUsersCollection)
+var collectionsSet = wire.NewSet(mongo.NewDeliveriesCollection, mongo.NewEventsCollection, mongo.NewMessagesCollection, mongo.NewPresenceCollection, mongo.NewRevokedTokensCollection, mongo.NewRoomsCollection, mongo.NewTokensCollection, mongo.NewUsersCollection)
 
-var repositoriesSet = wire.NewSet(repositories.NewConnectionsRepository, repositories.NewMessagesRepository, repositories.NewTokensRepository, repositories.NewUsersRepository)
+var repositoriesSet = wire.NewSet(repositories.NewConnectionsRepository, repositories.NewMessagesRepository, repositories.NewPresenceRepository, repositories.NewRevokedTokensRepository, repositories.NewRoomsRepository, repositories.NewTokensRepository, repositories.NewUsersRepository)
 
-var servicesSet = wire.NewSet(services.NewTokenService, services.NewUserService, services.NewWebSocketService)
+var servicesSet = wire.NewSet(services.NewHealthService, services.NewMessageService, services.NewRoomService, services.NewTokensJanitor, services.NewTokenService, services.NewUserService, services.NewWebSocketService)